@@ -4,25 +4,31 @@ import (
 	"context"
 	"crypto/tls"
 	"database/sql"
+	"flag"
 	"fmt"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
 	"syscall"
 	"text/template"
 
 	"github.com/jackc/pgx"
 	"github.com/jackc/pgx/stdlib"
+	_ "github.com/mattn/go-sqlite3"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
 
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/analytics"
 	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/app"
 	grpcserver "github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/app/grpc"
 	rest "github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/app/http"
 	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/config"
 	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/store"
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/urlcheck"
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/urlpolicy"
 	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/models"
 	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/pkg/shortener"
 )
@@ -61,6 +67,14 @@ func main() {
 
 	config.Parse()
 
+	if flag.Arg(0) == "migrate" {
+		if err := runMigrate(ctx, flag.Args()[1:], os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	if err := run(ctx); err != nil {
 		panic("unexpected error: " + err.Error())
 	}
@@ -72,12 +86,44 @@ func run(ctx context.Context) error {
 		return fmt.Errorf("cannot create storage: %w", err)
 	}
 	defer storage.Close()
+	go store.RunReaper(ctx, storage, config.ReaperInterval, config.ExpiredRetention, config.ClickRetention)
 	removeChan := make(chan models.BatchRemoveRequest)
 	instance := app.NewInstance(config.BaseURL, storage, removeChan)
+	if instance.Domains, err = app.NewDomains(strings.Split(config.ShortDomains, ",")); err != nil {
+		return err
+	}
+	instance.Policy = &urlpolicy.Policy{
+		Schemes:       config.Schemes(),
+		MaxLength:     config.MaxURLLength,
+		StripTracking: config.StripTrackingParams,
+	}
+	if config.BlocklistFile != "" {
+		blocklist, err := urlcheck.NewBlocklist(config.BlocklistFile)
+		if err != nil {
+			return err
+		}
+		go blocklist.Watch(ctx, config.BlocklistReloadInterval)
+		instance.URLChecker = urlcheck.Chain{blocklist}
+	}
+
+	instance.Clicks = analytics.NewRecorder(storage, config.ClickBatchSize, config.ClickFlushInterval)
+	// the recorder outlives ctx: requests served during graceful shutdown still record clicks
+	clicksCtx, stopClicks := context.WithCancel(context.Background())
+	clicksSaved := make(chan struct{})
+	go func() {
+		instance.Clicks.Run(clicksCtx)
+		close(clicksSaved)
+	}()
+	// runs after the servers have stopped, or on an early error return,
+	// and before storage.Close so that the last clicks are saved
+	defer func() {
+		stopClicks()
+		<-clicksSaved
+	}()
 	restHandler := &rest.Handler{Instance: instance}
 
 	grpcServer := grpcserver.NewShortenerServer(instance)
-	s := grpc.NewServer(grpc.UnaryInterceptor(grpcserver.AuthInterceptor))
+	s := grpc.NewServer(grpc.ChainUnaryInterceptor(grpcserver.AuthInterceptor, grpcserver.HostInterceptor))
 	shortener.RegisterShortenerServer(s, grpcServer)
 	lis, err := net.Listen("tcp", config.GrpcPort)
 	if err != nil {
@@ -164,10 +210,30 @@ func run(ctx context.Context) error {
 	return nil
 }
 
+// sqliteScheme префикс строки подключения, по которому выбирается хранилище SQLite
+const sqliteScheme = "sqlite://"
+
 func newStore(ctx context.Context) (storage store.AuthStore, err error) {
+	ids, err := store.NewIDGenerator(config.IDStrategy, config.IDLength, config.IDSalt)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create id generator: %w", err)
+	}
+	opts := []store.Option{store.WithIDGenerator(ids)}
+
+	if strings.HasPrefix(config.DatabaseDSN, sqliteScheme) {
+		logrus.Debug("Create SQLite storage")
+		sqlite, err := newSQLiteStore(ctx, strings.TrimPrefix(config.DatabaseDSN, sqliteScheme), opts...)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create SQLite store: %w", err)
+		}
+		if err = sqlite.Bootstrap(ctx); err != nil {
+			return nil, fmt.Errorf("cannot bootstrap SQLite store: %w", err)
+		}
+		return sqlite, nil
+	}
 	if config.DatabaseDSN != "" {
 		logrus.Debug("Create DB storage")
-		rdb, errs := newRDBStore(ctx, config.DatabaseDSN)
+		rdb, errs := newRDBStore(ctx, config.DatabaseDSN, opts...)
 		if errs != nil {
 			return nil, fmt.Errorf("cannot create RDB store: %w", err)
 		}
@@ -178,16 +244,17 @@ func newStore(ctx context.Context) (storage store.AuthStore, err error) {
 	}
 	if config.PersistFile != "" {
 		logrus.Debug("Create file storage")
-		storage, err = store.NewFileStore(config.PersistFile)
+		fs, err := store.NewFileStore(config.PersistFile, opts...)
 		if err != nil {
 			return nil, fmt.Errorf("cannot create file store: %w", err)
 		}
-		return
+		go fs.RunCompaction(ctx, config.SnapshotInterval, config.SnapshotThreshold)
+		return fs, nil
 	}
-	return store.NewInMemory(), nil
+	return store.NewInMemory(opts...), nil
 }
 
-func newRDBStore(ctx context.Context, dsn string) (*store.RDB, error) {
+func newRDBStore(ctx context.Context, dsn string, opts ...store.Option) (*store.RDB, error) {
 	// disable prepared statements
 	driverConfig := stdlib.DriverConfig{
 		ConnConfig: pgx.ConnConfig{
@@ -205,5 +272,25 @@ func newRDBStore(ctx context.Context, dsn string) (*store.RDB, error) {
 		return nil, fmt.Errorf("cannot perform initial ping: %w", err)
 	}
 
-	return store.NewRDB(conn), nil
+	return store.NewRDB(conn, opts...), nil
+}
+
+func newSQLiteStore(ctx context.Context, path string, opts ...store.Option) (*store.SQLite, error) {
+	dsn := path
+	if !strings.Contains(dsn, "?") {
+		dsn += "?_busy_timeout=5000&_journal_mode=WAL"
+	}
+
+	conn, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open database: %w", err)
+	}
+	// SQLite allows a single writer at a time
+	conn.SetMaxOpenConns(1)
+
+	if err = conn.PingContext(ctx); err != nil {
+		return nil, fmt.Errorf("cannot perform initial ping: %w", err)
+	}
+
+	return store.NewSQLite(conn, opts...), nil
 }
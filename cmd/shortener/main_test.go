@@ -9,6 +9,7 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -17,6 +18,7 @@ import (
 	"github.com/testcontainers/testcontainers-go"
 
 	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/config"
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/store"
 	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/models"
 	"github.com/testcontainers/testcontainers-go/modules/postgres"
 	"github.com/testcontainers/testcontainers-go/wait"
@@ -58,7 +60,8 @@ func Test_run(t *testing.T) {
 	targetURL := "https://praktikum.yandex.ru/"
 
 	for i := 0; i < 50; i++ {
-		expectedID := fmt.Sprintf("%x", i)
+		expectedID, _ := store.CounterGenerator{}.Generate(uint64(i))
+		targetURL := fmt.Sprintf("%s%d", targetURL, i)
 
 		t.Run("shorten", func(t *testing.T) {
 			expectResponse := "http://localhost:8080/" + expectedID
@@ -98,7 +101,8 @@ func Test_run(t *testing.T) {
 	}
 
 	for i := 50; i < 100; i++ {
-		expectedID := fmt.Sprintf("%x", i)
+		expectedID, _ := store.CounterGenerator{}.Generate(uint64(i))
+		targetURL := fmt.Sprintf("%s%d", targetURL, i)
 
 		t.Run("shortenAPI", func(t *testing.T) {
 			expectResponse := "{\"result\":\"http://localhost:8080/" + expectedID + "\"}\n"
@@ -144,7 +148,7 @@ func Test_run(t *testing.T) {
 	t.Run("sends_gzip", func(t *testing.T) {
 		buf := bytes.NewBuffer(nil)
 		zb := gzip.NewWriter(buf)
-		_, err := zb.Write([]byte(targetURL))
+		_, err := zb.Write([]byte(targetURL + "sends_gzip"))
 		require.NoError(t, err)
 		err = zb.Close()
 		require.NoError(t, err)
@@ -162,14 +166,14 @@ func Test_run(t *testing.T) {
 		b, err := io.ReadAll(resp.Body)
 		require.NoError(t, err)
 
-		expectResponse := "http://localhost:8080/64"
+		expectResponse := "http://localhost:8080/1C"
 		actualResponse := string(b)
 
 		require.Equal(t, expectResponse, actualResponse)
 	})
 
 	t.Run("accepts_gzip", func(t *testing.T) {
-		buf := bytes.NewBufferString(targetURL)
+		buf := bytes.NewBufferString(targetURL + "accepts_gzip")
 		r := httptest.NewRequest("POST", "http://localhost:8080/", buf)
 		r.RequestURI = ""
 		r.Header.Set("Accept-Encoding", "gzip")
@@ -186,7 +190,7 @@ func Test_run(t *testing.T) {
 		b, err := io.ReadAll(zr)
 		require.NoError(t, err)
 
-		expectResponse := "http://localhost:8080/65"
+		expectResponse := "http://localhost:8080/1D"
 		actualResponse := string(b)
 
 		require.Equal(t, expectResponse, actualResponse)
@@ -217,6 +221,13 @@ func Test_newStore(t *testing.T) {
 		require.NoError(t, err)
 		require.NotNil(t, store)
 	})
+	t.Run("create sqlite store", func(t *testing.T) {
+		config.DatabaseDSN = "sqlite://" + filepath.Join(t.TempDir(), "store.db")
+		store, err := newStore(context.Background())
+		require.NoError(t, err)
+		require.NotNil(t, store)
+		require.NoError(t, store.Close())
+	})
 	pgContainer, _ = CreatePostgresContainer(context.Background())
 
 	t.Run("create pg store", func(t *testing.T) {
@@ -226,5 +237,33 @@ func Test_newStore(t *testing.T) {
 		require.NoError(t, err)
 		require.NotNil(t, store)
 	})
+	t.Run("migrate pg store", func(t *testing.T) {
+		connStr, _ := pgContainer.ConnectionString(context.Background(), "sslmode=disable")
+		config.DatabaseDSN = connStr
+		var out bytes.Buffer
+		require.NoError(t, runMigrate(context.Background(), []string{"status"}, &out))
+		assert.Contains(t, out.String(), "0001_create_urls\tapplied at")
+	})
 	pgContainer.Terminate(context.Background())
 }
+
+func Test_runMigrate(t *testing.T) {
+	tests := []struct {
+		name string
+		dsn  string
+		args []string
+	}{
+		{name: "no command", dsn: "postgres://localhost/db", args: nil},
+		{name: "unknown command", dsn: "postgres://localhost/db", args: []string{"redo"}},
+		{name: "no dsn", dsn: "", args: []string{"up"}},
+		{name: "sqlite dsn", dsn: "sqlite://store.db", args: []string{"status"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.DatabaseDSN = tt.dsn
+			defer func() { config.DatabaseDSN = "" }()
+			err := runMigrate(context.Background(), tt.args, io.Discard)
+			assert.Error(t, err)
+		})
+	}
+}
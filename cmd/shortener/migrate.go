@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/config"
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/store"
+)
+
+// migrateUsage подсказка по подкоманде migrate
+const migrateUsage = "usage: shortener [flags] migrate up|down|status"
+
+// runMigrate выполняет подкоманду migrate для хранилища Postgres
+func runMigrate(ctx context.Context, args []string, w io.Writer) error {
+	if len(args) != 1 {
+		return errors.New(migrateUsage)
+	}
+	if config.DatabaseDSN == "" || strings.HasPrefix(config.DatabaseDSN, sqliteScheme) {
+		return errors.New("migrations require a Postgres database DSN")
+	}
+
+	var run func(ctx context.Context, m *store.Migrator) error
+	switch args[0] {
+	case "up":
+		run = func(ctx context.Context, m *store.Migrator) error {
+			applied, err := m.Up(ctx)
+			for _, mg := range applied {
+				fmt.Fprintf(w, "applied %04d_%s\n", mg.Version, mg.Name)
+			}
+			if err == nil && len(applied) == 0 {
+				fmt.Fprintln(w, "no pending migrations")
+			}
+			return err
+		}
+	case "down":
+		run = func(ctx context.Context, m *store.Migrator) error {
+			reverted, err := m.Down(ctx)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(w, "reverted %04d_%s\n", reverted.Version, reverted.Name)
+			return nil
+		}
+	case "status":
+		run = func(ctx context.Context, m *store.Migrator) error {
+			statuses, err := m.Status(ctx)
+			if err != nil {
+				return err
+			}
+			for _, s := range statuses {
+				state := "pending"
+				if s.AppliedAt != nil {
+					state = "applied at " + s.AppliedAt.Format("2006-01-02 15:04:05")
+				}
+				fmt.Fprintf(w, "%04d_%s\t%s\n", s.Version, s.Name, state)
+			}
+			return nil
+		}
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	rdb, err := newRDBStore(ctx, config.DatabaseDSN)
+	if err != nil {
+		return fmt.Errorf("cannot create RDB store: %w", err)
+	}
+	defer rdb.Close()
+
+	m, err := rdb.Migrator()
+	if err != nil {
+		return err
+	}
+	return run(ctx, m)
+}
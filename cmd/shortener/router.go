@@ -7,6 +7,7 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/gofrs/uuid"
 
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/app"
 	rest "github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/app/http"
 	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/auth"
 )
@@ -14,13 +15,21 @@ import (
 func newRouter(i *rest.Handler) http.Handler {
 	r := chi.NewRouter()
 
-	r.Use(gzipMiddleware, authMiddleware)
+	r.Use(gzipMiddleware, authMiddleware, hostMiddleware)
 	r.Post("/", i.ShortenHandler)
 	r.Post("/api/shorten", i.ShortenAPIHandler)
 	r.Post("/api/shorten/batch", i.BatchShortenAPIHandler)
 	r.Delete("/api/user/urls", i.BatchRemoveAPIHandler)
 	r.Get("/{id}", i.ExpandHandler)
+	r.Post("/{id}", i.UnlockHandler)
+	r.Get("/{id}/qr", i.QRHandler)
+	r.Get("/{id}/*", i.ExpandHandler)
+	r.Post("/{id}/*", i.UnlockHandler)
+	r.Get("/{id}+", i.PreviewHandler)
 	r.Get("/api/user/urls", i.UserURLsHandler)
+	r.Patch("/api/user/urls/{id}", i.UpdateURLHandler)
+	r.Get("/api/user/urls/{id}/history", i.HistoryHandler)
+	r.Get("/api/user/urls/{id}/stats", i.LinkStatsHandler)
 	r.Get("/ping", i.PingHandler)
 	r.Get("/api/internal/stats", i.StatisticsHandler)
 
@@ -89,6 +98,13 @@ func authMiddleware(h http.Handler) http.Handler {
 	})
 }
 
+// hostMiddleware передает хост запроса в контекст, по нему выбирается домен коротких ссылок
+func hostMiddleware(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, r.WithContext(app.WithHost(r.Context(), r.Host)))
+	})
+}
+
 func ensureRandom() (res uuid.UUID) {
 	for i := 0; i < 10; i++ {
 		res = uuid.Must(uuid.NewV4())
@@ -1,15 +1,20 @@
 package main
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
 
 	"github.com/gofrs/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/app"
+	rest "github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/app/http"
 	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/auth"
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/store"
 )
 
 func Test_authMiddleware(t *testing.T) {
@@ -55,3 +60,64 @@ func Test_authMiddleware(t *testing.T) {
 		assert.Empty(t, w.Header().Get("Set-Cookie"))
 	})
 }
+
+func Test_hostMiddleware(t *testing.T) {
+	instance := &app.Instance{
+		BaseURL: "http://localhost:8080",
+		Domains: map[string]string{"a.co": "https://a.co"},
+	}
+	r := httptest.NewRequest("GET", "http://a.co/x", nil)
+	w := httptest.NewRecorder()
+
+	mw := hostMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "a.co/x", instance.LinkID(r.Context(), "x"))
+	}))
+	mw.ServeHTTP(w, r)
+}
+
+func Test_newRouterPreview(t *testing.T) {
+	storage := store.NewInMemory()
+	u, _ := url.Parse("https://praktikum.yandex.ru/")
+	id, err := storage.Save(context.Background(), u)
+	require.NoError(t, err)
+	router := newRouter(&rest.Handler{Instance: &app.Instance{
+		BaseURL: "http://localhost:8080",
+		Store:   storage,
+	}})
+
+	r := httptest.NewRequest("GET", "http://localhost:8080/"+id+"+", nil)
+	r.Header.Set("Accept", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"original_url":"https://praktikum.yandex.ru/"`)
+
+	r = httptest.NewRequest("GET", "http://localhost:8080/"+id, nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+}
+
+func Test_newRouterPassthrough(t *testing.T) {
+	storage := store.NewInMemory()
+	u, _ := url.Parse("https://practicum.yandex.ru/docs")
+	_, err := storage.SaveLink(context.Background(), store.Link{ID: "docs", URL: u, Passthrough: store.PassthroughAll})
+	require.NoError(t, err)
+	router := newRouter(&rest.Handler{Instance: &app.Instance{
+		BaseURL: "http://localhost:8080",
+		Store:   storage,
+	}})
+
+	r := httptest.NewRequest("GET", "http://localhost:8080/docs/getting-started?lang=en", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+	assert.Equal(t, "https://practicum.yandex.ru/docs/getting-started?lang=en", w.Header().Get("Location"))
+
+	// the QR code route still wins over the passthrough path
+	r = httptest.NewRequest("GET", "http://localhost:8080/docs/qr", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Location"))
+}
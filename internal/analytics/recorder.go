@@ -0,0 +1,123 @@
+// Package analytics собирает переходы по коротким ссылкам и пакетами сохраняет их в хранилище.
+package analytics
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"time"
+
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/store"
+)
+
+// Значения по умолчанию для Recorder
+const (
+	DefaultBatchSize     = 100         // DefaultBatchSize сколько переходов сохранять за раз
+	DefaultFlushInterval = time.Second // DefaultFlushInterval как часто сохранять неполный пакет
+	bufferBatches        = 10          // bufferBatches размер очереди в пакетах
+)
+
+// ClickSaver хранилище переходов
+type ClickSaver interface {
+	SaveClicks(ctx context.Context, clicks []store.Click) error
+}
+
+// Recorder асинхронно записывает переходы по ссылкам.
+// Record не блокирует обработчик запроса: переходы копятся в очереди
+// и сохраняются пакетами по batchSize штук или раз в flushInterval.
+// Если очередь переполнена, переход отбрасывается.
+type Recorder struct {
+	saver         ClickSaver
+	events        chan store.Click
+	batchSize     int
+	flushInterval time.Duration
+}
+
+// NewRecorder создает Recorder, сохраняющий переходы в saver.
+// Нулевые batchSize и flushInterval заменяются значениями по умолчанию.
+func NewRecorder(saver ClickSaver, batchSize int, flushInterval time.Duration) *Recorder {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	if flushInterval <= 0 {
+		flushInterval = DefaultFlushInterval
+	}
+	return &Recorder{
+		saver:         saver,
+		events:        make(chan store.Click, batchSize*bufferBatches),
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+	}
+}
+
+// Record ставит переход в очередь на сохранение.
+// Возвращает false, если очередь переполнена и переход отброшен.
+// У nil Recorder ничего не делает, чтобы приложение работало и без аналитики.
+func (r *Recorder) Record(click store.Click) bool {
+	if r == nil {
+		return false
+	}
+	select {
+	case r.events <- click:
+		return true
+	default:
+		return false
+	}
+}
+
+// Run сохраняет переходы из очереди до отмены контекста,
+// после чего сохраняет то, что успело накопиться, и возвращается.
+func (r *Recorder) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]store.Click, 0, r.batchSize)
+	flush := func(ctx context.Context) {
+		if len(batch) == 0 {
+			return
+		}
+		if err := r.saver.SaveClicks(ctx, batch); err != nil {
+			log.Printf("cannot save %d clicks: %v\n", len(batch), err)
+		}
+		batch = make([]store.Click, 0, r.batchSize)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			// the recorder is stopping, the tail is saved without the cancelled context
+			for {
+				select {
+				case click := <-r.events:
+					batch = append(batch, click)
+					if len(batch) == r.batchSize {
+						flush(context.Background())
+					}
+				default:
+					flush(context.Background())
+					return
+				}
+			}
+		case click := <-r.events:
+			batch = append(batch, click)
+			if len(batch) == r.batchSize {
+				flush(ctx)
+			}
+		case <-ticker.C:
+			flush(ctx)
+		}
+	}
+}
+
+// HashIP возвращает HMAC-SHA256 адреса клиента с ключом key,
+// чтобы считать уникальных посетителей, не храня сами адреса
+func HashIP(key []byte, ip string) string {
+	if ip == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(ip))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,111 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/store"
+)
+
+// saverFunc адаптер, позволяющий использовать функцию как ClickSaver
+type saverFunc func(ctx context.Context, clicks []store.Click) error
+
+func (fn saverFunc) SaveClicks(ctx context.Context, clicks []store.Click) error {
+	return fn(ctx, clicks)
+}
+
+// collector запоминает сохраненные пакеты
+type collector struct {
+	mu      sync.Mutex
+	batches [][]store.Click
+}
+
+func (c *collector) SaveClicks(_ context.Context, clicks []store.Click) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.batches = append(c.batches, clicks)
+	return nil
+}
+
+func (c *collector) sizes() []int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var res []int
+	for _, b := range c.batches {
+		res = append(res, len(b))
+	}
+	return res
+}
+
+func TestRecorder_BatchSize(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := &collector{}
+	r := NewRecorder(c, 3, time.Hour)
+	go r.Run(ctx)
+
+	for i := 0; i < 6; i++ {
+		assert.True(t, r.Record(store.Click{ID: "abc"}))
+	}
+	assert.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual([]int{3, 3}, c.sizes())
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestRecorder_FlushInterval(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := &collector{}
+	r := NewRecorder(c, 100, 10*time.Millisecond)
+	go r.Run(ctx)
+
+	r.Record(store.Click{ID: "abc"})
+	assert.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual([]int{1}, c.sizes())
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestRecorder_FlushOnStop(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	c := &collector{}
+	r := NewRecorder(c, 100, time.Hour)
+	for i := 0; i < 5; i++ {
+		r.Record(store.Click{ID: "abc"})
+	}
+	cancel()
+	// Run returns only after the queued clicks are saved
+	r.Run(ctx)
+	assert.Equal(t, []int{5}, c.sizes())
+}
+
+func TestRecorder_Overflow(t *testing.T) {
+	r := NewRecorder(saverFunc(func(context.Context, []store.Click) error {
+		return errors.New("unreachable")
+	}), 1, time.Hour)
+	for i := 0; i < bufferBatches; i++ {
+		assert.True(t, r.Record(store.Click{}))
+	}
+	assert.False(t, r.Record(store.Click{}))
+}
+
+func TestRecorder_Nil(t *testing.T) {
+	var r *Recorder
+	assert.False(t, r.Record(store.Click{}))
+}
+
+func TestHashIP(t *testing.T) {
+	key := []byte("secret")
+	assert.Equal(t, HashIP(key, "10.0.0.1"), HashIP(key, "10.0.0.1"))
+	assert.NotEqual(t, HashIP(key, "10.0.0.1"), HashIP(key, "10.0.0.2"))
+	assert.NotEqual(t, HashIP(key, "10.0.0.1"), HashIP([]byte("other"), "10.0.0.1"))
+	assert.NotContains(t, HashIP(key, "10.0.0.1"), "10.0.0.1")
+	assert.Empty(t, HashIP(key, ""))
+}
@@ -0,0 +1,76 @@
+package analytics
+
+import (
+	"sort"
+	"time"
+
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/store"
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/models"
+)
+
+// DateLayout формат дней в статистике
+const DateLayout = "2006-01-02"
+
+// TopSize сколько самых частых источников и клиентов попадает в статистику
+const TopSize = 10
+
+// Summarize считает статистику переходов по ссылке id за дни с from по to.
+// from и to должны быть началами дней по UTC, to не включается.
+func Summarize(id string, clicks []store.Click, from, to time.Time) models.LinkStats {
+	stats := models.LinkStats{
+		ID:          id,
+		TotalClicks: len(clicks),
+		Daily:       []models.DailyClicks{},
+	}
+
+	visitors := make(map[string]struct{})
+	daily := make(map[string]int)
+	referrers := make(map[string]int)
+	userAgents := make(map[string]int)
+	variants := make(map[string]int)
+	for _, c := range clicks {
+		if c.IPHash != "" {
+			visitors[c.IPHash] = struct{}{}
+		}
+		daily[c.Time.UTC().Format(DateLayout)]++
+		if c.Referrer != "" {
+			referrers[c.Referrer]++
+		}
+		if c.UserAgent != "" {
+			userAgents[c.UserAgent]++
+		}
+		if c.Variant != "" {
+			variants[c.Variant]++
+		}
+	}
+	stats.UniqueVisitors = len(visitors)
+
+	for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+		date := day.Format(DateLayout)
+		stats.Daily = append(stats.Daily, models.DailyClicks{Date: date, Clicks: daily[date]})
+	}
+	stats.TopReferrers = top(referrers, TopSize)
+	stats.TopUserAgents = top(userAgents, TopSize)
+	if len(variants) > 0 {
+		stats.Variants = top(variants, len(variants))
+	}
+	return stats
+}
+
+// top возвращает n самых частых значений, при равенстве по алфавиту
+func top(counts map[string]int, n int) []models.TopValue {
+	res := make([]models.TopValue, 0, len(counts))
+	for value, clicks := range counts {
+		res = append(res, models.TopValue{Value: value, Clicks: clicks})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Clicks != res[j].Clicks {
+			return res[i].Clicks > res[j].Clicks
+		}
+		return res[i].Value < res[j].Value
+	})
+	if len(res) > n {
+		res = res[:n]
+	}
+	return res
+}
@@ -0,0 +1,70 @@
+package analytics
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/store"
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/models"
+)
+
+func TestSummarize(t *testing.T) {
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 3)
+	clicks := []store.Click{
+		{Time: from.Add(time.Hour), Referrer: "https://ya.ru/", UserAgent: "curl/8.0", IPHash: "a"},
+		{Time: from.Add(2 * time.Hour), Referrer: "https://ya.ru/", UserAgent: "Firefox", IPHash: "a"},
+		{Time: from.Add(50 * time.Hour), Referrer: "https://google.com/", UserAgent: "Firefox", IPHash: "b"},
+		{Time: from.Add(51 * time.Hour)},
+	}
+
+	got := Summarize("abc", clicks, from, to)
+	assert.Equal(t, models.LinkStats{
+		ID:             "abc",
+		TotalClicks:    4,
+		UniqueVisitors: 2,
+		Daily: []models.DailyClicks{
+			{Date: "2024-03-01", Clicks: 2},
+			{Date: "2024-03-02", Clicks: 0},
+			{Date: "2024-03-03", Clicks: 2},
+		},
+		TopReferrers: []models.TopValue{
+			{Value: "https://ya.ru/", Clicks: 2},
+			{Value: "https://google.com/", Clicks: 1},
+		},
+		TopUserAgents: []models.TopValue{
+			{Value: "Firefox", Clicks: 2},
+			{Value: "curl/8.0", Clicks: 1},
+		},
+	}, got)
+}
+
+func TestSummarize_Variants(t *testing.T) {
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	clicks := []store.Click{
+		{Time: from, Variant: "B"},
+		{Time: from, Variant: "A"},
+		{Time: from, Variant: "B"},
+	}
+
+	got := Summarize("abc", clicks, from, from.AddDate(0, 0, 1))
+	assert.Equal(t, []models.TopValue{{Value: "B", Clicks: 2}, {Value: "A", Clicks: 1}}, got.Variants)
+
+	got = Summarize("abc", []store.Click{{Time: from}}, from, from.AddDate(0, 0, 1))
+	assert.Nil(t, got.Variants, "links without a split have no breakdown")
+}
+
+func TestSummarize_Top(t *testing.T) {
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	var clicks []store.Click
+	for i := 0; i < TopSize+5; i++ {
+		clicks = append(clicks, store.Click{Time: from, Referrer: "https://site" + strconv.Itoa(i) + ".ru/"})
+	}
+
+	got := Summarize("abc", clicks, from, from.AddDate(0, 0, 1))
+	assert.Len(t, got.TopReferrers, TopSize)
+	assert.Empty(t, got.TopUserAgents)
+}
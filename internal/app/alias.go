@@ -0,0 +1,38 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Ограничения длины пользовательского идентификатора
+const (
+	MinAliasLength = 3  // MinAliasLength минимальная длина
+	MaxAliasLength = 64 // MaxAliasLength максимальная длина
+)
+
+// ReservedAliases идентификаторы, которые нельзя занять, чтобы они не перекрывали маршруты приложения
+var ReservedAliases = []string{"api", "ping", "admin", "static", "health", "metrics", "debug"}
+
+// ValidateAlias проверяет, что пользовательский идентификатор состоит из латинских букв,
+// цифр, '-' и '_', подходит по длине и не входит в список зарезервированных
+func ValidateAlias(alias string) error {
+	if len(alias) < MinAliasLength || len(alias) > MaxAliasLength {
+		return fmt.Errorf("%w: length must be between %d and %d", ErrAlias, MinAliasLength, MaxAliasLength)
+	}
+	for _, c := range alias {
+		if !isAliasChar(c) {
+			return fmt.Errorf("%w: character %q is not allowed", ErrAlias, c)
+		}
+	}
+	for _, reserved := range ReservedAliases {
+		if strings.EqualFold(alias, reserved) {
+			return fmt.Errorf("%w: %q is reserved", ErrAlias, alias)
+		}
+	}
+	return nil
+}
+
+func isAliasChar(c rune) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_'
+}
@@ -0,0 +1,37 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateAlias(t *testing.T) {
+	tests := []struct {
+		name    string
+		alias   string
+		wantErr bool
+	}{
+		{name: "valid", alias: "spring-sale_2024"},
+		{name: "min length", alias: "abc"},
+		{name: "max length", alias: strings.Repeat("a", MaxAliasLength)},
+		{name: "too short", alias: "ab", wantErr: true},
+		{name: "too long", alias: strings.Repeat("a", MaxAliasLength+1), wantErr: true},
+		{name: "slash", alias: "spring/sale", wantErr: true},
+		{name: "non latin", alias: "распродажа", wantErr: true},
+		{name: "space", alias: "spring sale", wantErr: true},
+		{name: "reserved", alias: "api", wantErr: true},
+		{name: "reserved any case", alias: "Ping", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateAlias(tt.alias)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrAlias)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
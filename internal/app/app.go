@@ -1,16 +1,32 @@
 package app
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/analytics"
 	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/store"
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/urlcheck"
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/urlpolicy"
 	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/models"
 )
 
 // Instance структура для хранения информации о приложении, включая хранилище
 type Instance struct {
 	BaseURL string
+	Domains map[string]string // Domains базовые URL дополнительных доменов коротких ссылок по хостам
 
 	Store      store.AuthStore
 	RemoveChan chan models.BatchRemoveRequest
+	Clicks     *analytics.Recorder // Clicks запись переходов по ссылкам, nil если аналитика не нужна
+	Policy     *urlpolicy.Policy   // Policy правила проверки сокращаемых адресов, nil - правила по умолчанию
+	URLChecker urlcheck.Checker    // URLChecker проверки безопасности адресов, nil если проверять не нужно
+
+	attempts attemptLimiter // attempts неудачные попытки ввода пароля по ссылкам
 }
 
 // NewInstance функция создания новой структуры приложения
@@ -21,3 +37,52 @@ func NewInstance(baseURL string, storage store.AuthStore, removeChan chan models
 		RemoveChan: removeChan,
 	}
 }
+
+// parseURL разбирает сокращаемый адрес и приводит его к каноническому виду по правилам Policy.
+// Строка, которая не разбирается как URL, дает ErrParseURL, нарушения правил - *urlpolicy.Error.
+func (i *Instance) parseURL(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return nil, ErrParseURL
+	}
+	policy := i.Policy
+	if policy == nil {
+		policy = &urlpolicy.Policy{}
+	}
+	return policy.Apply(u)
+}
+
+// targetURL разбирает сокращаемый адрес по правилам Policy и проверяет его через URLChecker.
+// Запрещенный адрес дает ошибку, оборачивающую urlcheck.ErrBlocked.
+func (i *Instance) targetURL(ctx context.Context, rawURL string) (*url.URL, error) {
+	u, err := i.parseURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if i.URLChecker == nil {
+		return u, nil
+	}
+	if err := i.URLChecker.Check(ctx, u); err != nil {
+		if errors.Is(err, urlcheck.ErrBlocked) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("cannot check URL: %w", err)
+	}
+	return u, nil
+}
+
+// blocked сообщает, запрещен ли адрес сохраненной ссылки. Ошибки проверки не мешают переходу
+// по ссылке и только записываются в журнал.
+func (i *Instance) blocked(ctx context.Context, u *url.URL) error {
+	if i.URLChecker == nil {
+		return nil
+	}
+	err := i.URLChecker.Check(ctx, u)
+	if errors.Is(err, urlcheck.ErrBlocked) {
+		return err
+	}
+	if err != nil {
+		log.Printf("cannot check URL %s: %v\n", u, err)
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/analytics"
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/config"
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/store"
+)
+
+// Visit сведения о клиенте, перешедшем по короткой ссылке
+type Visit struct {
+	Referrer  string
+	UserAgent string
+	IP        string
+	Variant   string // Variant название варианта ссылки с A/B-тестом, по которому перешел клиент
+}
+
+// RecordClick ставит переход по ссылке с кодом id на домене запроса в очередь на сохранение.
+// Адрес клиента сохраняется только в виде хеша.
+func (i *Instance) RecordClick(ctx context.Context, id string, v Visit) {
+	i.Clicks.Record(store.Click{
+		Time:      time.Now(),
+		ID:        i.LinkID(ctx, id),
+		Referrer:  v.Referrer,
+		UserAgent: v.UserAgent,
+		IPHash:    analytics.HashIP(config.AuthSecret, v.IP),
+		Variant:   v.Variant,
+	})
+}
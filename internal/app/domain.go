@@ -0,0 +1,112 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/store"
+)
+
+// hostKey ключ контекста с хостом запроса
+type hostKey struct{}
+
+// WithHost возвращает контекст с хостом запроса. По хосту выбирается домен,
+// на котором создаются ссылки и ищутся их коды.
+func WithHost(ctx context.Context, host string) context.Context {
+	return context.WithValue(ctx, hostKey{}, host)
+}
+
+// domainKey ключ контекста с доменом, выбранным владельцем ссылок
+type domainKey struct{}
+
+// WithDomain возвращает контекст, в котором коды ссылок ищутся на выбранном домене picked, а не на домене запроса.
+// Так владелец управляет ссылками на любом домене сервиса. Пустой picked оставляет домен запроса.
+func (i *Instance) WithDomain(ctx context.Context, picked string) (context.Context, error) {
+	if picked == "" {
+		return ctx, nil
+	}
+	domain, err := i.linkDomain(ctx, picked)
+	if err != nil {
+		return ctx, err
+	}
+	return context.WithValue(ctx, domainKey{}, domain), nil
+}
+
+// NewDomains разбирает базовые URL дополнительных доменов коротких ссылок
+// и возвращает их по хостам в нижнем регистре
+func NewDomains(baseURLs []string) (map[string]string, error) {
+	domains := make(map[string]string, len(baseURLs))
+	for _, raw := range baseURLs {
+		raw = strings.TrimRight(strings.TrimSpace(raw), "/")
+		if raw == "" {
+			continue
+		}
+		u, err := url.Parse(raw)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("bad short domain base URL %q", raw)
+		}
+		domains[strings.ToLower(u.Host)] = raw
+	}
+	return domains, nil
+}
+
+// LinkID возвращает идентификатор ссылки с кодом code на выбранном домене или на домене запроса из контекста
+func (i *Instance) LinkID(ctx context.Context, code string) string {
+	if domain, ok := ctx.Value(domainKey{}).(string); ok {
+		return store.DomainID(domain, code)
+	}
+	host, _ := ctx.Value(hostKey{}).(string)
+	return store.DomainID(i.domain(host), code)
+}
+
+// linkDomain возвращает домен новой ссылки: выбранный пользователем, а если он не выбран - домен запроса.
+// Домен по умолчанию обозначается пустой строкой.
+func (i *Instance) linkDomain(ctx context.Context, picked string) (string, error) {
+	if picked == "" {
+		host, _ := ctx.Value(hostKey{}).(string)
+		return i.domain(host), nil
+	}
+	picked = strings.ToLower(picked)
+	if u, err := url.Parse(i.BaseURL); err == nil && strings.ToLower(u.Host) == picked {
+		return "", nil
+	}
+	if domain := i.domain(picked); domain != "" {
+		return domain, nil
+	}
+	return "", fmt.Errorf("%w: %q is not served", ErrDomain, picked)
+}
+
+// domain возвращает дополнительный домен, которому соответствует хост, или пустую строку
+// для домена по умолчанию. Хост ищется сначала с портом, затем без него.
+func (i *Instance) domain(host string) string {
+	host = strings.ToLower(host)
+	if _, ok := i.Domains[host]; ok {
+		return host
+	}
+	if j := strings.LastIndexByte(host, ':'); j >= 0 && !strings.HasSuffix(host, "]") {
+		if _, ok := i.Domains[host[:j]]; ok {
+			return host[:j]
+		}
+	}
+	return ""
+}
+
+// shortURL возвращает короткую ссылку для идентификатора id на домене ссылки
+func (i *Instance) shortURL(id string) string {
+	domain, code := store.SplitID(id)
+	if domain == "" {
+		return i.BaseURL + "/" + code
+	}
+	base, ok := i.Domains[domain]
+	if !ok {
+		// the domain is no longer configured, keep the scheme of the default one
+		scheme := "http"
+		if u, err := url.Parse(i.BaseURL); err == nil && u.Scheme != "" {
+			scheme = u.Scheme
+		}
+		base = scheme + "://" + domain
+	}
+	return base + "/" + code
+}
@@ -0,0 +1,168 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/auth"
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/store"
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/models"
+)
+
+func TestNewDomains(t *testing.T) {
+	domains, err := NewDomains([]string{"https://A.co/", " http://b.co:8081", ""})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"a.co": "https://A.co", "b.co:8081": "http://b.co:8081"}, domains)
+
+	_, err = NewDomains([]string{"b.co"})
+	assert.Error(t, err)
+}
+
+func TestInstance_LinkID(t *testing.T) {
+	instance := &Instance{
+		BaseURL: "http://localhost:8080",
+		Domains: map[string]string{"a.co": "https://a.co", "b.co:8081": "http://b.co:8081"},
+	}
+	tests := []struct {
+		host string
+		want string
+	}{
+		{host: "", want: "x"},
+		{host: "localhost:8080", want: "x"},
+		{host: "unknown.co", want: "x"},
+		{host: "A.co", want: "a.co/x"},
+		{host: "a.co:443", want: "a.co/x"},
+		{host: "b.co:8081", want: "b.co:8081/x"},
+		{host: "b.co", want: "x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			assert.Equal(t, tt.want, instance.LinkID(WithHost(context.Background(), tt.host), "x"))
+		})
+	}
+	assert.Equal(t, "x", instance.LinkID(context.Background(), "x"))
+
+	assert.Equal(t, "http://localhost:8080/x", instance.shortURL("x"))
+	assert.Equal(t, "https://a.co/x", instance.shortURL("a.co/x"))
+	assert.Equal(t, "http://gone.co/x", instance.shortURL("gone.co/x"))
+}
+
+func TestInstance_Domains(t *testing.T) {
+	uid := uuid.Must(uuid.NewV4())
+	instance := &Instance{
+		BaseURL: "http://localhost:8080",
+		Domains: map[string]string{"a.co": "https://a.co", "b.co": "https://b.co"},
+		Store:   store.NewInMemory(),
+	}
+	ctx := auth.Context(context.Background(), uid)
+	onA := WithHost(ctx, "a.co")
+	onB := WithHost(ctx, "b.co")
+
+	// the same code points to different targets on different domains
+	shortA, err := instance.ShortenLink(onA, models.ShortenRequest{URL: "https://practicum.yandex.ru/", Alias: "sale"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://a.co/sale", shortA)
+	shortB, err := instance.ShortenLink(onB, models.ShortenRequest{URL: "https://ya.ru/", Alias: "sale"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://b.co/sale", shortB)
+	_, err = instance.ShortenLink(onB, models.ShortenRequest{URL: "https://go.dev/", Alias: "sale"})
+	assert.ErrorIs(t, err, store.ErrIDTaken)
+
+	link, err := instance.Expand(onA, "sale", "")
+	require.NoError(t, err)
+	assert.Equal(t, "https://practicum.yandex.ru/", link.URL.String())
+	link, err = instance.Expand(onB, "sale", "")
+	require.NoError(t, err)
+	assert.Equal(t, "https://ya.ru/", link.URL.String())
+	_, err = instance.Expand(ctx, "sale", "")
+	assert.ErrorIs(t, err, store.ErrNotFound)
+
+	// plain links are created on the request domain, or on the one the user picked
+	plain, err := instance.Shorten(onA, "https://go.dev/")
+	require.NoError(t, err)
+	assert.Contains(t, plain, "https://a.co/")
+	picked, err := instance.ShortenLink(ctx, models.ShortenRequest{URL: "https://go.dev/", LinkOptions: models.LinkOptions{Domain: "B.co"}})
+	require.NoError(t, err)
+	assert.Contains(t, picked, "https://b.co/")
+	def, err := instance.ShortenLink(onA, models.ShortenRequest{URL: "https://go.dev/", LinkOptions: models.LinkOptions{Domain: "localhost:8080"}})
+	require.NoError(t, err)
+	assert.Contains(t, def, "http://localhost:8080/")
+	_, err = instance.ShortenLink(ctx, models.ShortenRequest{URL: "https://go.dev/", LinkOptions: models.LinkOptions{Domain: "evil.co"}})
+	assert.ErrorIs(t, err, ErrDomain)
+
+	batch, err := instance.BatchShorten([]models.BatchShortenRequest{
+		{CorrelationID: "1", OriginalURL: "https://go.dev/doc/"},
+		{CorrelationID: "2", OriginalURL: "https://go.dev/blog/", LinkOptions: models.LinkOptions{Domain: "a.co"}},
+	}, onB)
+	require.NoError(t, err)
+	assert.Contains(t, batch[0].ShortURL, "https://b.co/")
+	assert.Contains(t, batch[1].ShortURL, "https://a.co/")
+
+	links, _, err := instance.LoadUsers(ctx, store.LinkFilter{})
+	require.NoError(t, err)
+	var short []string
+	for _, link := range links {
+		short = append(short, link.ShortURL)
+	}
+	assert.Contains(t, short, "https://a.co/sale")
+	assert.Contains(t, short, "https://b.co/sale")
+
+	resp, err := instance.UpdateURL(onB, "sale", models.UpdateURLRequest{OriginalURL: "https://ya.ru/search"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://b.co/sale", resp.ShortURL)
+	link, err = instance.Expand(onA, "sale", "")
+	require.NoError(t, err)
+	assert.Equal(t, "https://practicum.yandex.ru/", link.URL.String())
+}
+
+func TestInstance_WithDomain(t *testing.T) {
+	uid := uuid.Must(uuid.NewV4())
+	instance := &Instance{
+		BaseURL: "http://localhost:8080",
+		Domains: map[string]string{"a.co": "https://a.co"},
+		Store:   store.NewInMemory(),
+	}
+	ctx := auth.Context(WithHost(context.Background(), "localhost:8080"), uid)
+
+	// the link is created from the API host on a picked domain
+	short, err := instance.ShortenLink(ctx, models.ShortenRequest{
+		URL:         "https://go.dev/",
+		Alias:       "docs",
+		LinkOptions: models.LinkOptions{Domain: "a.co"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "https://a.co/docs", short)
+
+	_, err = instance.History(ctx, "docs")
+	assert.ErrorIs(t, err, store.ErrNotFound)
+
+	onA, err := instance.WithDomain(ctx, "A.co")
+	require.NoError(t, err)
+	assert.Equal(t, "a.co/docs", instance.LinkID(onA, "docs"))
+	resp, err := instance.UpdateURL(onA, "docs", models.UpdateURLRequest{OriginalURL: "https://go.dev/doc/"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://a.co/docs", resp.ShortURL)
+	history, err := instance.History(onA, "docs")
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, "https://go.dev/", history[0].OriginalURL)
+	from, to, err := StatsWindow("", "", time.Now())
+	require.NoError(t, err)
+	_, err = instance.LinkStats(onA, "docs", from, to)
+	assert.NoError(t, err)
+
+	// the picked domain wins over the request host, the default one is picked by its host
+	onDefault, err := instance.WithDomain(WithHost(ctx, "a.co"), "localhost:8080")
+	require.NoError(t, err)
+	assert.Equal(t, "docs", instance.LinkID(onDefault, "docs"))
+	same, err := instance.WithDomain(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, "docs", instance.LinkID(same, "docs"))
+	_, err = instance.WithDomain(ctx, "evil.co")
+	assert.ErrorIs(t, err, ErrDomain)
+}
@@ -4,7 +4,41 @@ import "errors"
 
 // Типы пользовательских ошибок
 var (
-	ErrAuth      = errors.New("auth unprocessed")                 // ErrAuth ошибка авторизации
-	ErrParseURL  = errors.New("cannot parse given string as URL") // ErrParseURL ошибка парсинга строки в URL
-	ErrURLLength = errors.New("invalid shorten URLs length")      //ErrURLLength ошибка длины ссылки
+	ErrAuth             = errors.New("auth unprocessed")                 // ErrAuth ошибка авторизации
+	ErrParseURL         = errors.New("cannot parse given string as URL") // ErrParseURL ошибка парсинга строки в URL
+	ErrURLLength        = errors.New("invalid shorten URLs length")      //ErrURLLength ошибка длины ссылки
+	ErrAlias            = errors.New("invalid alias")                    // ErrAlias недопустимый пользовательский идентификатор
+	ErrExpiry           = errors.New("invalid expiry")                   // ErrExpiry недопустимый срок действия ссылки
+	ErrStatsWindow      = errors.New("invalid stats period")             // ErrStatsWindow недопустимый период статистики
+	ErrQR               = errors.New("invalid QR code parameters")       // ErrQR недопустимые параметры QR-кода
+	ErrPassword         = errors.New("invalid password")                 // ErrPassword недопустимый пароль ссылки
+	ErrMaxClicks        = errors.New("invalid max clicks")               // ErrMaxClicks недопустимое ограничение числа переходов
+	ErrMeta             = errors.New("invalid link description")         // ErrMeta недопустимое название, заметки или метки ссылки
+	ErrPage             = errors.New("invalid page parameters")          // ErrPage недопустимые параметры списка ссылок
+	ErrDomain           = errors.New("unknown short domain")             // ErrDomain домен коротких ссылок не обслуживается
+	ErrRedirect         = errors.New("invalid redirect mode")            // ErrRedirect недопустимый способ перехода по ссылке
+	ErrPassthrough      = errors.New("invalid passthrough mode")         // ErrPassthrough недопустимый режим переноса частей запроса
+	ErrTemplate         = errors.New("invalid link template")            // ErrTemplate недопустимый адрес ссылки-шаблона
+	ErrTemplateValues   = errors.New("cannot fill link template")        // ErrTemplateValues в запросе нет допустимых значений для шаблона
+	ErrRules            = errors.New("invalid redirect rules")           // ErrRules недопустимые правила перехода по ссылке
+	ErrVariants         = errors.New("invalid split variants")           // ErrVariants недопустимые варианты A/B-теста
+	ErrPasswordRequired = errors.New("password required")                // ErrPasswordRequired ссылка защищена паролем
+	ErrWrongPassword    = errors.New("wrong password")                   // ErrWrongPassword неверный пароль ссылки
+	ErrTooManyAttempts  = errors.New("too many password attempts")       // ErrTooManyAttempts превышено число попыток ввода пароля
 )
+
+// invalidLinkErrs ошибки недопустимых параметров создаваемой ссылки
+var invalidLinkErrs = []error{
+	ErrAlias, ErrExpiry, ErrPassword, ErrMaxClicks, ErrMeta, ErrDomain,
+	ErrRedirect, ErrPassthrough, ErrTemplate, ErrRules, ErrVariants,
+}
+
+// IsInvalidLink сообщает, вызвана ли ошибка создания ссылки недопустимыми параметрами запроса
+func IsInvalidLink(err error) bool {
+	for _, e := range invalidLinkErrs {
+		if errors.Is(err, e) {
+			return true
+		}
+	}
+	return false
+}
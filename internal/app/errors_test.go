@@ -0,0 +1,32 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsInvalidLink(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil},
+		{name: "alias", err: ValidateAlias("ab"), want: true},
+		{name: "wrapped", err: fmt.Errorf("url 2: %w", fmt.Errorf("%w: too long", ErrMeta)), want: true},
+		{name: "variants", err: ErrVariants, want: true},
+		{name: "parse url", err: ErrParseURL},
+		{name: "other", err: errors.New("storage is down")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsInvalidLink(tt.err))
+		})
+	}
+	for _, e := range invalidLinkErrs {
+		assert.True(t, IsInvalidLink(fmt.Errorf("wrapped: %w", e)), e.Error())
+	}
+}
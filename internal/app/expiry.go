@@ -0,0 +1,29 @@
+package app
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/models"
+)
+
+// ExpiresAt вычисляет момент истечения срока действия ссылки относительно now.
+// Возвращает nil, если срок не задан.
+func ExpiresAt(opts models.LinkOptions, now time.Time) (*time.Time, error) {
+	switch {
+	case opts.ExpiresAt != nil && opts.TTL != 0:
+		return nil, fmt.Errorf("%w: expires_at and ttl are mutually exclusive", ErrExpiry)
+	case opts.TTL < 0:
+		return nil, fmt.Errorf("%w: ttl must be positive", ErrExpiry)
+	case opts.TTL > 0:
+		expiresAt := now.Add(time.Duration(opts.TTL) * time.Second)
+		return &expiresAt, nil
+	case opts.ExpiresAt != nil:
+		if !opts.ExpiresAt.After(now) {
+			return nil, fmt.Errorf("%w: expires_at must be in the future", ErrExpiry)
+		}
+		expiresAt := *opts.ExpiresAt
+		return &expiresAt, nil
+	}
+	return nil, nil
+}
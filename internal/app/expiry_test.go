@@ -0,0 +1,43 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/models"
+)
+
+func TestExpiresAt(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	future := now.Add(time.Hour)
+	past := now.Add(-time.Hour)
+
+	tests := []struct {
+		name    string
+		opts    models.LinkOptions
+		want    *time.Time
+		wantErr bool
+	}{
+		{name: "no expiry", opts: models.LinkOptions{}},
+		{name: "ttl", opts: models.LinkOptions{TTL: 3600}, want: &future},
+		{name: "expires at", opts: models.LinkOptions{ExpiresAt: &future}, want: &future},
+		{name: "negative ttl", opts: models.LinkOptions{TTL: -1}, wantErr: true},
+		{name: "expires at in the past", opts: models.LinkOptions{ExpiresAt: &past}, wantErr: true},
+		{name: "expires at now", opts: models.LinkOptions{ExpiresAt: &now}, wantErr: true},
+		{name: "both", opts: models.LinkOptions{ExpiresAt: &future, TTL: 60}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExpiresAt(tt.opts, now)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrExpiry)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
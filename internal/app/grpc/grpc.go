@@ -5,14 +5,24 @@ import (
 	"errors"
 	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/app"
 	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/auth"
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/store"
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/urlcheck"
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/urlpolicy"
 	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/models"
 	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/pkg/shortener"
 	"github.com/gofrs/uuid"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Server структура grpc сервера
@@ -23,10 +33,27 @@ type Server struct {
 
 // Shorten обработчик запроса на сокращение ссылок
 func (s *Server) Shorten(ctx context.Context, request *shortener.ShortenRequest) (*shortener.ShortenResponse, error) {
-	shorten, err := s.instance.Shorten(ctx, request.Url)
+	shorten, err := s.instance.ShortenLink(ctx, models.ShortenRequest{
+		URL:         request.Url,
+		Alias:       request.Alias,
+		LinkOptions: linkOptions(request),
+	})
+	if errors.Is(err, urlcheck.ErrBlocked) {
+		return nil, status.Errorf(codes.PermissionDenied, err.Error())
+	}
+	var perr *urlpolicy.Error
+	if errors.As(err, &perr) {
+		return nil, status.Errorf(codes.InvalidArgument, err.Error())
+	}
 	if err != nil && errors.Is(err, app.ErrParseURL) {
 		return nil, status.Errorf(codes.InvalidArgument, app.ErrParseURL.Error())
 	}
+	if app.IsInvalidLink(err) {
+		return nil, status.Errorf(codes.InvalidArgument, err.Error())
+	}
+	if errors.Is(err, store.ErrIDTaken) {
+		return nil, status.Errorf(codes.AlreadyExists, "alias is already taken")
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -41,15 +68,27 @@ func (s *Server) BatchShorten(ctx context.Context, req *shortener.BatchShortenRe
 		batchReq := models.BatchShortenRequest{
 			CorrelationID: r.CorrelationId,
 			OriginalURL:   r.OriginalUrl,
+			LinkOptions:   linkOptions(r),
 		}
 		batch = append(batch, batchReq)
 	}
 
 	shorten, err := s.instance.BatchShorten(batch, ctx)
+	if errors.Is(err, urlcheck.ErrBlocked) {
+		return nil, status.Errorf(codes.PermissionDenied, err.Error())
+	}
+	var perr *urlpolicy.Error
+	if errors.As(err, &perr) {
+		return nil, status.Errorf(codes.InvalidArgument, err.Error())
+	}
 	if errors.Is(err, app.ErrParseURL) {
 		return nil, status.Errorf(codes.InvalidArgument, "Cannot parse given string as URL")
 	}
 
+	if app.IsInvalidLink(err) {
+		return nil, status.Errorf(codes.InvalidArgument, err.Error())
+	}
+
 	if errors.Is(err, app.ErrURLLength) {
 		return nil, status.Errorf(codes.Internal, "invalid shorten URLs length")
 	}
@@ -68,13 +107,21 @@ func (s *Server) BatchShorten(ctx context.Context, req *shortener.BatchShortenRe
 }
 
 // BatchRemove пакетное удаление пользовательских ссылок ссылок
-func (s *Server) BatchRemove(_ context.Context, req *shortener.BatchRemoveRequest) (*emptypb.Empty, error) {
+func (s *Server) BatchRemove(ctx context.Context, req *shortener.BatchRemoveRequest) (*emptypb.Empty, error) {
 	id, err := uuid.FromString(req.Uuid)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "cannot convert user id")
 	}
+	ctx, err = s.instance.WithDomain(ctx, req.Domain)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, err.Error())
+	}
+	ids := make([]string, 0, len(req.Ids))
+	for _, code := range req.Ids {
+		ids = append(ids, s.instance.LinkID(ctx, code))
+	}
 	go func() {
-		s.instance.RemoveChan <- models.BatchRemoveRequest{UID: id, Ids: req.Ids}
+		s.instance.RemoveChan <- models.BatchRemoveRequest{UID: id, Ids: ids}
 	}()
 	return &emptypb.Empty{}, nil
 }
@@ -90,16 +137,118 @@ func (s *Server) Statistics(ctx context.Context, req *shortener.StatisticsReques
 	return &shortener.StatisticsResponse{Urls: uint32(statistics.Urls), Users: uint32(statistics.Users)}, nil
 }
 
-// Expand обработчик, возвращающий ссылку из хранилища
+// Expand обработчик, возвращающий ссылку из хранилища.
+// Для ссылки с паролем пароль передается в запросе. Способ перехода возвращается
+// в ответе, чтобы клиент мог повторить поведение HTTP-обработчика
 func (s *Server) Expand(ctx context.Context, req *shortener.UrlRequest) (*shortener.UrlResponse, error) {
-	loadURL, err := s.instance.LoadURL(ctx, req.Id)
+	md, _ := metadata.FromIncomingContext(ctx)
+	v := visit(ctx)
+	key := req.Visitor
+	if key == "" {
+		key = app.VisitorKey(v.IP)
+	}
+	ctx = app.WithVisitor(app.WithHeader(ctx, metadataHeader(md)), key)
+	link, err := s.instance.ExpandTail(ctx, req.Id, req.Password, app.Tail{Path: req.Path, RawQuery: req.Query})
+	if errors.Is(err, store.ErrExpired) {
+		return nil, status.Errorf(codes.FailedPrecondition, "link has expired")
+	}
+	if errors.Is(err, app.ErrPasswordRequired) {
+		return nil, status.Errorf(codes.Unauthenticated, err.Error())
+	}
+	if errors.Is(err, app.ErrWrongPassword) || errors.Is(err, urlcheck.ErrBlocked) {
+		return nil, status.Errorf(codes.PermissionDenied, err.Error())
+	}
+	if errors.Is(err, app.ErrTooManyAttempts) {
+		return nil, status.Errorf(codes.ResourceExhausted, err.Error())
+	}
+	if errors.Is(err, app.ErrTemplateValues) {
+		return nil, status.Errorf(codes.InvalidArgument, err.Error())
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, status.Errorf(codes.NotFound, err.Error())
+	}
+	if errors.Is(err, store.ErrDeleted) {
+		return nil, status.Errorf(codes.FailedPrecondition, "link is no longer available")
+	}
 	if err != nil {
 		return nil, err
 	}
-	return &shortener.UrlResponse{OriginalUrl: loadURL.String()}, nil
+	v.Variant = link.Variant
+	s.instance.RecordClick(ctx, req.Id, v)
+	resp := urlResponse(s.instance.LinkPreview(link))
+	resp.RedirectMode = string(link.Redirect)
+	resp.Variant = link.Variant
+	return resp, nil
+}
+
+// Preview обработчик, возвращающий сведения о ссылке без перехода по ней
+func (s *Server) Preview(ctx context.Context, req *shortener.UrlRequest) (*shortener.UrlResponse, error) {
+	preview, err := s.instance.Preview(ctx, req.Id)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, status.Errorf(codes.NotFound, err.Error())
+	}
+	if errors.Is(err, store.ErrDeleted) {
+		return nil, status.Errorf(codes.FailedPrecondition, "link is no longer available")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return urlResponse(preview), nil
+}
+
+func urlResponse(preview *models.LinkPreview) *shortener.UrlResponse {
+	resp := &shortener.UrlResponse{
+		ShortUrl:          preview.ShortURL,
+		Status:            preview.Status,
+		OriginalUrl:       preview.OriginalURL,
+		Title:             preview.Title,
+		PasswordProtected: preview.Protected,
+	}
+	if preview.CreatedAt != nil {
+		resp.CreatedAt = timestamppb.New(*preview.CreatedAt)
+	}
+	if preview.ExpiresAt != nil {
+		resp.ExpiresAt = timestamppb.New(*preview.ExpiresAt)
+	}
+	return resp
+}
+
+// metadataHeader переводит метаданные запроса в заголовки, по которым выбирается правило перехода.
+// Служебные ключи вида :authority пропускаются.
+func metadataHeader(md metadata.MD) http.Header {
+	h := make(http.Header, len(md))
+	for k, vals := range md {
+		if strings.HasPrefix(k, ":") {
+			continue
+		}
+		h[http.CanonicalHeaderKey(k)] = vals
+	}
+	return h
+}
+
+// visit собирает сведения о клиенте из метаданных и адреса соединения
+func visit(ctx context.Context) app.Visit {
+	var v app.Visit
+	md, _ := metadata.FromIncomingContext(ctx)
+	if vals := md.Get("referer"); len(vals) > 0 {
+		v.Referrer = vals[0]
+	}
+	if vals := md.Get("user-agent"); len(vals) > 0 {
+		v.UserAgent = vals[0]
+	}
+	if vals := md.Get("x-real-ip"); len(vals) > 0 {
+		v.IP = vals[0]
+	} else if p, ok := peer.FromContext(ctx); ok {
+		v.IP = p.Addr.String()
+		if host, _, err := net.SplitHostPort(v.IP); err == nil {
+			v.IP = host
+		}
+	}
+	return v
 }
 
-// UserUrls список ссылок пользователя
+// UserUrls список ссылок пользователя с отбором по метке и строке поиска.
+// При заданном limit возвращается страница ссылок и курсор следующей страницы.
 func (s *Server) UserUrls(ctx context.Context, req *shortener.UserUrlsRequest) (*shortener.UserUrlsResponse, error) {
 	md, _ := metadata.FromIncomingContext(ctx)
 	log.Println(md)
@@ -108,15 +257,169 @@ func (s *Server) UserUrls(ctx context.Context, req *shortener.UserUrlsRequest) (
 		return nil, status.Errorf(codes.Internal, "cannot convert user id")
 	}
 	userContext := auth.Context(ctx, id)
-	users, err := s.instance.LoadUsers(userContext)
+	var limit string
+	if req.Limit > 0 {
+		limit = strconv.Itoa(int(req.Limit))
+	}
+	filter, err := app.ParseLinkQuery(req.Tag, req.Search, req.Sort, req.Order, limit, req.Cursor)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, err.Error())
+	}
+	users, next, err := s.instance.LoadUsers(userContext, filter)
 	if err != nil {
 		return nil, status.Errorf(codes.NotFound, err.Error())
 	}
 	var urls []*shortener.UserUrls
 	for _, u := range users {
-		urls = append(urls, &shortener.UserUrls{OriginalUrl: u.OriginalURL, ShortUrl: u.ShortURL})
+		urls = append(urls, &shortener.UserUrls{
+			OriginalUrl: u.OriginalURL,
+			ShortUrl:    u.ShortURL,
+			Title:       u.Title,
+			Notes:       u.Notes,
+			Tags:        u.Tags,
+			Rules:       protoRules(u.Rules),
+		})
+	}
+	return &shortener.UserUrlsResponse{Urls: urls, NextCursor: next}, nil
+}
+
+// LinkStats статистика переходов по ссылке для ее владельца
+func (s *Server) LinkStats(ctx context.Context, req *shortener.LinkStatsRequest) (*shortener.LinkStatsResponse, error) {
+	from, to, err := app.StatsWindow(req.From, req.To, time.Now())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, err.Error())
+	}
+	ctx, err = s.instance.WithDomain(ctx, req.Domain)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, err.Error())
+	}
+	stats, err := s.instance.LinkStats(ctx, req.Id, from, to)
+	if errors.Is(err, app.ErrAuth) {
+		return nil, status.Errorf(codes.Unauthenticated, err.Error())
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, status.Errorf(codes.NotFound, "link not found")
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, err.Error())
+	}
+
+	resp := &shortener.LinkStatsResponse{
+		Id:             stats.ID,
+		TotalClicks:    uint32(stats.TotalClicks),
+		UniqueVisitors: uint32(stats.UniqueVisitors),
+		TopReferrers:   topValues(stats.TopReferrers),
+		TopUserAgents:  topValues(stats.TopUserAgents),
+		Variants:       topValues(stats.Variants),
+	}
+	for _, d := range stats.Daily {
+		resp.Daily = append(resp.Daily, &shortener.DailyClicks{Date: d.Date, Clicks: uint32(d.Clicks)})
+	}
+	return resp, nil
+}
+
+func topValues(values []models.TopValue) []*shortener.TopValue {
+	res := make([]*shortener.TopValue, 0, len(values))
+	for _, v := range values {
+		res = append(res, &shortener.TopValue{Value: v.Value, Clicks: uint32(v.Clicks)})
+	}
+	return res
+}
+
+// QRCode возвращает изображение QR-кода с короткой ссылкой
+func (s *Server) QRCode(ctx context.Context, req *shortener.QRCodeRequest) (*shortener.QRCodeResponse, error) {
+	var size string
+	if req.Size > 0 {
+		size = strconv.Itoa(int(req.Size))
+	}
+	opts, err := app.ParseQROptions(req.Format, size, req.Level)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, err.Error())
+	}
+
+	image, contentType, err := s.instance.QRCode(ctx, req.Id, opts)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, status.Errorf(codes.NotFound, "link not found")
+	}
+	if errors.Is(err, store.ErrDeleted) || errors.Is(err, store.ErrExpired) {
+		return nil, status.Errorf(codes.FailedPrecondition, "link is no longer available")
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, err.Error())
+	}
+	return &shortener.QRCodeResponse{Image: image, ContentType: contentType}, nil
+}
+
+// Update изменяет адрес и описание ссылки пользователя из контекста, прежний адрес сохраняется в истории.
+// Незаданные поля запроса не меняются.
+func (s *Server) Update(ctx context.Context, req *shortener.UpdateRequest) (*shortener.UpdateResponse, error) {
+	ctx, err := s.instance.WithDomain(ctx, req.Domain)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, err.Error())
+	}
+	update := models.UpdateURLRequest{OriginalURL: req.OriginalUrl, Title: req.Title, Notes: req.Notes}
+	if req.Tags != nil {
+		update.Tags = &req.Tags.Tags
+	}
+	if req.Rules != nil {
+		rules := modelRules(req.Rules.Rules)
+		update.Rules = &rules
+	}
+	resp, err := s.instance.UpdateURL(ctx, req.Id, update)
+	if errors.Is(err, urlcheck.ErrBlocked) {
+		return nil, status.Errorf(codes.PermissionDenied, err.Error())
+	}
+	var perr *urlpolicy.Error
+	if errors.Is(err, app.ErrParseURL) || errors.Is(err, app.ErrMeta) || errors.Is(err, app.ErrTemplate) ||
+		errors.Is(err, app.ErrRules) || errors.As(err, &perr) {
+		return nil, status.Errorf(codes.InvalidArgument, err.Error())
+	}
+	if errors.Is(err, app.ErrAuth) {
+		return nil, status.Errorf(codes.Unauthenticated, err.Error())
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, status.Errorf(codes.NotFound, "link not found")
+	}
+	if errors.Is(err, store.ErrDeleted) || errors.Is(err, store.ErrExpired) {
+		return nil, status.Errorf(codes.FailedPrecondition, "link is no longer available")
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, err.Error())
 	}
-	return &shortener.UserUrlsResponse{Urls: urls}, nil
+	return &shortener.UpdateResponse{
+		ShortUrl:    resp.ShortURL,
+		OriginalUrl: resp.OriginalURL,
+		Title:       resp.Title,
+		Notes:       resp.Notes,
+		Tags:        resp.Tags,
+		Rules:       protoRules(resp.Rules),
+	}, nil
+}
+
+// History возвращает владельцу прежние адреса ссылки
+func (s *Server) History(ctx context.Context, req *shortener.HistoryRequest) (*shortener.HistoryResponse, error) {
+	ctx, err := s.instance.WithDomain(ctx, req.Domain)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, err.Error())
+	}
+	history, err := s.instance.History(ctx, req.Id)
+	if errors.Is(err, app.ErrAuth) {
+		return nil, status.Errorf(codes.Unauthenticated, err.Error())
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, status.Errorf(codes.NotFound, "link not found")
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, err.Error())
+	}
+	resp := &shortener.HistoryResponse{}
+	for _, r := range history {
+		resp.Revisions = append(resp.Revisions, &shortener.UrlRevision{
+			OriginalUrl: r.OriginalURL,
+			ReplacedAt:  timestamppb.New(r.ReplacedAt),
+		})
+	}
+	return resp, nil
 }
 
 // Ping проверяет, что приложение в состоянии обработать запросы
@@ -128,6 +431,87 @@ func (s *Server) Ping(ctx context.Context, empty *emptypb.Empty) (*emptypb.Empty
 	return empty, nil
 }
 
+// linkParams параметры ссылки, общие для запросов Shorten и BatchShorten
+type linkParams interface {
+	GetExpiresAt() *timestamppb.Timestamp
+	GetTtl() int64
+	GetPassword() string
+	GetMaxClicks() uint32
+	GetTitle() string
+	GetNotes() string
+	GetTags() []string
+	GetDomain() string
+	GetRedirectMode() string
+	GetPassthrough() string
+	GetTemplate() bool
+	GetRules() []*shortener.Rule
+	GetVariants() []*shortener.Variant
+}
+
+// linkOptions переводит параметры ссылки из запроса grpc в параметры ссылки приложения
+func linkOptions(req linkParams) models.LinkOptions {
+	opts := models.LinkOptions{
+		TTL:         req.GetTtl(),
+		Password:    req.GetPassword(),
+		MaxClicks:   int(req.GetMaxClicks()),
+		Title:       req.GetTitle(),
+		Notes:       req.GetNotes(),
+		Tags:        req.GetTags(),
+		Domain:      req.GetDomain(),
+		Redirect:    req.GetRedirectMode(),
+		Passthrough: req.GetPassthrough(),
+		Template:    req.GetTemplate(),
+		Rules:       modelRules(req.GetRules()),
+		Variants:    modelVariants(req.GetVariants()),
+	}
+	if expiresAt := req.GetExpiresAt(); expiresAt != nil {
+		t := expiresAt.AsTime()
+		opts.ExpiresAt = &t
+	}
+	return opts
+}
+
+// modelRules переводит правила перехода из запроса grpc в правила приложения
+func modelRules(rules []*shortener.Rule) []models.Rule {
+	res := make([]models.Rule, 0, len(rules))
+	for _, r := range rules {
+		rule := models.Rule{Devices: r.GetDevices(), Languages: r.GetLanguages(), URL: r.GetUrl()}
+		for _, h := range r.GetHeaders() {
+			rule.Headers = append(rule.Headers, models.RuleHeader{Name: h.GetName(), Value: h.GetValue()})
+		}
+		if t := r.GetTime(); t != nil {
+			rule.Time = &models.RuleTime{Days: t.GetDays(), From: t.GetFrom(), To: t.GetTo(), Zone: t.GetZone()}
+		}
+		res = append(res, rule)
+	}
+	return res
+}
+
+// modelVariants переводит варианты A/B-теста из запроса grpc в варианты приложения
+func modelVariants(variants []*shortener.Variant) []models.Variant {
+	res := make([]models.Variant, 0, len(variants))
+	for _, v := range variants {
+		res = append(res, models.Variant{Name: v.GetName(), URL: v.GetUrl(), Weight: int(v.GetWeight())})
+	}
+	return res
+}
+
+// protoRules переводит правила перехода приложения в сообщения grpc
+func protoRules(rules []models.Rule) []*shortener.Rule {
+	var res []*shortener.Rule
+	for _, r := range rules {
+		rule := &shortener.Rule{Devices: r.Devices, Languages: r.Languages, Url: r.URL}
+		for _, h := range r.Headers {
+			rule.Headers = append(rule.Headers, &shortener.HeaderMatch{Name: h.Name, Value: h.Value})
+		}
+		if r.Time != nil {
+			rule.Time = &shortener.TimeWindow{Days: r.Time.Days, From: r.Time.From, To: r.Time.To, Zone: r.Time.Zone}
+		}
+		res = append(res, rule)
+	}
+	return res
+}
+
 // NewShortenerServer создает экземпляр grpc сервера
 func NewShortenerServer(instance *app.Instance) *Server {
 	server := &Server{instance: instance}
@@ -2,6 +2,7 @@ package grpc
 
 import (
 	"context"
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/app"
 	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/auth"
 	"github.com/gofrs/uuid"
 	"google.golang.org/grpc"
@@ -44,6 +45,16 @@ func AuthInterceptor(ctx context.Context, req interface{}, _ *grpc.UnaryServerIn
 	return handler(ctx, req)
 }
 
+// HostInterceptor перехватчик, передающий хост из :authority в контекст запроса,
+// чтобы коды ссылок искались на домене, к которому обратился клиент
+func HostInterceptor(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if vals := md.Get(":authority"); len(vals) > 0 {
+		ctx = app.WithHost(ctx, vals[0])
+	}
+	return handler(ctx, req)
+}
+
 func ensureRandom() (res uuid.UUID) {
 	for i := 0; i < 10; i++ {
 		res = uuid.Must(uuid.NewV4())
@@ -0,0 +1,97 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/auth"
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/store"
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/models"
+)
+
+// UpdateURL изменяет ссылку id текущего пользователя: адрес, описание и правила перехода меняются только те,
+// что заданы в req. Прежний адрес попадает в историю. Недопустимые значения не сохраняются, ссылка при этом остается прежней.
+func (i *Instance) UpdateURL(ctx context.Context, id string, req models.UpdateURLRequest) (models.URLResponse, error) {
+	uid := auth.UIDFromContext(ctx)
+	if uid == nil {
+		return models.URLResponse{}, ErrAuth
+	}
+	changeMeta := req.Title != nil || req.Notes != nil || req.Tags != nil
+	var u *url.URL
+	if req.OriginalURL != "" || !changeMeta && req.Rules == nil {
+		var err error
+		if u, err = i.targetURL(ctx, req.OriginalURL); err != nil {
+			return models.URLResponse{}, err
+		}
+	}
+
+	id = i.LinkID(ctx, id)
+	link, err := i.Store.LoadLink(ctx, id)
+	if err != nil {
+		return models.URLResponse{}, err
+	}
+	if u != nil && link.Template {
+		if err := ValidateTemplate(u); err != nil {
+			return models.URLResponse{}, err
+		}
+	}
+	rules := link.Rules
+	if req.Rules != nil {
+		if len(*req.Rules) > 0 && link.Template {
+			return models.URLResponse{}, fmt.Errorf("%w: cannot be combined with template", ErrRules)
+		}
+		if rules, err = i.newRules(ctx, *req.Rules); err != nil {
+			return models.URLResponse{}, err
+		}
+	}
+	meta := link.Meta
+	if changeMeta {
+		title, notes, tags := meta.Title, meta.Notes, meta.Tags
+		if req.Title != nil {
+			title = *req.Title
+		}
+		if req.Notes != nil {
+			notes = *req.Notes
+		}
+		if req.Tags != nil {
+			tags = *req.Tags
+		}
+		if meta, err = NewMeta(title, notes, tags); err != nil {
+			return models.URLResponse{}, err
+		}
+	}
+
+	// the address, description and rules change together or not at all
+	upd := store.LinkUpdate{URL: u}
+	if changeMeta {
+		upd.Meta = &meta
+	}
+	if req.Rules != nil {
+		upd.Rules = &rules
+	}
+	if err := i.Store.UpdateLink(ctx, *uid, id, upd); err != nil {
+		return models.URLResponse{}, fmt.Errorf("cannot update link in storage: %w", err)
+	}
+	if u == nil {
+		u = link.URL
+	}
+	return i.urlResponse(id, u, meta, rules), nil
+}
+
+// History возвращает прежние адреса ссылки id текущего пользователя от старых к новым
+func (i *Instance) History(ctx context.Context, id string) ([]models.URLRevision, error) {
+	uid := auth.UIDFromContext(ctx)
+	if uid == nil {
+		return nil, ErrAuth
+	}
+	revisions, err := i.Store.LoadHistory(ctx, *uid, i.LinkID(ctx, id))
+	if err != nil {
+		return nil, err
+	}
+	res := make([]models.URLRevision, 0, len(revisions))
+	for _, r := range revisions {
+		res = append(res, models.URLRevision{OriginalURL: r.URL.String(), ReplacedAt: r.ReplacedAt})
+	}
+	return res, nil
+}
@@ -0,0 +1,156 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/auth"
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/store"
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/urlpolicy"
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/models"
+)
+
+func TestInstance_parseURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  *urlpolicy.Policy
+		rawURL  string
+		want    string
+		wantErr error
+	}{
+		{name: "absolute", rawURL: "https://practicum.yandex.ru/learn", want: "https://practicum.yandex.ru/learn"},
+		{name: "normalized", rawURL: "HTTP://Localhost:80", want: "http://localhost/"},
+		{name: "tracking kept", rawURL: "https://ya.ru/?utm_source=x", want: "https://ya.ru/?utm_source=x"},
+		{
+			name:   "tracking stripped",
+			policy: &urlpolicy.Policy{StripTracking: true},
+			rawURL: "https://ya.ru/?utm_source=x",
+			want:   "https://ya.ru/",
+		},
+		{name: "unparsable", rawURL: "://bad", wantErr: ErrParseURL},
+		{name: "no scheme", rawURL: "practicum.yandex.ru", wantErr: &urlpolicy.Error{}},
+		{name: "no host", rawURL: "https://", wantErr: &urlpolicy.Error{}},
+		{name: "scheme not allowed", policy: &urlpolicy.Policy{Schemes: []string{"https"}}, rawURL: "http://ya.ru/", wantErr: &urlpolicy.Error{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := &Instance{Policy: tt.policy}
+			u, err := instance.parseURL(tt.rawURL)
+			var perr *urlpolicy.Error
+			switch {
+			case tt.wantErr == ErrParseURL:
+				assert.ErrorIs(t, err, ErrParseURL)
+			case tt.wantErr != nil:
+				assert.True(t, errors.As(err, &perr), "unexpected error %v", err)
+			default:
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, u.String())
+			}
+		})
+	}
+}
+
+func TestInstance_UpdateURL(t *testing.T) {
+	uid := uuid.Must(uuid.NewV4())
+	ctx := auth.Context(context.Background(), uid)
+	instance := &Instance{
+		BaseURL: "http://localhost:8080",
+		Store:   store.NewInMemory(),
+	}
+	u, _ := url.Parse("https://praktikum.yandex.ru/")
+	id, err := instance.Store.SaveUser(ctx, uid, u)
+	require.NoError(t, err)
+
+	_, err = instance.UpdateURL(context.Background(), id, models.UpdateURLRequest{OriginalURL: "https://practicum.yandex.ru/"})
+	assert.ErrorIs(t, err, ErrAuth)
+
+	// an invalid address leaves the link untouched
+	_, err = instance.UpdateURL(ctx, id, models.UpdateURLRequest{OriginalURL: "practicum"})
+	var perr *urlpolicy.Error
+	require.True(t, errors.As(err, &perr))
+	assert.Equal(t, urlpolicy.FieldScheme, perr.Field)
+	history, err := instance.History(ctx, id)
+	require.NoError(t, err)
+	assert.Empty(t, history)
+
+	resp, err := instance.UpdateURL(ctx, id, models.UpdateURLRequest{OriginalURL: "https://practicum.yandex.ru/"})
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost:8080/"+id, resp.ShortURL)
+	assert.Equal(t, "https://practicum.yandex.ru/", resp.OriginalURL)
+
+	link, err := instance.Expand(ctx, id, "")
+	require.NoError(t, err)
+	assert.Equal(t, "https://practicum.yandex.ru/", link.URL.String())
+
+	history, err = instance.History(ctx, id)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, u.String(), history[0].OriginalURL)
+
+	_, err = instance.UpdateURL(auth.Context(context.Background(), uuid.Must(uuid.NewV4())), id, models.UpdateURLRequest{OriginalURL: "https://ya.ru/"})
+	assert.ErrorIs(t, err, store.ErrNotFound)
+}
+
+func TestInstance_UpdateURL_Meta(t *testing.T) {
+	uid := uuid.Must(uuid.NewV4())
+	ctx := auth.Context(context.Background(), uid)
+	instance := &Instance{
+		BaseURL: "http://localhost:8080",
+		Store:   store.NewInMemory(),
+	}
+	u, _ := url.Parse("https://practicum.yandex.ru/")
+	id, err := instance.Store.SaveUser(ctx, uid, u)
+	require.NoError(t, err)
+
+	title, notes := "Practicum", "from the newsletter"
+	tags := []string{"learn", "go"}
+	resp, err := instance.UpdateURL(ctx, id, models.UpdateURLRequest{Title: &title, Notes: &notes, Tags: &tags})
+	require.NoError(t, err)
+	assert.Equal(t, u.String(), resp.OriginalURL)
+	assert.Equal(t, title, resp.Title)
+	assert.Equal(t, tags, resp.Tags)
+
+	// only the given fields change and the address stays in place
+	title = "Yandex Practicum"
+	resp, err = instance.UpdateURL(ctx, id, models.UpdateURLRequest{Title: &title})
+	require.NoError(t, err)
+	assert.Equal(t, title, resp.Title)
+	assert.Equal(t, notes, resp.Notes)
+	assert.Equal(t, tags, resp.Tags)
+	history, err := instance.History(ctx, id)
+	require.NoError(t, err)
+	assert.Empty(t, history)
+
+	// an invalid description leaves the link untouched, including its address
+	bad := []string{""}
+	_, err = instance.UpdateURL(ctx, id, models.UpdateURLRequest{OriginalURL: "https://ya.ru/", Tags: &bad})
+	assert.ErrorIs(t, err, ErrMeta)
+	link, err := instance.Expand(ctx, id, "")
+	require.NoError(t, err)
+	assert.Equal(t, u.String(), link.URL.String())
+
+	var none []string
+	resp, err = instance.UpdateURL(ctx, id, models.UpdateURLRequest{OriginalURL: "https://ya.ru/", Tags: &none})
+	require.NoError(t, err)
+	assert.Equal(t, "https://ya.ru/", resp.OriginalURL)
+	assert.Empty(t, resp.Tags)
+	assert.Equal(t, title, resp.Title)
+
+	links, _, err := instance.LoadUsers(ctx, store.LinkFilter{})
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	assert.Equal(t, resp, links[0])
+
+	// nothing to change is treated as an empty address
+	_, err = instance.UpdateURL(ctx, id, models.UpdateURLRequest{})
+	var perr *urlpolicy.Error
+	assert.True(t, errors.As(err, &perr))
+	_, err = instance.UpdateURL(auth.Context(context.Background(), uuid.Must(uuid.NewV4())), id, models.UpdateURLRequest{Title: &title})
+	assert.ErrorIs(t, err, store.ErrNotFound)
+}
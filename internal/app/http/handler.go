@@ -1,16 +1,23 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/app"
 	"github.com/go-chi/chi/v5"
 	"io"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/auth"
 	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/store"
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/urlcheck"
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/urlpolicy"
 	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/models"
 )
 
@@ -30,6 +37,17 @@ func (h *Handler) ShortenHandler(w http.ResponseWriter, r *http.Request) {
 
 	shortURL, err := h.Instance.Shorten(r.Context(), string(b))
 
+	if errors.Is(err, urlcheck.ErrBlocked) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+	var perr *urlpolicy.Error
+	if errors.As(err, &perr) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
 	if errors.Is(err, app.ErrParseURL) {
 		w.WriteHeader(http.StatusBadRequest)
 		_, _ = w.Write([]byte("Cannot parse given string as URL"))
@@ -61,14 +79,37 @@ func (h *Handler) ShortenAPIHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	shortURL, err := h.Instance.Shorten(r.Context(), req.URL)
+	shortURL, err := h.Instance.ShortenLink(r.Context(), req)
 
+	if errors.Is(err, urlcheck.ErrBlocked) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+	var perr *urlpolicy.Error
+	if errors.As(err, &perr) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
 	if errors.Is(err, app.ErrParseURL) {
 		w.WriteHeader(http.StatusBadRequest)
 		_, _ = w.Write([]byte("Cannot parse given string as URL"))
 		return
 	}
 
+	if app.IsInvalidLink(err) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+
+	if errors.Is(err, store.ErrIDTaken) {
+		w.WriteHeader(http.StatusConflict)
+		_, _ = w.Write([]byte("Alias is already taken"))
+		return
+	}
+
 	if err != nil && !errors.Is(err, store.ErrConflict) {
 		w.WriteHeader(http.StatusInternalServerError)
 		_, _ = w.Write([]byte(err.Error()))
@@ -92,7 +133,9 @@ func (h *Handler) ShortenAPIHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// ExpandHandler обработчик, возвращающий ссылку из хранилища
+// ExpandHandler обработчик, возвращающий ссылку из хранилища.
+// Для ссылки с паролем вместо перенаправления возвращается форма ввода пароля.
+// Путь после кода и параметры запроса переносятся в адрес перехода, если ссылка это разрешает
 func (h *Handler) ExpandHandler(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
@@ -101,13 +144,173 @@ func (h *Handler) ExpandHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	target, err := h.Instance.LoadURL(r.Context(), id)
+	ctx, key, fresh := expandContext(r)
+	link, err := h.Instance.ExpandTail(ctx, id, "", tail(r, id))
+	if errors.Is(err, app.ErrPasswordRequired) {
+		writePasswordForm(w, http.StatusOK, r.URL.RequestURI(), "")
+		return
+	}
+	if err != nil {
+		writeExpandError(w, err)
+		return
+	}
+	if len(link.Rules) > 0 || len(link.Variants) > 0 {
+		// the target depends on the client and the time, even permanent redirects must not be cached
+		w.Header().Set("Cache-Control", "no-store")
+	}
+	if fresh && len(link.Variants) > 0 {
+		setVisitor(w, key)
+	}
+
+	h.redirect(w, r, id, link, redirectStatus(link.Redirect))
+}
+
+// UnlockHandler обработчик формы ввода пароля, перенаправляющий на ссылку при верном пароле
+func (h *Handler) UnlockHandler(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if err := r.ParseForm(); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("Bad request body given"))
+		return
+	}
+
+	ctx, key, fresh := expandContext(r)
+	link, err := h.Instance.ExpandTail(ctx, id, r.PostForm.Get("password"), tail(r, id))
+	if errors.Is(err, app.ErrPasswordRequired) || errors.Is(err, app.ErrWrongPassword) {
+		writePasswordForm(w, http.StatusUnauthorized, r.URL.RequestURI(), "Wrong password")
+		return
+	}
+	if errors.Is(err, app.ErrTooManyAttempts) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(app.PasswordAttemptWindow.Seconds())))
+		writePasswordForm(w, http.StatusTooManyRequests, r.URL.RequestURI(), "Too many attempts, try again later")
+		return
+	}
+	if err != nil {
+		writeExpandError(w, err)
+		return
+	}
+
+	if fresh && len(link.Variants) > 0 {
+		setVisitor(w, key)
+	}
+
+	// the form was posted, so status code modes answer with See Other to switch to GET
+	h.redirect(w, r, id, link, http.StatusSeeOther)
+}
+
+// redirect записывает переход по ссылке id и перенаправляет клиента на адрес link.URL способом link.Redirect.
+// Для способов с кодом ответа используется code
+func (h *Handler) redirect(w http.ResponseWriter, r *http.Request, id string, link *store.Link, code int) {
+	h.Instance.RecordClick(r.Context(), id, app.Visit{
+		Referrer:  r.Referer(),
+		UserAgent: r.UserAgent(),
+		IP:        clientIP(r),
+		Variant:   link.Variant,
+	})
+
+	target := link.URL.String()
+	switch link.Redirect {
+	case store.RedirectInterstitial:
+		writeInterstitial(w, target)
+	case store.RedirectMetaRefresh:
+		writeMetaRefresh(w, target)
+	default:
+		w.Header().Set("Location", target)
+		w.WriteHeader(code)
+	}
+}
+
+// VisitorCookie кука с ключом посетителя, по которому он закрепляется за вариантом A/B-теста
+const VisitorCookie = "visitor"
+
+// expandContext возвращает контекст перехода по ссылке с заголовками запроса и ключом посетителя.
+// Посетителю без куки ключ выдается по его адресу, fresh сообщает, что такой ключ нужно сохранить в куке.
+func expandContext(r *http.Request) (ctx context.Context, key string, fresh bool) {
+	if c, err := r.Cookie(VisitorCookie); err == nil && c.Value != "" {
+		key = c.Value
+	} else {
+		key, fresh = app.VisitorKey(clientIP(r)), true
+	}
+	return app.WithVisitor(app.WithHeader(r.Context(), r.Header), key), key, fresh
+}
+
+// setVisitor сохраняет ключ посетителя в куке, чтобы он оставался в своем варианте и при смене адреса
+func setVisitor(w http.ResponseWriter, key string) {
+	if key == "" {
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     VisitorCookie,
+		Value:    key,
+		Path:     "/",
+		MaxAge:   int((365 * 24 * time.Hour).Seconds()),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// tail возвращает продолжение запроса после кода ссылки id.
+// Путь берется в экранированном виде, чтобы экранированные символы, например %2F, дошли до адреса как есть
+func tail(r *http.Request, id string) app.Tail {
+	return app.Tail{
+		Path:     strings.TrimPrefix(r.URL.EscapedPath(), "/"+id),
+		RawQuery: r.URL.RawQuery,
+	}
+}
+
+// writeExpandError отвечает статусом, соответствующим ошибке загрузки ссылки
+func writeExpandError(w http.ResponseWriter, err error) {
+	if errors.Is(err, store.ErrNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, store.ErrDeleted) || errors.Is(err, store.ErrExpired) {
+		w.WriteHeader(http.StatusGone)
+		return
+	}
+	if errors.Is(err, urlcheck.ErrBlocked) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, app.ErrTemplateValues) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+	w.WriteHeader(http.StatusInternalServerError)
+}
+
+// clientIP возвращает адрес клиента из X-Real-IP, а без него адрес соединения
+func clientIP(r *http.Request) string {
+	if ip := r.Header.Get("X-Real-IP"); ip != "" {
+		return ip
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// QRHandler обработчик, возвращающий QR-код с короткой ссылкой.
+// Параметры format (png или svg), size (сторона в пикселях) и level (L, M, Q или H) необязательны
+func (h *Handler) QRHandler(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	query := r.URL.Query()
+	opts, err := app.ParseQROptions(query.Get("format"), query.Get("size"), query.Get("level"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+
+	image, contentType, err := h.Instance.QRCode(r.Context(), id, opts)
 	if err != nil {
 		if errors.Is(err, store.ErrNotFound) {
 			w.WriteHeader(http.StatusNotFound)
 			return
 		}
-		if errors.Is(err, store.ErrDeleted) {
+		if errors.Is(err, store.ErrDeleted) || errors.Is(err, store.ErrExpired) {
 			w.WriteHeader(http.StatusGone)
 			return
 		}
@@ -115,21 +318,58 @@ func (h *Handler) ExpandHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	w.Header().Set("Location", target.String())
-	w.WriteHeader(http.StatusTemporaryRedirect)
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(image)
+}
+
+// PreviewHandler обработчик, показывающий, куда ведет ссылка, без перехода по ней.
+// Сведения возвращаются в JSON, если клиент предпочитает его в заголовке Accept, иначе HTML-страницей
+func (h *Handler) PreviewHandler(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	preview, err := h.Instance.Preview(r.Context(), id)
+	if errors.Is(err, store.ErrNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if !acceptsJSON(r) {
+		writePreview(w, preview)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(preview)
+	if err != nil {
+		fmt.Printf("cannot write response: %s", err)
+	}
 }
 
-// UserURLsHandler обработчик, который возвращает все ссылки пользователя.
-// Пользователь при этом берется из контекста запроса
+// UserURLsHandler обработчик, который возвращает ссылки пользователя.
+// Пользователь при этом берется из контекста запроса. Параметр tag оставляет только ссылки с этой меткой,
+// q - ссылки, у которых адрес или название содержат строку. Порядок задается параметрами sort (created или clicks)
+// и order (asc или desc). Если задан limit, возвращается страница ссылок, а курсор следующей страницы
+// передается в заголовке X-Next-Cursor и подставляется в параметр cursor следующего запроса.
 func (h *Handler) UserURLsHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
+	q := r.URL.Query()
+	filter, err := app.ParseLinkQuery(q.Get("tag"), q.Get("q"), q.Get("sort"), q.Get("order"), q.Get("limit"), q.Get("cursor"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
 
-	urls, err := h.Instance.LoadUsers(ctx)
+	urls, next, err := h.Instance.LoadUsers(ctx, filter)
 	if errors.Is(err, app.ErrAuth) {
 		w.WriteHeader(http.StatusUnprocessableEntity)
 		return
 	}
-	if errors.Is(err, store.ErrNotFound) || len(urls) == 0 {
+	narrowed := filter.Tag != "" || filter.Search != "" || filter.After != nil
+	if errors.Is(err, store.ErrNotFound) || len(urls) == 0 && !narrowed {
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
@@ -138,10 +378,142 @@ func (h *Handler) UserURLsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if next != "" {
+		w.Header().Set("X-Next-Cursor", next)
+	}
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(urls)
 }
 
+// ownerContext возвращает контекст запроса владельца, в котором коды ссылок ищутся на домене
+// из параметра domain. Для недоступного домена отвечает ошибкой и возвращает false.
+func (h *Handler) ownerContext(w http.ResponseWriter, r *http.Request) (context.Context, bool) {
+	ctx, err := h.Instance.WithDomain(r.Context(), r.URL.Query().Get("domain"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(err.Error()))
+		return nil, false
+	}
+	return ctx, true
+}
+
+// LinkStatsHandler обработчик, возвращающий владельцу статистику переходов по ссылке.
+// Период задается параметрами from и to в формате 2006-01-02, домен ссылки - параметром domain
+func (h *Handler) LinkStatsHandler(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	from, to, err := app.StatsWindow(r.URL.Query().Get("from"), r.URL.Query().Get("to"), time.Now())
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+	ctx, ok := h.ownerContext(w, r)
+	if !ok {
+		return
+	}
+
+	stats, err := h.Instance.LinkStats(ctx, id, from, to)
+	if errors.Is(err, app.ErrAuth) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		return
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(stats)
+	if err != nil {
+		fmt.Printf("cannot write response: %s", err)
+	}
+}
+
+// UpdateURLHandler обработчик, изменяющий адрес и описание ссылки пользователя.
+// Принимает в запросе структуру UpdateURLRequest, прежний адрес сохраняется в истории.
+// Домен ссылки задается параметром domain.
+func (h *Handler) UpdateURLHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := h.ownerContext(w, r)
+	if !ok {
+		return
+	}
+	var req models.UpdateURLRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("Bad request body given"))
+		return
+	}
+
+	resp, err := h.Instance.UpdateURL(ctx, chi.URLParam(r, "id"), req)
+	if errors.Is(err, urlcheck.ErrBlocked) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+	var perr *urlpolicy.Error
+	if errors.As(err, &perr) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+	if errors.Is(err, app.ErrParseURL) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("Cannot parse given string as URL"))
+		return
+	}
+	if errors.Is(err, app.ErrMeta) || errors.Is(err, app.ErrTemplate) || errors.Is(err, app.ErrRules) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+	if errors.Is(err, app.ErrAuth) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil {
+		writeExpandError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		fmt.Printf("cannot write response: %s", err)
+	}
+}
+
+// HistoryHandler обработчик, возвращающий владельцу прежние адреса ссылки.
+// Домен ссылки задается параметром domain.
+func (h *Handler) HistoryHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, ok := h.ownerContext(w, r)
+	if !ok {
+		return
+	}
+	history, err := h.Instance.History(ctx, chi.URLParam(r, "id"))
+	if errors.Is(err, app.ErrAuth) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		return
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(history)
+	if err != nil {
+		fmt.Printf("cannot write response: %s", err)
+	}
+}
+
 // BatchShortenAPIHandler пакетная обработка запросов на сокращение ссылок.
 // Принимает в запросе структуру BatchShortenRequest
 func (h *Handler) BatchShortenAPIHandler(w http.ResponseWriter, r *http.Request) {
@@ -160,12 +532,29 @@ func (h *Handler) BatchShortenAPIHandler(w http.ResponseWriter, r *http.Request)
 	}
 
 	shorten, err := h.Instance.BatchShorten(req, r.Context())
+	if errors.Is(err, urlcheck.ErrBlocked) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+	var perr *urlpolicy.Error
+	if errors.As(err, &perr) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
 	if errors.Is(err, app.ErrParseURL) {
 		w.WriteHeader(http.StatusBadRequest)
 		_, _ = w.Write([]byte("Cannot parse given string as URL"))
 		return
 	}
 
+	if app.IsInvalidLink(err) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+
 	if errors.Is(err, app.ErrURLLength) {
 		w.WriteHeader(http.StatusInternalServerError)
 		_, _ = w.Write([]byte("invalid shorten URLs length"))
@@ -188,14 +577,17 @@ func (h *Handler) BatchShortenAPIHandler(w http.ResponseWriter, r *http.Request)
 }
 
 // BatchRemoveAPIHandler пакетное удаление пользовательских ссылок ссылок.
-// Пользователь определяется из контекста запроса.
+// Пользователь определяется из контекста запроса, домен ссылок - параметром domain.
 func (h *Handler) BatchRemoveAPIHandler(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
-	uid := auth.UIDFromContext(ctx)
+	uid := auth.UIDFromContext(r.Context())
 	if uid == nil {
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
+	ctx, ok := h.ownerContext(w, r)
+	if !ok {
+		return
+	}
 
 	var ids []string
 	err := json.NewDecoder(r.Body).Decode(&ids)
@@ -211,6 +603,9 @@ func (h *Handler) BatchRemoveAPIHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	for j, id := range ids {
+		ids[j] = h.Instance.LinkID(ctx, id)
+	}
 	go func() {
 		h.Instance.RemoveChan <- models.BatchRemoveRequest{UID: *uid, Ids: ids}
 	}()
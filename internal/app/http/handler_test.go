@@ -11,31 +11,42 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/gofrs/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
 
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/analytics"
 	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/auth"
 	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/config"
 	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/store"
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/urlcheck/urlchecktest"
 	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/models"
 )
 
 func Test_ShortenAPIHandler(t *testing.T) {
 	targetURL := "https://praktikum.yandex.ru/"
+	checker := &urlchecktest.Fake{}
+	checker.Block("evil.example")
 
 	instance := &app.Instance{
-		BaseURL: "http://localhost:8080",
-		Store:   store.NewInMemory(),
+		BaseURL:    "http://localhost:8080",
+		Store:      store.NewInMemory(),
+		URLChecker: checker,
 	}
 	handler := Handler{Instance: instance}
 
 	testCases := []struct {
 		name             string
 		url              string
+		alias            string
+		redirect         string
+		template         bool
 		expectedStatus   int
 		expectedResponse []byte
 	}{
@@ -45,17 +56,72 @@ func Test_ShortenAPIHandler(t *testing.T) {
 			expectedStatus:   http.StatusBadRequest,
 			expectedResponse: []byte("Cannot parse given string as URL"),
 		},
+		{
+			name:             "scheme_not_allowed",
+			url:              "javascript:alert(1)",
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: []byte("invalid URL scheme: scheme \"javascript\" is not allowed"),
+		},
+		{
+			name:             "blocked",
+			url:              "https://evil.example/login",
+			expectedStatus:   http.StatusForbidden,
+			expectedResponse: []byte("URL is blocked: host evil.example is blocked by fake checker"),
+		},
 		{
 			name:             "success",
 			url:              targetURL,
 			expectedStatus:   http.StatusCreated,
 			expectedResponse: []byte("{\"result\":\"http://localhost:8080/0\"}\n"),
 		},
+		{
+			name:             "alias",
+			url:              targetURL,
+			alias:            "spring-sale",
+			expectedStatus:   http.StatusCreated,
+			expectedResponse: []byte("{\"result\":\"http://localhost:8080/spring-sale\"}\n"),
+		},
+		{
+			name:             "alias_taken",
+			url:              targetURL + "other",
+			alias:            "spring-sale",
+			expectedStatus:   http.StatusConflict,
+			expectedResponse: []byte("Alias is already taken"),
+		},
+		{
+			name:             "alias_reserved",
+			url:              targetURL,
+			alias:            "API",
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: []byte("invalid alias: \"API\" is reserved"),
+		},
+		{
+			name:             "alias_bad_chars",
+			url:              targetURL,
+			alias:            "spring/sale",
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: []byte("invalid alias: character '/' is not allowed"),
+		},
+		{
+			name:             "bad_template",
+			url:              "https://tracker.example/issues/",
+			template:         true,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: []byte("invalid link template: no placeholders"),
+		},
+		{
+			name:             "bad_redirect",
+			url:              targetURL,
+			redirect:         "303",
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: []byte("invalid redirect mode: unknown mode \"303\""),
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			b, err := json.Marshal(models.ShortenRequest{URL: tc.url})
+			opts := models.LinkOptions{Redirect: tc.redirect, Template: tc.template}
+			b, err := json.Marshal(models.ShortenRequest{URL: tc.url, Alias: tc.alias, LinkOptions: opts})
 			require.NoError(t, err)
 			body := bytes.NewBuffer(b)
 
@@ -76,10 +142,19 @@ func Test_expander(t *testing.T) {
 
 	storage := store.NewInMemory()
 	id, _ := storage.Save(context.Background(), parsedURL)
+	past := time.Now().Add(-time.Minute)
+	expiredID, _ := storage.SaveLink(context.Background(), store.Link{URL: parsedURL, ExpiresAt: &past})
+	exhaustedID, _ := storage.SaveLink(context.Background(), store.Link{URL: parsedURL, ClicksLeft: 1})
+	_, _ = storage.Load(context.Background(), exhaustedID)
+	blockedURL, _ := url.Parse("https://evil.example/login")
+	blockedID, _ := storage.Save(context.Background(), blockedURL)
+	checker := &urlchecktest.Fake{}
+	checker.Block("evil.example")
 
 	instance := &app.Instance{
-		BaseURL: "http://localhost:8080",
-		Store:   storage,
+		BaseURL:    "http://localhost:8080",
+		Store:      storage,
+		URLChecker: checker,
 	}
 	handler := Handler{Instance: instance}
 
@@ -107,6 +182,24 @@ func Test_expander(t *testing.T) {
 			expectedStatus:   http.StatusTemporaryRedirect,
 			expectedLocation: expectedURL,
 		},
+		{
+			name:             "expired",
+			id:               expiredID,
+			expectedStatus:   http.StatusGone,
+			expectedLocation: "",
+		},
+		{
+			name:             "exhausted",
+			id:               exhaustedID,
+			expectedStatus:   http.StatusGone,
+			expectedLocation: "",
+		},
+		{
+			name:             "blocked",
+			id:               blockedID,
+			expectedStatus:   http.StatusForbidden,
+			expectedLocation: "",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -126,22 +219,598 @@ func Test_expander(t *testing.T) {
 	}
 }
 
+func Test_expanderRedirectModes(t *testing.T) {
+	expectedURL := "https://praktikum.yandex.ru/?a=1&b=2"
+	parsedURL, _ := url.Parse(expectedURL)
+	storage := store.NewInMemory()
+	handler := Handler{Instance: &app.Instance{
+		BaseURL: "http://localhost:8080",
+		Store:   storage,
+	}}
+
+	testCases := []struct {
+		mode           store.RedirectMode
+		expectedStatus int
+	}{
+		{mode: "", expectedStatus: http.StatusTemporaryRedirect},
+		{mode: store.RedirectMovedPermanently, expectedStatus: http.StatusMovedPermanently},
+		{mode: store.RedirectFound, expectedStatus: http.StatusFound},
+		{mode: store.RedirectTemporary, expectedStatus: http.StatusTemporaryRedirect},
+		{mode: store.RedirectPermanent, expectedStatus: http.StatusPermanentRedirect},
+		{mode: store.RedirectInterstitial, expectedStatus: http.StatusOK},
+		{mode: store.RedirectMetaRefresh, expectedStatus: http.StatusOK},
+	}
+	for _, tc := range testCases {
+		t.Run(string(tc.mode), func(t *testing.T) {
+			id, err := storage.SaveLink(context.Background(), store.Link{URL: parsedURL, Redirect: tc.mode})
+			require.NoError(t, err)
+			r := httptest.NewRequest("GET", "http://localhost:8080/"+id, nil)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", id)
+			r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+			w := httptest.NewRecorder()
+
+			handler.ExpandHandler(w, r)
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			if tc.expectedStatus != http.StatusOK {
+				assert.Equal(t, expectedURL, w.Header().Get("Location"))
+				return
+			}
+			assert.Empty(t, w.Header().Get("Location"))
+			assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+			assert.Contains(t, w.Body.String(), `href="https://praktikum.yandex.ru/?a=1&amp;b=2"`)
+			if tc.mode == store.RedirectMetaRefresh {
+				assert.Equal(t, "no-referrer", w.Header().Get("Referrer-Policy"))
+				assert.Contains(t, w.Body.String(), `<meta name="referrer" content="no-referrer">`)
+				assert.Contains(t, w.Body.String(), `http-equiv="refresh"`)
+			}
+		})
+	}
+}
+
+func Test_expanderPassthrough(t *testing.T) {
+	parsedURL, _ := url.Parse("https://practicum.yandex.ru/docs?lang=ru")
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+	storage := store.NewInMemory()
+	_, err = storage.SaveLink(context.Background(), store.Link{ID: "docs", URL: parsedURL, Passthrough: store.PassthroughAll})
+	require.NoError(t, err)
+	_, err = storage.SaveLink(context.Background(), store.Link{ID: "plain", URL: parsedURL})
+	require.NoError(t, err)
+	_, err = storage.SaveLink(context.Background(), store.Link{ID: "locked", URL: parsedURL, Passthrough: store.PassthroughPath,
+		PasswordHash: string(hash)})
+	require.NoError(t, err)
+	handler := Handler{Instance: &app.Instance{
+		BaseURL: "http://localhost:8080",
+		Store:   storage,
+	}}
+
+	testCases := []struct {
+		name             string
+		id               string
+		path             string
+		expectedStatus   int
+		expectedLocation string
+	}{
+		{
+			name:             "path_and_query",
+			id:               "docs",
+			path:             "/docs/getting-started?lang=en&page=2",
+			expectedStatus:   http.StatusTemporaryRedirect,
+			expectedLocation: "https://practicum.yandex.ru/docs/getting-started?lang=ru&page=2",
+		},
+		{
+			name:           "dot_segments",
+			id:             "docs",
+			path:           "/docs/../admin",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:             "plain_query_ignored",
+			id:               "plain",
+			path:             "/plain?page=2",
+			expectedStatus:   http.StatusTemporaryRedirect,
+			expectedLocation: parsedURL.String(),
+		},
+		{
+			name:           "plain_path",
+			id:             "plain",
+			path:           "/plain/getting-started",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "http://localhost:8080"+tc.path, nil)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", tc.id)
+			r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+			w := httptest.NewRecorder()
+
+			handler.ExpandHandler(w, r)
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			assert.Equal(t, tc.expectedLocation, w.Header().Get("Location"))
+		})
+	}
+
+	t.Run("password", func(t *testing.T) {
+		withID := func(r *http.Request) *http.Request {
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "locked")
+			return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+		}
+		w := httptest.NewRecorder()
+		handler.ExpandHandler(w, withID(httptest.NewRequest("GET", "http://localhost:8080/locked/faq", nil)))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `action="/locked/faq"`)
+
+		form := url.Values{"password": {"secret"}}
+		r := httptest.NewRequest("POST", "http://localhost:8080/locked/faq", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w = httptest.NewRecorder()
+		handler.UnlockHandler(w, withID(r))
+		assert.Equal(t, http.StatusSeeOther, w.Code)
+		assert.Equal(t, "https://practicum.yandex.ru/docs/faq?lang=ru", w.Header().Get("Location"))
+	})
+}
+
+func Test_expanderTemplate(t *testing.T) {
+	template, _ := url.Parse("https://tracker.example/issues/{1}?q={q}")
+	storage := store.NewInMemory()
+	_, err := storage.SaveLink(context.Background(), store.Link{ID: "jira", URL: template, Template: true})
+	require.NoError(t, err)
+	handler := Handler{Instance: &app.Instance{
+		BaseURL: "http://localhost:8080",
+		Store:   storage,
+	}}
+
+	testCases := []struct {
+		name             string
+		path             string
+		expectedStatus   int
+		expectedLocation string
+	}{
+		{
+			name:             "filled",
+			path:             "/jira/42?q=open",
+			expectedStatus:   http.StatusTemporaryRedirect,
+			expectedLocation: "https://tracker.example/issues/42?q=open",
+		},
+		{
+			name:             "escaped",
+			path:             "/jira/%2F%2Fevil.example?q=a%26b%3Dc",
+			expectedStatus:   http.StatusTemporaryRedirect,
+			expectedLocation: "https://tracker.example/issues/%2F%2Fevil.example?q=a%26b%3Dc",
+		},
+		{
+			name:           "missing",
+			path:           "/jira?q=open",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "extra",
+			path:           "/jira/42/43?q=open",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "http://localhost:8080"+tc.path, nil)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "jira")
+			r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+			w := httptest.NewRecorder()
+
+			handler.ExpandHandler(w, r)
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			assert.Equal(t, tc.expectedLocation, w.Header().Get("Location"))
+		})
+	}
+}
+
+func Test_expanderRules(t *testing.T) {
+	target, _ := url.Parse("https://example.com/app")
+	storage := store.NewInMemory()
+	_, err := storage.SaveLink(context.Background(), store.Link{ID: "app", URL: target, Rules: []store.Rule{
+		{Devices: []string{app.DeviceIOS}, URL: "https://apps.apple.com/app/id1"},
+		{Devices: []string{app.DeviceAndroid}, URL: "https://play.google.com/store/apps/details?id=app"},
+		{Languages: []string{"ru"}, URL: "https://example.com/ru/app"},
+	}})
+	require.NoError(t, err)
+	handler := Handler{Instance: &app.Instance{
+		BaseURL: "http://localhost:8080",
+		Store:   storage,
+	}}
+
+	testCases := []struct {
+		name             string
+		header           http.Header
+		expectedLocation string
+	}{
+		{
+			name:             "ios",
+			header:           http.Header{"User-Agent": {"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X)"}},
+			expectedLocation: "https://apps.apple.com/app/id1",
+		},
+		{
+			name:             "android",
+			header:           http.Header{"User-Agent": {"Mozilla/5.0 (Linux; Android 14; Pixel 8) Mobile"}},
+			expectedLocation: "https://play.google.com/store/apps/details?id=app",
+		},
+		{
+			name:             "language",
+			header:           http.Header{"User-Agent": {"Mozilla/5.0 (X11; Linux x86_64)"}, "Accept-Language": {"ru-RU,ru;q=0.9"}},
+			expectedLocation: "https://example.com/ru/app",
+		},
+		{
+			name:             "default",
+			header:           http.Header{"User-Agent": {"Mozilla/5.0 (X11; Linux x86_64)"}, "Accept-Language": {"en"}},
+			expectedLocation: "https://example.com/app",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "http://localhost:8080/app", nil)
+			r.Header = tc.header
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "app")
+			r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+			w := httptest.NewRecorder()
+
+			handler.ExpandHandler(w, r)
+
+			assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+			assert.Equal(t, tc.expectedLocation, w.Header().Get("Location"))
+			assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+		})
+	}
+}
+
+func Test_expanderVariants(t *testing.T) {
+	target, _ := url.Parse("https://example.com/")
+	storage := store.NewInMemory()
+	_, err := storage.SaveLink(context.Background(), store.Link{ID: "split", URL: target, Variants: []store.Variant{
+		{Name: "A", URL: "https://example.com/a", Weight: 1},
+		{Name: "B", URL: "https://example.com/b", Weight: 1},
+	}})
+	require.NoError(t, err)
+	_, err = storage.SaveLink(context.Background(), store.Link{ID: "plain", URL: target})
+	require.NoError(t, err)
+	handler := Handler{Instance: &app.Instance{
+		BaseURL: "http://localhost:8080",
+		Store:   storage,
+	}}
+	expand := func(id, ip string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "http://localhost:8080/"+id, nil)
+		r.RemoteAddr = ip + ":1234"
+		if cookie != nil {
+			r.AddCookie(cookie)
+		}
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", id)
+		r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+		w := httptest.NewRecorder()
+		handler.ExpandHandler(w, r)
+		return w
+	}
+
+	// a new visitor gets a key derived from the address and keeps it in a cookie
+	w := expand("split", "192.0.2.1", nil)
+	require.Equal(t, http.StatusTemporaryRedirect, w.Code)
+	location := w.Header().Get("Location")
+	assert.Contains(t, []string{"https://example.com/a", "https://example.com/b"}, location)
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, VisitorCookie, cookies[0].Name)
+	assert.True(t, cookies[0].HttpOnly)
+
+	// the same address lands in the same variant
+	assert.Equal(t, location, expand("split", "192.0.2.1", nil).Header().Get("Location"))
+
+	// the cookie keeps the variant on any address and is not reissued
+	for j := 0; j < 10; j++ {
+		w = expand("split", "198.51.100."+strconv.Itoa(j), &http.Cookie{Name: VisitorCookie, Value: cookies[0].Value})
+		assert.Equal(t, location, w.Header().Get("Location"))
+		assert.Empty(t, w.Result().Cookies())
+	}
+
+	// links without a split set no cookies
+	w = expand("plain", "192.0.2.1", nil)
+	assert.Equal(t, "https://example.com/", w.Header().Get("Location"))
+	assert.Empty(t, w.Result().Cookies())
+}
+
+func Test_expanderDomains(t *testing.T) {
+	storage := store.NewInMemory()
+	first, _ := url.Parse("https://practicum.yandex.ru/")
+	second, _ := url.Parse("https://ya.ru/")
+	_, err := storage.SaveLink(context.Background(), store.Link{ID: "sale", Domain: "a.co", URL: first})
+	require.NoError(t, err)
+	_, err = storage.SaveLink(context.Background(), store.Link{ID: "sale", Domain: "b.co", URL: second})
+	require.NoError(t, err)
+
+	instance := &app.Instance{
+		BaseURL: "http://localhost:8080",
+		Domains: map[string]string{"a.co": "https://a.co", "b.co": "https://b.co"},
+		Store:   storage,
+	}
+	handler := Handler{Instance: instance}
+
+	for host, expectedLocation := range map[string]string{"a.co": first.String(), "b.co": second.String(), "localhost:8080": ""} {
+		t.Run(host, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "http://"+host+"/sale", nil)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "sale")
+			ctx := app.WithHost(context.WithValue(r.Context(), chi.RouteCtxKey, rctx), r.Host)
+			r = r.WithContext(ctx)
+			w := httptest.NewRecorder()
+
+			handler.ExpandHandler(w, r)
+
+			assert.Equal(t, expectedLocation, w.Header().Get("Location"))
+			if expectedLocation == "" {
+				assert.Equal(t, http.StatusNotFound, w.Code)
+			}
+		})
+	}
+}
+
+func Test_ownerDomain(t *testing.T) {
+	uid := uuid.Must(uuid.NewV4())
+	storage := store.NewInMemory()
+	u, _ := url.Parse("https://practicum.yandex.ru/")
+	_, err := storage.SaveLink(context.Background(), store.Link{ID: "sale", Domain: "a.co", URL: u, UserID: &uid})
+	require.NoError(t, err)
+
+	instance := &app.Instance{
+		BaseURL:    "http://localhost:8080",
+		Domains:    map[string]string{"a.co": "https://a.co"},
+		Store:      storage,
+		RemoveChan: make(chan models.BatchRemoveRequest, 1),
+	}
+	handler := Handler{Instance: instance}
+	request := func(method, target string, body string) *http.Request {
+		r := httptest.NewRequest(method, target, strings.NewReader(body))
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", "sale")
+		ctx := app.WithHost(auth.Context(context.Background(), uid), r.Host)
+		return r.WithContext(context.WithValue(ctx, chi.RouteCtxKey, rctx))
+	}
+
+	for query, expectedStatus := range map[string]int{"": http.StatusNotFound, "?domain=a.co": http.StatusOK, "?domain=evil.co": http.StatusBadRequest} {
+		t.Run("history"+query, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.HistoryHandler(w, request("GET", "http://localhost:8080/api/user/urls/sale/history"+query, ""))
+			assert.Equal(t, expectedStatus, w.Code)
+		})
+	}
+
+	w := httptest.NewRecorder()
+	handler.UpdateURLHandler(w, request("PATCH", "http://localhost:8080/api/user/urls/sale?domain=a.co", `{"original_url":"https://ya.ru/"}`))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"short_url":"https://a.co/sale"`)
+
+	w = httptest.NewRecorder()
+	handler.BatchRemoveAPIHandler(w, request("DELETE", "http://localhost:8080/api/user/urls?domain=a.co", `["sale"]`))
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, models.BatchRemoveRequest{UID: uid, Ids: []string{"a.co/sale"}}, <-instance.RemoveChan)
+}
+
+// clickSaver передает сохраненные переходы в канал
+type clickSaver chan store.Click
+
+func (c clickSaver) SaveClicks(_ context.Context, clicks []store.Click) error {
+	for _, click := range clicks {
+		c <- click
+	}
+	return nil
+}
+
+func Test_expanderRecordsClick(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	parsedURL, _ := url.Parse("https://praktikum.yandex.ru/")
+	storage := store.NewInMemory()
+	id, _ := storage.Save(ctx, parsedURL)
+
+	saved := make(clickSaver, 10)
+	instance := &app.Instance{
+		BaseURL: "http://localhost:8080",
+		Store:   storage,
+		Clicks:  analytics.NewRecorder(saved, 1, time.Hour),
+	}
+	go instance.Clicks.Run(ctx)
+	handler := Handler{Instance: instance}
+
+	expand := func(id string) int {
+		r := httptest.NewRequest("GET", "http://localhost:8080/"+id, nil)
+		r.Header.Set("Referer", "https://ya.ru/")
+		r.Header.Set("User-Agent", "curl/8.0")
+		r.Header.Set("X-Real-IP", "10.0.0.1")
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", id)
+		r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+		w := httptest.NewRecorder()
+		handler.ExpandHandler(w, r)
+		return w.Code
+	}
+
+	require.Equal(t, http.StatusNotFound, expand("missing"))
+	require.Equal(t, http.StatusTemporaryRedirect, expand(id))
+
+	select {
+	case click := <-saved:
+		assert.Equal(t, id, click.ID)
+		assert.Equal(t, "https://ya.ru/", click.Referrer)
+		assert.Equal(t, "curl/8.0", click.UserAgent)
+		assert.Equal(t, analytics.HashIP(config.AuthSecret, "10.0.0.1"), click.IPHash)
+		assert.False(t, click.Time.IsZero())
+	case <-time.After(time.Second):
+		t.Fatal("click is not recorded")
+	}
+	assert.Empty(t, saved, "only successful expansions are recorded")
+}
+
+func Test_passwordProtected(t *testing.T) {
+	expectedURL := "https://praktikum.yandex.ru/"
+	parsedURL, _ := url.Parse(expectedURL)
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	storage := store.NewInMemory()
+	id, err := storage.SaveLink(context.Background(), store.Link{URL: parsedURL, PasswordHash: string(hash)})
+	require.NoError(t, err)
+	handler := Handler{Instance: &app.Instance{
+		BaseURL: "http://localhost:8080",
+		Store:   storage,
+	}}
+
+	withID := func(r *http.Request) *http.Request {
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", id)
+		return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+	}
+	unlock := func(password string) *httptest.ResponseRecorder {
+		form := url.Values{"password": {password}}
+		r := httptest.NewRequest("POST", "http://localhost:8080/"+id, strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		handler.UnlockHandler(w, withID(r))
+		return w
+	}
+
+	w := httptest.NewRecorder()
+	handler.ExpandHandler(w, withID(httptest.NewRequest("GET", "http://localhost:8080/"+id, nil)))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Location"))
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, w.Body.String(), `name="password"`)
+
+	w = unlock("secret")
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+	assert.Equal(t, expectedURL, w.Header().Get("Location"))
+
+	w = unlock("")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	for j := 0; j < app.MaxPasswordAttempts; j++ {
+		w = unlock("guess")
+		require.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), "Wrong password")
+	}
+	w = unlock("secret")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+}
+
+func Test_passwordProtectedInterstitial(t *testing.T) {
+	parsedURL, _ := url.Parse("https://praktikum.yandex.ru/")
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+	storage := store.NewInMemory()
+	id, err := storage.SaveLink(context.Background(), store.Link{
+		URL:          parsedURL,
+		PasswordHash: string(hash),
+		Redirect:     store.RedirectInterstitial,
+	})
+	require.NoError(t, err)
+	handler := Handler{Instance: &app.Instance{
+		BaseURL: "http://localhost:8080",
+		Store:   storage,
+	}}
+
+	form := url.Values{"password": {"secret"}}
+	r := httptest.NewRequest("POST", "http://localhost:8080/"+id, strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", id)
+	r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+	w := httptest.NewRecorder()
+
+	handler.UnlockHandler(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Location"))
+	assert.Contains(t, w.Body.String(), `href="https://praktikum.yandex.ru/"`)
+}
+
+func Test_PreviewHandler(t *testing.T) {
+	parsedURL, _ := url.Parse("https://praktikum.yandex.ru/")
+	storage := store.NewInMemory()
+	id, err := storage.SaveLink(context.Background(), store.Link{URL: parsedURL, Meta: store.Meta{Title: "<b>Practicum</b>"}})
+	require.NoError(t, err)
+	handler := Handler{Instance: &app.Instance{
+		BaseURL: "http://localhost:8080",
+		Store:   storage,
+	}}
+
+	preview := func(id, accept string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "http://localhost:8080/"+id+"+", nil)
+		if accept != "" {
+			r.Header.Set("Accept", accept)
+		}
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", id)
+		r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+		w := httptest.NewRecorder()
+		handler.PreviewHandler(w, r)
+		return w
+	}
+
+	w := preview(id, "application/json")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	var resp models.LinkPreview
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, models.LinkActive, resp.Status)
+	assert.Equal(t, parsedURL.String(), resp.OriginalURL)
+	assert.Equal(t, "<b>Practicum</b>", resp.Title)
+	assert.NotNil(t, resp.CreatedAt)
+
+	for _, accept := range []string{"", "*/*", "text/html,application/json;q=0.9"} {
+		w = preview(id, accept)
+		require.Equal(t, http.StatusOK, w.Code, accept)
+		assert.Empty(t, w.Header().Get("Location"))
+		assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+		assert.Contains(t, w.Body.String(), "<code>https://praktikum.yandex.ru/</code>")
+		assert.Contains(t, w.Body.String(), "&lt;b&gt;Practicum&lt;/b&gt;")
+	}
+
+	w = preview("missing", "application/json")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
 func Test_userURLs(t *testing.T) {
 	uid := uuid.Must(uuid.NewV4())
 	u, _ := url.Parse("https://praktikum.yandex.ru/")
 
 	storage := store.NewInMemory()
 	id, _ := storage.SaveUser(context.Background(), uid, u)
+	tagged, _ := storage.SaveLink(context.Background(), store.Link{
+		URL:    &url.URL{Scheme: "https", Host: "go.dev"},
+		UserID: &uid,
+		Meta:   store.Meta{Title: "Go", Tags: []string{"go"}},
+	})
 
 	instance := &app.Instance{
 		BaseURL: "http://localhost:8080",
 		Store:   storage,
 	}
 	handler := Handler{Instance: instance}
+	links := map[string]string{
+		id:     "{\"short_url\":\"http://localhost:8080/" + id + "\",\"original_url\":\"https://praktikum.yandex.ru/\"}",
+		tagged: "{\"short_url\":\"http://localhost:8080/" + tagged + "\",\"original_url\":\"https://go.dev\",\"title\":\"Go\",\"tags\":[\"go\"]}",
+	}
 
 	testCases := []struct {
 		name           string
 		ctx            context.Context
+		query          string
 		expectedStatus int
 		expectedBody   []byte
 	}{
@@ -161,13 +830,48 @@ func Test_userURLs(t *testing.T) {
 			name:           "has_urls",
 			ctx:            auth.Context(context.Background(), uid),
 			expectedStatus: http.StatusOK,
-			expectedBody:   []byte("[{\"short_url\":\"http://localhost:8080/" + id + "\",\"original_url\":\"https://praktikum.yandex.ru/\"}]\n"),
+			expectedBody:   []byte("[" + links[id] + "," + links[tagged] + "]\n"),
+		},
+		{
+			name:           "by_tag",
+			ctx:            auth.Context(context.Background(), uid),
+			query:          "?tag=go",
+			expectedStatus: http.StatusOK,
+			expectedBody:   []byte("[" + links[tagged] + "]\n"),
+		},
+		{
+			name:           "no_tagged_urls",
+			ctx:            auth.Context(context.Background(), uid),
+			query:          "?tag=missing",
+			expectedStatus: http.StatusOK,
+			expectedBody:   []byte("[]\n"),
+		},
+		{
+			name:           "search",
+			ctx:            auth.Context(context.Background(), uid),
+			query:          "?q=GO.DEV",
+			expectedStatus: http.StatusOK,
+			expectedBody:   []byte("[" + links[tagged] + "]\n"),
+		},
+		{
+			name:           "newest_first",
+			ctx:            auth.Context(context.Background(), uid),
+			query:          "?sort=created&order=desc",
+			expectedStatus: http.StatusOK,
+			expectedBody:   []byte("[" + links[tagged] + "," + links[id] + "]\n"),
+		},
+		{
+			name:           "bad_limit",
+			ctx:            auth.Context(context.Background(), uid),
+			query:          "?limit=0",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   []byte("invalid page parameters: limit must be between 1 and 1000"),
 		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			r := httptest.NewRequest("GET", "http://localhost:8080/user/urls", nil)
+			r := httptest.NewRequest("GET", "http://localhost:8080/user/urls"+tc.query, nil)
 			r = r.WithContext(tc.ctx)
 
 			w := httptest.NewRecorder()
@@ -177,6 +881,248 @@ func Test_userURLs(t *testing.T) {
 			assert.Equal(t, tc.expectedBody, w.Body.Bytes())
 		})
 	}
+
+	t.Run("pages", func(t *testing.T) {
+		get := func(query string) *httptest.ResponseRecorder {
+			r := httptest.NewRequest("GET", "http://localhost:8080/user/urls"+query, nil)
+			r = r.WithContext(auth.Context(context.Background(), uid))
+			w := httptest.NewRecorder()
+			handler.UserURLsHandler(w, r)
+			return w
+		}
+
+		w := get("?limit=1")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "["+links[id]+"]\n", w.Body.String())
+		next := w.Header().Get("X-Next-Cursor")
+		require.NotEmpty(t, next)
+
+		w = get("?limit=1&cursor=" + next)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "["+links[tagged]+"]\n", w.Body.String())
+		assert.Empty(t, w.Header().Get("X-Next-Cursor"))
+
+		// a cursor only fits the order it was issued for
+		w = get("?limit=1&order=desc&cursor=" + next)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func Test_QRHandler(t *testing.T) {
+	uid := uuid.Must(uuid.NewV4())
+	u, _ := url.Parse("https://praktikum.yandex.ru/")
+
+	storage := store.NewInMemory()
+	id, _ := storage.SaveUser(context.Background(), uid, u)
+	deletedID, _ := storage.SaveUser(context.Background(), uid, &url.URL{Scheme: "https", Host: "deleted.ru"})
+	_ = storage.DeleteUsers(context.Background(), uid, deletedID)
+
+	instance := &app.Instance{
+		BaseURL: "http://localhost:8080",
+		Store:   storage,
+	}
+	handler := Handler{Instance: instance}
+
+	testCases := []struct {
+		name                string
+		id                  string
+		query               string
+		expectedStatus      int
+		expectedContentType string
+	}{
+		{name: "png", id: id, expectedStatus: http.StatusOK, expectedContentType: "image/png"},
+		{name: "svg", id: id, query: "?format=svg&size=128&level=H", expectedStatus: http.StatusOK, expectedContentType: "image/svg+xml"},
+		{name: "bad_params", id: id, query: "?size=100000", expectedStatus: http.StatusBadRequest},
+		{name: "not_found", id: "missing", expectedStatus: http.StatusNotFound},
+		{name: "deleted", id: deletedID, expectedStatus: http.StatusGone},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "http://localhost:8080/"+tc.id+"/qr"+tc.query, nil)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", tc.id)
+			r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+
+			w := httptest.NewRecorder()
+			handler.QRHandler(w, r)
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			if tc.expectedContentType != "" {
+				assert.Equal(t, tc.expectedContentType, w.Header().Get("Content-Type"))
+				assert.NotEmpty(t, w.Body.Bytes())
+			}
+		})
+	}
+}
+
+func Test_LinkStatsHandler(t *testing.T) {
+	uid := uuid.Must(uuid.NewV4())
+	u, _ := url.Parse("https://praktikum.yandex.ru/")
+
+	storage := store.NewInMemory()
+	id, _ := storage.SaveUser(context.Background(), uid, u)
+	now := time.Now().UTC()
+	_ = storage.SaveClicks(context.Background(), []store.Click{{Time: now, ID: id, Referrer: "https://ya.ru/", IPHash: "a"}})
+
+	instance := &app.Instance{
+		BaseURL: "http://localhost:8080",
+		Store:   storage,
+	}
+	handler := Handler{Instance: instance}
+
+	today := now.Format("2006-01-02")
+	testCases := []struct {
+		name           string
+		ctx            context.Context
+		query          string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "no_uid",
+			ctx:            context.Background(),
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "not_owner",
+			ctx:            auth.Context(context.Background(), uuid.Must(uuid.NewV4())),
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "bad_period",
+			ctx:            auth.Context(context.Background(), uid),
+			query:          "?from=yesterday",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "invalid stats period: bad from date \"yesterday\"",
+		},
+		{
+			name:           "success",
+			ctx:            auth.Context(context.Background(), uid),
+			query:          "?from=" + today + "&to=" + today,
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"id":"` + id + `","total_clicks":1,"unique_visitors":1,"daily":[{"date":"` + today + `","clicks":1}],` +
+				`"top_referrers":[{"value":"https://ya.ru/","clicks":1}],"top_user_agents":[]}` + "\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "http://localhost:8080/api/user/urls/"+id+"/stats"+tc.query, nil)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", id)
+			r = r.WithContext(context.WithValue(tc.ctx, chi.RouteCtxKey, rctx))
+
+			w := httptest.NewRecorder()
+			handler.LinkStatsHandler(w, r)
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			assert.Equal(t, tc.expectedBody, w.Body.String())
+		})
+	}
+}
+
+func Test_UpdateURLHandler(t *testing.T) {
+	uid := uuid.Must(uuid.NewV4())
+	u, _ := url.Parse("https://praktikum.yandex.ru/")
+
+	storage := store.NewInMemory()
+	id, _ := storage.SaveUser(context.Background(), uid, u)
+	instance := &app.Instance{
+		BaseURL: "http://localhost:8080",
+		Store:   storage,
+	}
+	handler := Handler{Instance: instance}
+
+	testCases := []struct {
+		name           string
+		ctx            context.Context
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "no_uid",
+			ctx:            context.Background(),
+			body:           `{"original_url":"https://practicum.yandex.ru/"}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "not_owner",
+			ctx:            auth.Context(context.Background(), uuid.Must(uuid.NewV4())),
+			body:           `{"original_url":"https://practicum.yandex.ru/"}`,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "bad_body",
+			ctx:            auth.Context(context.Background(), uid),
+			body:           `not json`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Bad request body given",
+		},
+		{
+			name:           "bad_url",
+			ctx:            auth.Context(context.Background(), uid),
+			body:           `{"original_url":"practicum"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "invalid URL scheme: scheme is required",
+		},
+		{
+			name:           "success",
+			ctx:            auth.Context(context.Background(), uid),
+			body:           `{"original_url":"https://practicum.yandex.ru/"}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"short_url":"http://localhost:8080/` + id + `","original_url":"https://practicum.yandex.ru/"}` + "\n",
+		},
+		{
+			name:           "bad_tags",
+			ctx:            auth.Context(context.Background(), uid),
+			body:           `{"tags":[" "]}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "invalid link description: tag must not be empty",
+		},
+		{
+			name:           "meta",
+			ctx:            auth.Context(context.Background(), uid),
+			body:           `{"title":"Practicum","tags":["learn","go"]}`,
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"short_url":"http://localhost:8080/` + id + `","original_url":"https://practicum.yandex.ru/",` +
+				`"title":"Practicum","tags":["learn","go"]}` + "\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest("PATCH", "http://localhost:8080/api/user/urls/"+id, strings.NewReader(tc.body))
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", id)
+			r = r.WithContext(context.WithValue(tc.ctx, chi.RouteCtxKey, rctx))
+
+			w := httptest.NewRecorder()
+			handler.UpdateURLHandler(w, r)
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			assert.Equal(t, tc.expectedBody, w.Body.String())
+		})
+	}
+
+	target, err := storage.Load(context.Background(), id)
+	require.NoError(t, err)
+	assert.Equal(t, "https://practicum.yandex.ru/", target.String())
+
+	r := httptest.NewRequest("GET", "http://localhost:8080/api/user/urls/"+id+"/history", nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", id)
+	r = r.WithContext(context.WithValue(auth.Context(context.Background(), uid), chi.RouteCtxKey, rctx))
+	w := httptest.NewRecorder()
+	handler.HistoryHandler(w, r)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var history []models.URLRevision
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&history))
+	require.Len(t, history, 1, "rejected changes are not recorded")
+	assert.Equal(t, u.String(), history[0].OriginalURL)
+	assert.False(t, history[0].ReplacedAt.IsZero())
 }
 
 func Test_ShortenHandler(t *testing.T) {
@@ -375,6 +1321,12 @@ func TestInstance_BatchShortenAPIHandler(t *testing.T) {
 			expectedStatus:   http.StatusBadRequest,
 			expectedResponse: []byte("Cannot parse given string as URL"),
 		},
+		{
+			name:             "no_host",
+			url:              "https:///path",
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: []byte("url 1: invalid URL host: host is required"),
+		},
 		{
 			name:             "success",
 			url:              targetURL,
@@ -0,0 +1,28 @@
+package http
+
+import (
+	"html/template"
+	"net/http"
+)
+
+// passwordForm страница ввода пароля для защищенной ссылки
+var passwordForm = template.Must(template.New("password").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Password required</title></head>
+<body>
+<form method="post" action="{{.Action}}">
+{{if .Error}}<p>{{.Error}}</p>
+{{end}}<label>Password <input type="password" name="password" autofocus></label>
+<button type="submit">Open</button>
+</form>
+</body>
+</html>
+`))
+
+// writePasswordForm отвечает формой ввода пароля, отправляемой на action, с сообщением об ошибке msg
+func writePasswordForm(w http.ResponseWriter, status int, action, msg string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = passwordForm.Execute(w, struct{ Action, Error string }{action, msg})
+}
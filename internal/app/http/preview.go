@@ -0,0 +1,52 @@
+package http
+
+import (
+	"html/template"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/models"
+)
+
+// previewPage страница с адресом ссылки и ее состоянием
+var previewPage = template.Must(template.New("preview").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Link preview</title></head>
+<body>
+<p><code>{{.ShortURL}}</code></p>
+{{if eq .Status "active"}}{{if .Protected}}<p>This link is protected by a password.</p>
+{{else}}{{if .Title}}<p>{{.Title}}</p>
+{{end}}<p>leads to <code>{{.OriginalURL}}</code></p>
+{{end}}{{else}}<p>This link is {{.Status}}.</p>
+{{end}}{{with .CreatedAt}}<p>Created {{.UTC.Format "2006-01-02 15:04 MST"}}</p>
+{{end}}{{with .ExpiresAt}}<p>Expires {{.UTC.Format "2006-01-02 15:04 MST"}}</p>
+{{end}}</body>
+</html>
+`))
+
+// writePreview отвечает страницей со сведениями о ссылке
+func writePreview(w http.ResponseWriter, preview *models.LinkPreview) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	_ = previewPage.Execute(w, preview)
+}
+
+// acceptsJSON сообщает, идет ли в заголовке Accept application/json раньше text/html.
+// Веса q не учитываются, без заголовка выбирается HTML
+func acceptsJSON(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case "application/json":
+			return true
+		case "text/html":
+			return false
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package http
+
+import (
+	"html/template"
+	"net/http"
+
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/store"
+)
+
+// redirectStatuses коды ответа для способов перехода с перенаправлением
+var redirectStatuses = map[store.RedirectMode]int{
+	store.RedirectMovedPermanently: http.StatusMovedPermanently,
+	store.RedirectFound:            http.StatusFound,
+	store.RedirectTemporary:        http.StatusTemporaryRedirect,
+	store.RedirectPermanent:        http.StatusPermanentRedirect,
+}
+
+// redirectStatus возвращает код ответа для способа перехода mode, по умолчанию 307
+func redirectStatus(mode store.RedirectMode) int {
+	if code, ok := redirectStatuses[mode]; ok {
+		return code
+	}
+	return http.StatusTemporaryRedirect
+}
+
+// interstitialPage промежуточная страница, показывающая адрес перед переходом
+var interstitialPage = template.Must(template.New("interstitial").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Leaving for another site</title></head>
+<body>
+<p>This link leads to</p>
+<p><code>{{.}}</code></p>
+<a href="{{.}}">Continue</a>
+</body>
+</html>
+`))
+
+// metaRefreshPage страница, переходящая по адресу без передачи Referer
+var metaRefreshPage = template.Must(template.New("meta-refresh").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="referrer" content="no-referrer">
+<meta http-equiv="refresh" content="0; url={{.}}">
+<title>Redirecting</title>
+</head>
+<body>
+<a href="{{.}}" rel="noreferrer">Continue</a>
+</body>
+</html>
+`))
+
+// writeInterstitial отвечает промежуточной страницей со ссылкой на target
+func writeInterstitial(w http.ResponseWriter, target string) {
+	writePage(w, interstitialPage, target)
+}
+
+// writeMetaRefresh отвечает страницей, переходящей на target без передачи Referer
+func writeMetaRefresh(w http.ResponseWriter, target string) {
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	writePage(w, metaRefreshPage, target)
+}
+
+func writePage(w http.ResponseWriter, page *template.Template, target string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	_ = page.Execute(w, target)
+}
@@ -0,0 +1,93 @@
+package app
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/store"
+)
+
+// MaxPageSize наибольшее число ссылок на одной странице списка ссылок пользователя
+const MaxPageSize = 1000
+
+// Поля сортировки списка ссылок пользователя
+const (
+	SortCreated = "created" // SortCreated по времени создания
+	SortClicks  = "clicks"  // SortClicks по числу переходов
+)
+
+// Направления сортировки списка ссылок пользователя
+const (
+	OrderAsc  = "asc"  // OrderAsc по возрастанию
+	OrderDesc = "desc" // OrderDesc по убыванию
+)
+
+// linkCursor содержимое курсора страницы. Порядок сохраняется в курсоре,
+// чтобы курсор нельзя было применить к списку в другом порядке.
+type linkCursor struct {
+	Order store.LinkOrder `json:"o"`
+	Desc  bool            `json:"d,omitempty"`
+	Key   int64           `json:"k"`
+	ID    string          `json:"id"`
+}
+
+// ParseLinkQuery разбирает параметры списка ссылок пользователя из строк запроса.
+// Пустые значения означают любую метку и адрес, сортировку по времени создания по возрастанию,
+// список без ограничения длины и первую страницу.
+func ParseLinkQuery(tag, search, sortBy, order, limit, cursor string) (store.LinkFilter, error) {
+	filter := store.LinkFilter{Tag: strings.TrimSpace(tag), Search: strings.TrimSpace(search)}
+	switch sortBy {
+	case "", SortCreated:
+	case SortClicks:
+		filter.Order = store.OrderClicks
+	default:
+		return filter, fmt.Errorf("%w: unknown sort field %q", ErrPage, sortBy)
+	}
+	switch order {
+	case "", OrderAsc:
+	case OrderDesc:
+		filter.Desc = true
+	default:
+		return filter, fmt.Errorf("%w: unknown sort order %q", ErrPage, order)
+	}
+	if limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > MaxPageSize {
+			return filter, fmt.Errorf("%w: limit must be between 1 and %d", ErrPage, MaxPageSize)
+		}
+		filter.Limit = n
+	}
+	if cursor != "" {
+		after, err := decodeCursor(cursor, filter)
+		if err != nil {
+			return filter, err
+		}
+		filter.After = &after
+	}
+	return filter, nil
+}
+
+// encodeCursor возвращает курсор страницы, которая начинается после позиции after
+func encodeCursor(filter store.LinkFilter, after store.LinkCursor) string {
+	b, _ := json.Marshal(linkCursor{Order: filter.Order, Desc: filter.Desc, Key: after.Key, ID: after.ID})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCursor разбирает курсор страницы и проверяет, что он выдан для того же порядка
+func decodeCursor(cursor string, filter store.LinkFilter) (store.LinkCursor, error) {
+	var c linkCursor
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err == nil {
+		err = json.Unmarshal(b, &c)
+	}
+	if err != nil || c.ID == "" {
+		return store.LinkCursor{}, fmt.Errorf("%w: malformed cursor", ErrPage)
+	}
+	if c.Order != filter.Order || c.Desc != filter.Desc {
+		return store.LinkCursor{}, fmt.Errorf("%w: cursor belongs to a different sort order", ErrPage)
+	}
+	return store.LinkCursor{Key: c.Key, ID: c.ID}, nil
+}
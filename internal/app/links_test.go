@@ -0,0 +1,63 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/store"
+)
+
+func TestParseLinkQuery(t *testing.T) {
+	byClicks := store.LinkFilter{Order: store.OrderClicks, Desc: true}
+	cursor := encodeCursor(byClicks, store.LinkCursor{Key: 42, ID: "abc"})
+
+	tests := []struct {
+		name    string
+		params  [6]string // tag, search, sort, order, limit, cursor
+		want    store.LinkFilter
+		wantErr bool
+	}{
+		{name: "defaults", want: store.LinkFilter{}},
+		{
+			name:   "filters",
+			params: [6]string{" go ", " docs ", SortCreated, OrderAsc, "10", ""},
+			want:   store.LinkFilter{Tag: "go", Search: "docs", Limit: 10},
+		},
+		{
+			name:   "cursor",
+			params: [6]string{"", "", SortClicks, OrderDesc, "", cursor},
+			want:   store.LinkFilter{Order: store.OrderClicks, Desc: true, After: &store.LinkCursor{Key: 42, ID: "abc"}},
+		},
+		{name: "unknown sort", params: [6]string{"", "", "title", "", "", ""}, wantErr: true},
+		{name: "unknown order", params: [6]string{"", "", "", "up", "", ""}, wantErr: true},
+		{name: "zero limit", params: [6]string{"", "", "", "", "0", ""}, wantErr: true},
+		{name: "large limit", params: [6]string{"", "", "", "", "1001", ""}, wantErr: true},
+		{name: "malformed cursor", params: [6]string{"", "", "", "", "", "%%%"}, wantErr: true},
+		{name: "cursor of another order", params: [6]string{"", "", SortClicks, OrderAsc, "", cursor}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := tt.params
+			got, err := ParseLinkQuery(p[0], p[1], p[2], p[3], p[4], p[5])
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrPage)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestEncodeCursor(t *testing.T) {
+	filter := store.LinkFilter{Order: store.OrderCreated}
+	cursor := encodeCursor(filter, store.LinkCursor{Key: 1700000000000000000, ID: "my-alias"})
+	assert.False(t, strings.ContainsAny(cursor, "+/="), "cursor must be safe in a query string")
+
+	got, err := decodeCursor(cursor, filter)
+	require.NoError(t, err)
+	assert.Equal(t, store.LinkCursor{Key: 1700000000000000000, ID: "my-alias"}, got)
+}
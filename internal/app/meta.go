@@ -0,0 +1,59 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/store"
+)
+
+// Ограничения описания ссылки
+const (
+	MaxTitleLength = 200  // MaxTitleLength наибольшая длина названия в символах
+	MaxNotesLength = 2000 // MaxNotesLength наибольшая длина заметок в символах
+	MaxTags        = 20   // MaxTags наибольшее число меток у ссылки
+	MaxTagLength   = 64   // MaxTagLength наибольшая длина метки в символах
+)
+
+// NewMeta проверяет описание ссылки и приводит метки к единому виду:
+// пробелы по краям отбрасываются, повторы удаляются с сохранением порядка.
+func NewMeta(title, notes string, tags []string) (store.Meta, error) {
+	meta := store.Meta{Title: strings.TrimSpace(title), Notes: notes}
+	if utf8.RuneCountInString(meta.Title) > MaxTitleLength {
+		return store.Meta{}, fmt.Errorf("%w: title is longer than %d characters", ErrMeta, MaxTitleLength)
+	}
+	if utf8.RuneCountInString(meta.Notes) > MaxNotesLength {
+		return store.Meta{}, fmt.Errorf("%w: notes are longer than %d characters", ErrMeta, MaxNotesLength)
+	}
+	var err error
+	if meta.Tags, err = NormalizeTags(tags); err != nil {
+		return store.Meta{}, err
+	}
+	return meta, nil
+}
+
+// NormalizeTags проверяет метки ссылки и удаляет повторы.
+// Для пустого списка возвращается nil.
+func NormalizeTags(tags []string) ([]string, error) {
+	var res []string
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			return nil, fmt.Errorf("%w: tag must not be empty", ErrMeta)
+		}
+		if utf8.RuneCountInString(tag) > MaxTagLength {
+			return nil, fmt.Errorf("%w: tag %q is longer than %d characters", ErrMeta, tag, MaxTagLength)
+		}
+		if seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		res = append(res, tag)
+	}
+	if len(res) > MaxTags {
+		return nil, fmt.Errorf("%w: more than %d tags", ErrMeta, MaxTags)
+	}
+	return res, nil
+}
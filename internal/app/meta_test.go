@@ -0,0 +1,51 @@
+package app
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewMeta(t *testing.T) {
+	tests := []struct {
+		name     string
+		title    string
+		notes    string
+		tags     []string
+		wantTags []string
+		wantErr  bool
+	}{
+		{name: "empty"},
+		{name: "normalized tags", title: " Go ", tags: []string{" go", "work", "go "}, wantTags: []string{"go", "work"}},
+		{name: "unicode title", title: strings.Repeat("я", MaxTitleLength)},
+		{name: "long title", title: strings.Repeat("a", MaxTitleLength+1), wantErr: true},
+		{name: "long notes", notes: strings.Repeat("a", MaxNotesLength+1), wantErr: true},
+		{name: "empty tag", tags: []string{"go", " "}, wantErr: true},
+		{name: "long tag", tags: []string{strings.Repeat("a", MaxTagLength+1)}, wantErr: true},
+		{name: "too many tags", tags: manyTags(MaxTags + 1), wantErr: true},
+		{name: "duplicates do not count", tags: append(manyTags(MaxTags), "tag0"), wantTags: manyTags(MaxTags)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta, err := NewMeta(tt.title, tt.notes, tt.tags)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrMeta)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, strings.TrimSpace(tt.title), meta.Title)
+			assert.Equal(t, tt.wantTags, meta.Tags)
+		})
+	}
+}
+
+func manyTags(n int) []string {
+	tags := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		tags = append(tags, "tag"+strconv.Itoa(i))
+	}
+	return tags
+}
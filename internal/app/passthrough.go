@@ -0,0 +1,108 @@
+package app
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/store"
+)
+
+// Tail часть запроса после кода ссылки, которая может быть перенесена в адрес перехода
+type Tail struct {
+	Path     string // Path путь после кода в экранированном виде, например /getting-started
+	RawQuery string // RawQuery строка параметров запроса без ?
+}
+
+// ParsePassthrough проверяет режим переноса частей запроса. Пустая строка означает, что ничего не переносится.
+func ParsePassthrough(mode string) (store.PassthroughMode, error) {
+	switch m := store.PassthroughMode(mode); m {
+	case "", store.PassthroughPath, store.PassthroughQuery, store.PassthroughAll:
+		return m, nil
+	}
+	return "", fmt.Errorf("%w: unknown mode %q", ErrPassthrough, mode)
+}
+
+// expandedURL возвращает адрес перехода по ссылке link с продолжением запроса tail
+func expandedURL(link *store.Link, tail Tail) (*url.URL, error) {
+	if link.Template {
+		return fillTemplate(link.URL, tail)
+	}
+	if !acceptsTail(link.Passthrough, tail) {
+		return nil, store.ErrNotFound
+	}
+	return mergeTail(link.URL, link.Passthrough, tail), nil
+}
+
+// acceptsTail сообщает, можно ли перейти по ссылке с режимом mode, если запрос продолжается tail.
+// Путь после кода допустим только у ссылок, переносящих путь, и не может содержать сегменты . и ..,
+// чтобы не выйти за пределы пути адреса.
+func acceptsTail(mode store.PassthroughMode, tail Tail) bool {
+	extra := strings.TrimPrefix(tail.Path, "/")
+	if extra == "" {
+		return true
+	}
+	if !mode.Path() {
+		return false
+	}
+	for _, segment := range strings.Split(extra, "/") {
+		if s, err := url.PathUnescape(segment); err != nil || s == "." || s == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeTail переносит tail в адрес target по режиму mode, не меняя target.
+// Путь дописывается к пути адреса через /. Параметры запроса добавляются после параметров адреса,
+// а параметры, которые уже есть в адресе, сохраняют значения из адреса: входящие значения для них отбрасываются.
+func mergeTail(target *url.URL, mode store.PassthroughMode, tail Tail) *url.URL {
+	res := *target
+	if extra := strings.TrimPrefix(tail.Path, "/"); extra != "" && mode.Path() {
+		escaped := strings.TrimSuffix(res.EscapedPath(), "/") + "/" + extra
+		if p, err := url.PathUnescape(escaped); err == nil {
+			res.Path, res.RawPath = p, escaped
+		}
+	}
+	if tail.RawQuery != "" && mode.Query() {
+		res.RawQuery = mergeQuery(res.RawQuery, tail.RawQuery)
+	}
+	return &res
+}
+
+// mergeQuery добавляет к строке параметров адреса target параметры из incoming, ключей которых в адресе нет.
+// Порядок и экранирование параметров сохраняются.
+func mergeQuery(target, incoming string) string {
+	parts := splitQuery(target)
+	own := make(map[string]bool, len(parts))
+	for _, part := range parts {
+		own[queryKey(part)] = true
+	}
+	for _, part := range splitQuery(incoming) {
+		if !own[queryKey(part)] {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func splitQuery(rawQuery string) []string {
+	var parts []string
+	for _, part := range strings.Split(rawQuery, "&") {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+func queryKey(part string) string {
+	key := part
+	if i := strings.IndexByte(part, '='); i >= 0 {
+		key = part[:i]
+	}
+	if k, err := url.QueryUnescape(key); err == nil {
+		key = k
+	}
+	return key
+}
@@ -0,0 +1,122 @@
+package app
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/store"
+)
+
+func TestParsePassthrough(t *testing.T) {
+	for _, mode := range []string{"", "path", "query", "all"} {
+		got, err := ParsePassthrough(mode)
+		require.NoError(t, err, mode)
+		assert.Equal(t, store.PassthroughMode(mode), got)
+	}
+	_, err := ParsePassthrough("both")
+	assert.ErrorIs(t, err, ErrPassthrough)
+}
+
+func TestMergeTail(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		mode   store.PassthroughMode
+		tail   Tail
+		want   string
+	}{
+		{name: "nothing", target: "https://ya.ru/docs", mode: store.PassthroughAll, want: "https://ya.ru/docs"},
+		{
+			name:   "path",
+			target: "https://ya.ru/docs",
+			mode:   store.PassthroughPath,
+			tail:   Tail{Path: "/getting-started", RawQuery: "lang=en"},
+			want:   "https://ya.ru/docs/getting-started",
+		},
+		{
+			name:   "path after slash",
+			target: "https://ya.ru/docs/?v=2",
+			mode:   store.PassthroughPath,
+			tail:   Tail{Path: "/a/b/"},
+			want:   "https://ya.ru/docs/a/b/?v=2",
+		},
+		{
+			name:   "escaped path",
+			target: "https://ya.ru/docs",
+			mode:   store.PassthroughPath,
+			tail:   Tail{Path: "/a%2Fb%20c"},
+			want:   "https://ya.ru/docs/a%2Fb%20c",
+		},
+		{
+			name:   "query",
+			target: "https://ya.ru/docs",
+			mode:   store.PassthroughQuery,
+			tail:   Tail{Path: "/ignored", RawQuery: "lang=en"},
+			want:   "https://ya.ru/docs?lang=en",
+		},
+		{
+			name:   "query conflict",
+			target: "https://ya.ru/docs?lang=ru&ref=short",
+			mode:   store.PassthroughAll,
+			tail:   Tail{Path: "/faq", RawQuery: "lang=en&q=go&q=rust&ref=evil&%72ef=escaped"},
+			want:   "https://ya.ru/docs/faq?lang=ru&ref=short&q=go&q=rust",
+		},
+		{
+			name:   "not allowed",
+			target: "https://ya.ru/docs?lang=ru",
+			tail:   Tail{Path: "/faq", RawQuery: "lang=en"},
+			want:   "https://ya.ru/docs?lang=ru",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, err := url.Parse(tt.target)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, mergeTail(target, tt.mode, tt.tail).String())
+			assert.Equal(t, tt.target, target.String(), "target is not changed")
+		})
+	}
+}
+
+func TestAcceptsTail(t *testing.T) {
+	assert.True(t, acceptsTail("", Tail{RawQuery: "a=1"}))
+	assert.True(t, acceptsTail("", Tail{Path: "/"}))
+	assert.False(t, acceptsTail("", Tail{Path: "/faq"}))
+	assert.False(t, acceptsTail(store.PassthroughQuery, Tail{Path: "/faq"}))
+	assert.True(t, acceptsTail(store.PassthroughPath, Tail{Path: "/faq/"}))
+	assert.False(t, acceptsTail(store.PassthroughPath, Tail{Path: "/../admin"}))
+	assert.False(t, acceptsTail(store.PassthroughAll, Tail{Path: "/a/%2e%2E/admin"}))
+	assert.False(t, acceptsTail(store.PassthroughAll, Tail{Path: "/a/./b"}))
+}
+
+func TestInstance_ExpandTail(t *testing.T) {
+	ctx := context.Background()
+	instance := &Instance{
+		BaseURL: "http://localhost:8080",
+		Store:   store.NewInMemory(),
+	}
+	u, _ := url.Parse("https://ya.ru/docs")
+	prefix, err := instance.Store.SaveLink(ctx, store.Link{URL: u, Passthrough: store.PassthroughAll, ClicksLeft: 1})
+	require.NoError(t, err)
+	plain, err := instance.Store.Save(ctx, u)
+	require.NoError(t, err)
+
+	_, err = instance.ExpandTail(ctx, plain, "", Tail{Path: "/faq"})
+	assert.ErrorIs(t, err, store.ErrNotFound)
+	link, err := instance.ExpandTail(ctx, plain, "", Tail{RawQuery: "lang=en"})
+	require.NoError(t, err)
+	assert.Equal(t, u.String(), link.URL.String())
+
+	// a rejected path does not use up the link
+	_, err = instance.ExpandTail(ctx, prefix, "", Tail{Path: "/../admin"})
+	assert.ErrorIs(t, err, store.ErrNotFound)
+	link, err = instance.ExpandTail(ctx, prefix, "", Tail{Path: "/getting-started", RawQuery: "lang=en"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://ya.ru/docs/getting-started?lang=en", link.URL.String())
+	_, err = instance.ExpandTail(ctx, prefix, "", Tail{})
+	assert.ErrorIs(t, err, store.ErrDeleted)
+}
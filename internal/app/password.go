@@ -0,0 +1,113 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/store"
+)
+
+// Ограничение попыток ввода пароля: не больше MaxPasswordAttempts неудачных попыток
+// по одной ссылке за PasswordAttemptWindow
+const (
+	MaxPasswordAttempts   = 5
+	PasswordAttemptWindow = time.Minute
+)
+
+// maxTrackedLinks число ссылок, после которого из учета удаляются устаревшие попытки
+const maxTrackedLinks = 10000
+
+// HashPassword возвращает соленый хеш пароля ссылки.
+// Пустой пароль означает ссылку без пароля, для него возвращается пустая строка.
+func HashPassword(password string) (string, error) {
+	if password == "" {
+		return "", nil
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if errors.Is(err, bcrypt.ErrPasswordTooLong) {
+		return "", fmt.Errorf("%w: password is longer than 72 bytes", ErrPassword)
+	}
+	if err != nil {
+		return "", fmt.Errorf("cannot hash password: %w", err)
+	}
+	return string(hash), nil
+}
+
+// checkPassword проверяет пароль защищенной ссылки с учетом ограничения попыток.
+// Попытка занимается до сравнения хешей, чтобы параллельные запросы не обходили ограничение,
+// и возвращается при верном пароле.
+func (i *Instance) checkPassword(link *store.Link, password string) error {
+	if link.PasswordHash == "" {
+		return nil
+	}
+	if password == "" {
+		return ErrPasswordRequired
+	}
+
+	now := time.Now()
+	if !i.attempts.allow(link.ID, now) {
+		return ErrTooManyAttempts
+	}
+	if bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(password)) != nil {
+		return ErrWrongPassword
+	}
+	i.attempts.refund(link.ID, now)
+	return nil
+}
+
+// attemptLimiter считает попытки ввода пароля по каждой ссылке в фиксированном окне.
+// Нулевое значение готово к использованию.
+type attemptLimiter struct {
+	mu    sync.Mutex
+	tries map[string]*attempts
+}
+
+// attempts попытки по одной ссылке
+type attempts struct {
+	count int
+	since time.Time
+}
+
+// allow занимает попытку проверить пароль ссылки id и сообщает, разрешена ли она.
+// Занятая попытка считается неудачной, пока ее не вернет refund.
+func (l *attemptLimiter) allow(id string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.tries == nil {
+		l.tries = make(map[string]*attempts)
+	}
+	if len(l.tries) >= maxTrackedLinks {
+		for k, a := range l.tries {
+			if now.Sub(a.since) >= PasswordAttemptWindow {
+				delete(l.tries, k)
+			}
+		}
+	}
+
+	a, ok := l.tries[id]
+	if !ok || now.Sub(a.since) >= PasswordAttemptWindow {
+		l.tries[id] = &attempts{count: 1, since: now}
+		return true
+	}
+	if a.count >= MaxPasswordAttempts {
+		return false
+	}
+	a.count++
+	return true
+}
+
+// refund возвращает попытку ссылки id, занятую allow в момент now, если пароль оказался верным
+func (l *attemptLimiter) refund(id string, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// an attempt taken in an earlier window is already forgotten
+	if a, ok := l.tries[id]; ok && !now.Before(a.since) && now.Sub(a.since) < PasswordAttemptWindow && a.count > 0 {
+		a.count--
+	}
+}
@@ -0,0 +1,179 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/store"
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/models"
+)
+
+func TestHashPassword(t *testing.T) {
+	hash, err := HashPassword("")
+	require.NoError(t, err)
+	assert.Empty(t, hash)
+
+	hash, err = HashPassword("secret")
+	require.NoError(t, err)
+	assert.NotContains(t, hash, "secret")
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(hash), []byte("secret")))
+
+	other, err := HashPassword("secret")
+	require.NoError(t, err)
+	assert.NotEqual(t, hash, other, "hashes are salted")
+
+	_, err = HashPassword(strings.Repeat("x", 73))
+	assert.ErrorIs(t, err, ErrPassword)
+}
+
+func TestInstance_Expand(t *testing.T) {
+	ctx := context.Background()
+	instance := &Instance{
+		BaseURL: "http://localhost:8080",
+		Store:   store.NewInMemory(),
+	}
+	u, _ := url.Parse("https://praktikum.yandex.ru/")
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+	protected, err := instance.Store.SaveLink(ctx, store.Link{URL: u, PasswordHash: string(hash)})
+	require.NoError(t, err)
+	plain, err := instance.Store.Save(ctx, u)
+	require.NoError(t, err)
+
+	link, err := instance.Expand(ctx, plain, "")
+	require.NoError(t, err)
+	assert.Equal(t, u.String(), link.URL.String())
+
+	_, err = instance.Expand(ctx, protected, "")
+	assert.ErrorIs(t, err, ErrPasswordRequired)
+
+	link, err = instance.Expand(ctx, protected, "secret")
+	require.NoError(t, err)
+	assert.Equal(t, u.String(), link.URL.String())
+
+	for j := 0; j < MaxPasswordAttempts; j++ {
+		_, err = instance.Expand(ctx, protected, "guess")
+		assert.ErrorIs(t, err, ErrWrongPassword)
+	}
+	_, err = instance.Expand(ctx, protected, "secret")
+	assert.ErrorIs(t, err, ErrTooManyAttempts)
+
+	// other links are not affected
+	other, err := instance.Store.SaveLink(ctx, store.Link{URL: u, PasswordHash: string(hash)})
+	require.NoError(t, err)
+	_, err = instance.Expand(ctx, other, "secret")
+	assert.NoError(t, err)
+
+	_, err = instance.Expand(ctx, "missing", "secret")
+	assert.ErrorIs(t, err, store.ErrNotFound)
+}
+
+func TestInstance_ShortenLink_Password(t *testing.T) {
+	ctx := context.Background()
+	instance := &Instance{
+		BaseURL: "http://localhost:8080",
+		Store:   store.NewInMemory(),
+	}
+
+	shortURL, err := instance.ShortenLink(ctx, models.ShortenRequest{
+		URL:         "https://praktikum.yandex.ru/",
+		LinkOptions: models.LinkOptions{Password: "secret"},
+	})
+	require.NoError(t, err)
+	id := strings.TrimPrefix(shortURL, instance.BaseURL+"/")
+
+	_, err = instance.Expand(ctx, id, "")
+	assert.ErrorIs(t, err, ErrPasswordRequired)
+	_, err = instance.Expand(ctx, id, "secret")
+	assert.NoError(t, err)
+
+	_, err = instance.ShortenLink(ctx, models.ShortenRequest{
+		URL:         "https://praktikum.yandex.ru/",
+		LinkOptions: models.LinkOptions{Password: strings.Repeat("x", 73)},
+	})
+	assert.ErrorIs(t, err, ErrPassword)
+}
+
+func TestAttemptLimiter(t *testing.T) {
+	var l attemptLimiter
+	now := time.Now()
+
+	for j := 0; j < MaxPasswordAttempts; j++ {
+		require.True(t, l.allow("a", now))
+	}
+	assert.False(t, l.allow("a", now.Add(PasswordAttemptWindow/2)))
+	assert.True(t, l.allow("b", now))
+
+	// the window is over
+	assert.True(t, l.allow("a", now.Add(PasswordAttemptWindow)))
+	assert.True(t, l.allow("a", now.Add(PasswordAttemptWindow)))
+
+	// successful attempts are given back
+	for j := 0; j < 2*MaxPasswordAttempts; j++ {
+		require.True(t, l.allow("c", now))
+		l.refund("c", now)
+	}
+}
+
+func TestAttemptLimiter_Concurrent(t *testing.T) {
+	var l attemptLimiter
+	now := time.Now()
+
+	const requests = 50
+	var allowed int64
+	var wg sync.WaitGroup
+	for j := 0; j < requests; j++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if l.allow("a", now) {
+				atomic.AddInt64(&allowed, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.EqualValues(t, MaxPasswordAttempts, allowed)
+}
+
+func TestInstance_Expand_ConcurrentGuesses(t *testing.T) {
+	ctx := context.Background()
+	instance := &Instance{
+		BaseURL: "http://localhost:8080",
+		Store:   store.NewInMemory(),
+	}
+	u, _ := url.Parse("https://praktikum.yandex.ru/")
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+	id, err := instance.Store.SaveLink(ctx, store.Link{URL: u, PasswordHash: string(hash)})
+	require.NoError(t, err)
+
+	const requests = 50
+	var wrong, limited int64
+	var wg sync.WaitGroup
+	for j := 0; j < requests; j++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := instance.Expand(ctx, id, "guess")
+			switch {
+			case errors.Is(err, ErrWrongPassword):
+				atomic.AddInt64(&wrong, 1)
+			case errors.Is(err, ErrTooManyAttempts):
+				atomic.AddInt64(&limited, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.EqualValues(t, MaxPasswordAttempts, wrong, "only the allowed attempts reach bcrypt")
+	assert.EqualValues(t, requests-MaxPasswordAttempts, limited)
+}
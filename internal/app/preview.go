@@ -0,0 +1,54 @@
+package app
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/store"
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/models"
+)
+
+// Preview возвращает сведения о ссылке с кодом id на домене запроса, не списывая переход и не записывая его.
+// Удаленная ссылка, ссылка с истекшим сроком и ссылка на запрещенный адрес возвращаются только с состоянием,
+// у ссылки с паролем адрес и название не раскрываются. Для несуществующей ссылки возвращается store.ErrNotFound.
+func (i *Instance) Preview(ctx context.Context, id string) (*models.LinkPreview, error) {
+	id = i.LinkID(ctx, id)
+	preview := &models.LinkPreview{ShortURL: i.shortURL(id), Status: models.LinkActive}
+	link, err := i.Store.LoadLink(ctx, id)
+	switch {
+	case errors.Is(err, store.ErrDeleted):
+		preview.Status = models.LinkDeleted
+		return preview, nil
+	case errors.Is(err, store.ErrExpired):
+		preview.Status = models.LinkExpired
+		return preview, nil
+	case err != nil:
+		return nil, err
+	}
+	if err := i.blocked(ctx, link.URL); err != nil {
+		preview.Status = models.LinkBlocked
+		return preview, nil
+	}
+
+	preview = i.LinkPreview(link)
+	if preview.Protected {
+		preview.OriginalURL, preview.Title = "", ""
+	}
+	return preview, nil
+}
+
+// LinkPreview возвращает сведения об активной ссылке link, например полученной от Expand, вместе с адресом
+func (i *Instance) LinkPreview(link *store.Link) *models.LinkPreview {
+	preview := &models.LinkPreview{
+		ShortURL:    i.shortURL(link.ID),
+		Status:      models.LinkActive,
+		OriginalURL: link.URL.String(),
+		Title:       link.Title,
+		ExpiresAt:   link.ExpiresAt,
+		Protected:   link.PasswordHash != "",
+	}
+	if !link.CreatedAt.IsZero() {
+		preview.CreatedAt = &link.CreatedAt
+	}
+	return preview
+}
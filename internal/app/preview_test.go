@@ -0,0 +1,87 @@
+package app
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/store"
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/urlcheck/urlchecktest"
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/models"
+)
+
+func TestInstance_Preview(t *testing.T) {
+	ctx := context.Background()
+	checker := &urlchecktest.Fake{}
+	instance := &Instance{
+		BaseURL:    "http://localhost:8080",
+		Store:      store.NewInMemory(),
+		URLChecker: checker,
+	}
+	uid := uuid.Must(uuid.NewV4())
+	u, _ := url.Parse("https://practicum.yandex.ru/")
+	future := time.Now().Add(time.Hour)
+	past := time.Now().Add(-time.Minute)
+
+	active, err := instance.Store.SaveLink(ctx, store.Link{URL: u, UserID: &uid, ExpiresAt: &future, ClicksLeft: 1,
+		Meta: store.Meta{Title: "Practicum", Notes: "private"}})
+	require.NoError(t, err)
+	protected, err := instance.Store.SaveLink(ctx, store.Link{URL: u, PasswordHash: "hash", Meta: store.Meta{Title: "Practicum"}})
+	require.NoError(t, err)
+	expired, err := instance.Store.SaveLink(ctx, store.Link{URL: u, ExpiresAt: &past})
+	require.NoError(t, err)
+	deleted, err := instance.Store.SaveUser(ctx, uid, u)
+	require.NoError(t, err)
+	require.NoError(t, instance.Store.DeleteUsers(ctx, uid, deleted))
+
+	preview, err := instance.Preview(ctx, active)
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost:8080/"+active, preview.ShortURL)
+	assert.Equal(t, models.LinkActive, preview.Status)
+	assert.Equal(t, u.String(), preview.OriginalURL)
+	assert.Equal(t, "Practicum", preview.Title)
+	require.NotNil(t, preview.CreatedAt)
+	assert.WithinDuration(t, time.Now(), *preview.CreatedAt, time.Minute)
+	require.NotNil(t, preview.ExpiresAt)
+	assert.False(t, preview.Protected)
+
+	// the single click is still there
+	_, err = instance.Preview(ctx, active)
+	require.NoError(t, err)
+	_, err = instance.Expand(ctx, active, "")
+	require.NoError(t, err)
+	preview, err = instance.Preview(ctx, active)
+	require.NoError(t, err)
+	assert.Equal(t, models.LinkDeleted, preview.Status)
+
+	preview, err = instance.Preview(ctx, protected)
+	require.NoError(t, err)
+	assert.Equal(t, models.LinkActive, preview.Status)
+	assert.True(t, preview.Protected)
+	assert.Empty(t, preview.OriginalURL)
+	assert.Empty(t, preview.Title)
+
+	preview, err = instance.Preview(ctx, expired)
+	require.NoError(t, err)
+	assert.Equal(t, models.LinkExpired, preview.Status)
+	assert.Empty(t, preview.OriginalURL)
+
+	preview, err = instance.Preview(ctx, deleted)
+	require.NoError(t, err)
+	assert.Equal(t, models.LinkDeleted, preview.Status)
+	assert.Empty(t, preview.OriginalURL)
+
+	checker.Block("practicum.yandex.ru")
+	preview, err = instance.Preview(ctx, protected)
+	require.NoError(t, err)
+	assert.Equal(t, models.LinkBlocked, preview.Status)
+	assert.Empty(t, preview.OriginalURL)
+
+	_, err = instance.Preview(ctx, "missing")
+	assert.ErrorIs(t, err, store.ErrNotFound)
+}
@@ -0,0 +1,80 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/qr"
+)
+
+// Форматы изображения QR-кода
+const (
+	QRFormatPNG = "png" // QRFormatPNG растровое изображение
+	QRFormatSVG = "svg" // QRFormatSVG векторное изображение
+)
+
+// Ограничения размера изображения QR-кода в пикселях
+const (
+	DefaultQRSize = 256  // DefaultQRSize размер по умолчанию
+	MinQRSize     = 32   // MinQRSize минимальный размер
+	MaxQRSize     = 2048 // MaxQRSize максимальный размер
+)
+
+// QROptions параметры изображения QR-кода
+type QROptions struct {
+	Format string   // Format png или svg
+	Size   int      // Size сторона изображения в пикселях
+	Level  qr.Level // Level уровень коррекции ошибок
+}
+
+// ParseQROptions разбирает параметры QR-кода из строк запроса.
+// Пустые значения заменяются на PNG, DefaultQRSize и уровень коррекции M.
+func ParseQROptions(format, size, level string) (QROptions, error) {
+	opts := QROptions{Format: QRFormatPNG, Size: DefaultQRSize, Level: qr.M}
+	switch format {
+	case "", QRFormatPNG:
+	case QRFormatSVG:
+		opts.Format = QRFormatSVG
+	default:
+		return opts, fmt.Errorf("%w: unknown format %q", ErrQR, format)
+	}
+	if size != "" {
+		n, err := strconv.Atoi(size)
+		if err != nil || n < MinQRSize || n > MaxQRSize {
+			return opts, fmt.Errorf("%w: size must be between %d and %d", ErrQR, MinQRSize, MaxQRSize)
+		}
+		opts.Size = n
+	}
+	if level != "" {
+		l, err := qr.ParseLevel(level)
+		if err != nil {
+			return opts, fmt.Errorf("%w: %s", ErrQR, err)
+		}
+		opts.Level = l
+	}
+	return opts, nil
+}
+
+// QRCode рисует QR-код с короткой ссылкой id и возвращает изображение и его тип.
+// Для удаленных, просроченных и несуществующих ссылок возвращает ошибки хранилища,
+// переходы по ссылке при этом не списываются.
+func (i *Instance) QRCode(ctx context.Context, id string, opts QROptions) (image []byte, contentType string, err error) {
+	id = i.LinkID(ctx, id)
+	if _, err := i.Store.LoadLink(ctx, id); err != nil {
+		return nil, "", err
+	}
+
+	code, err := qr.Encode([]byte(i.shortURL(id)), opts.Level)
+	if err != nil {
+		return nil, "", fmt.Errorf("cannot encode QR code: %w", err)
+	}
+	if opts.Format == QRFormatSVG {
+		return code.SVG(opts.Size), "image/svg+xml", nil
+	}
+	image, err = code.PNG(opts.Size)
+	if err != nil {
+		return nil, "", err
+	}
+	return image, "image/png", nil
+}
@@ -0,0 +1,70 @@
+package app
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/qr"
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/store"
+)
+
+func TestParseQROptions(t *testing.T) {
+	tests := []struct {
+		name                string
+		format, size, level string
+		want                QROptions
+		wantErr             bool
+	}{
+		{name: "defaults", want: QROptions{Format: QRFormatPNG, Size: DefaultQRSize, Level: qr.M}},
+		{name: "svg", format: "svg", size: "512", level: "h", want: QROptions{Format: QRFormatSVG, Size: 512, Level: qr.H}},
+		{name: "unknown format", format: "gif", wantErr: true},
+		{name: "too small", size: "16", wantErr: true},
+		{name: "too large", size: "4096", wantErr: true},
+		{name: "bad size", size: "big", wantErr: true},
+		{name: "bad level", level: "X", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseQROptions(tt.format, tt.size, tt.level)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrQR)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestInstance_QRCode(t *testing.T) {
+	uid := uuid.Must(uuid.NewV4())
+	instance := &Instance{
+		BaseURL: "http://localhost:8080",
+		Store:   store.NewInMemory(),
+	}
+	u, _ := url.Parse("https://praktikum.yandex.ru/")
+	id, err := instance.Store.SaveUser(context.Background(), uid, u)
+	require.NoError(t, err)
+
+	image, contentType, err := instance.QRCode(context.Background(), id, QROptions{Format: QRFormatPNG, Size: 128, Level: qr.L})
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", contentType)
+	assert.Equal(t, []byte("\x89PNG"), image[:4])
+
+	image, contentType, err = instance.QRCode(context.Background(), id, QROptions{Format: QRFormatSVG, Size: 128, Level: qr.L})
+	require.NoError(t, err)
+	assert.Equal(t, "image/svg+xml", contentType)
+	assert.Contains(t, string(image), "<svg")
+
+	_, _, err = instance.QRCode(context.Background(), "missing", QROptions{Format: QRFormatPNG, Size: 128})
+	assert.ErrorIs(t, err, store.ErrNotFound)
+
+	require.NoError(t, instance.Store.DeleteUsers(context.Background(), uid, id))
+	_, _, err = instance.QRCode(context.Background(), id, QROptions{Format: QRFormatPNG, Size: 128})
+	assert.ErrorIs(t, err, store.ErrDeleted)
+}
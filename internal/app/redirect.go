@@ -0,0 +1,28 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/store"
+)
+
+// redirectModes способы перехода, которые можно задать для ссылки
+var redirectModes = map[store.RedirectMode]bool{
+	store.RedirectMovedPermanently: true,
+	store.RedirectFound:            true,
+	store.RedirectTemporary:        true,
+	store.RedirectPermanent:        true,
+	store.RedirectInterstitial:     true,
+	store.RedirectMetaRefresh:      true,
+}
+
+// ParseRedirect проверяет способ перехода по ссылке. Пустая строка означает способ по умолчанию.
+func ParseRedirect(mode string) (store.RedirectMode, error) {
+	if mode == "" {
+		return "", nil
+	}
+	if !redirectModes[store.RedirectMode(mode)] {
+		return "", fmt.Errorf("%w: unknown mode %q", ErrRedirect, mode)
+	}
+	return store.RedirectMode(mode), nil
+}
@@ -0,0 +1,57 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/store"
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/models"
+)
+
+func TestParseRedirect(t *testing.T) {
+	for _, mode := range []string{"", "301", "302", "307", "308", "interstitial", "meta-refresh"} {
+		got, err := ParseRedirect(mode)
+		require.NoError(t, err, mode)
+		assert.Equal(t, store.RedirectMode(mode), got)
+	}
+	for _, mode := range []string{"303", "200", "Interstitial", "refresh"} {
+		_, err := ParseRedirect(mode)
+		assert.ErrorIs(t, err, ErrRedirect, mode)
+	}
+}
+
+func TestInstance_ShortenLink_Redirect(t *testing.T) {
+	ctx := context.Background()
+	instance := &Instance{
+		BaseURL: "http://localhost:8080",
+		Store:   store.NewInMemory(),
+	}
+	shorten := func(mode string) (string, error) {
+		shortURL, err := instance.ShortenLink(ctx, models.ShortenRequest{
+			URL:         "https://practicum.yandex.ru/",
+			LinkOptions: models.LinkOptions{Redirect: mode},
+		})
+		return strings.TrimPrefix(shortURL, instance.BaseURL+"/"), err
+	}
+
+	plain, err := shorten("")
+	require.NoError(t, err)
+	link, err := instance.Expand(ctx, plain, "")
+	require.NoError(t, err)
+	assert.Equal(t, store.RedirectTemporary, link.Redirect)
+
+	// a link with its own mode is not shared with the plain one
+	permanent, err := shorten("301")
+	require.NoError(t, err)
+	assert.NotEqual(t, plain, permanent)
+	link, err = instance.Expand(ctx, permanent, "")
+	require.NoError(t, err)
+	assert.Equal(t, store.RedirectMovedPermanently, link.Redirect)
+
+	_, err = shorten("303")
+	assert.ErrorIs(t, err, ErrRedirect)
+}
@@ -0,0 +1,380 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http/httpguts"
+
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/store"
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/models"
+)
+
+// MaxRules наибольшее число правил перехода у ссылки
+const MaxRules = 20
+
+// Классы устройств в правилах перехода
+const (
+	DeviceIOS     = "ios"     // DeviceIOS iPhone, iPad и iPod
+	DeviceAndroid = "android" // DeviceAndroid телефоны и планшеты на Android
+	DeviceMobile  = "mobile"  // DeviceMobile любое мобильное устройство, включая ios и android
+	DeviceDesktop = "desktop" // DeviceDesktop все остальные клиенты с заголовком User-Agent
+)
+
+// weekdays дни недели в окнах времени правил
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// languageTag языковая метка вида en, pt-BR или zh-Hant-TW
+var languageTag = regexp.MustCompile(`^[A-Za-z]{1,8}(-[A-Za-z0-9]{1,8})*$`)
+
+// headerKey ключ контекста с заголовками запроса
+type headerKey struct{}
+
+// WithHeader возвращает контекст с заголовками запроса. По ним выбирается правило перехода по ссылке.
+func WithHeader(ctx context.Context, h http.Header) context.Context {
+	return context.WithValue(ctx, headerKey{}, h)
+}
+
+// requestHeader заголовки запроса из контекста, пустые, если их нет
+func requestHeader(ctx context.Context) http.Header {
+	h, _ := ctx.Value(headerKey{}).(http.Header)
+	if h == nil {
+		return http.Header{}
+	}
+	return h
+}
+
+// newRules проверяет правила перехода и приводит их к единому виду. Адрес каждого правила
+// проверяется так же, как адрес самой ссылки. Для пустого списка возвращается nil.
+func (i *Instance) newRules(ctx context.Context, rules []models.Rule) ([]store.Rule, error) {
+	if len(rules) > MaxRules {
+		return nil, fmt.Errorf("%w: more than %d rules", ErrRules, MaxRules)
+	}
+	var res []store.Rule
+	for n, r := range rules {
+		rule, err := newRule(r)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %w", n+1, err)
+		}
+		u, err := i.targetURL(ctx, r.URL)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %w", n+1, err)
+		}
+		rule.URL = u.String()
+		res = append(res, rule)
+	}
+	return res, nil
+}
+
+// newRule проверяет условия правила, адрес проверяется отдельно
+func newRule(r models.Rule) (store.Rule, error) {
+	var rule store.Rule
+	if len(r.Devices) == 0 && len(r.Languages) == 0 && len(r.Headers) == 0 && r.Time == nil {
+		return rule, fmt.Errorf("%w: rule must have at least one condition", ErrRules)
+	}
+	for _, d := range r.Devices {
+		d = strings.ToLower(strings.TrimSpace(d))
+		switch d {
+		case DeviceIOS, DeviceAndroid, DeviceMobile, DeviceDesktop:
+			rule.Devices = append(rule.Devices, d)
+		default:
+			return rule, fmt.Errorf("%w: unknown device %q", ErrRules, d)
+		}
+	}
+	for _, l := range r.Languages {
+		l = strings.TrimSpace(l)
+		if !languageTag.MatchString(l) {
+			return rule, fmt.Errorf("%w: invalid language %q", ErrRules, l)
+		}
+		rule.Languages = append(rule.Languages, l)
+	}
+	for _, h := range r.Headers {
+		if !httpguts.ValidHeaderFieldName(h.Name) {
+			return rule, fmt.Errorf("%w: invalid header name %q", ErrRules, h.Name)
+		}
+		value := strings.TrimSpace(h.Value)
+		if !httpguts.ValidHeaderFieldValue(value) {
+			return rule, fmt.Errorf("%w: invalid value of header %s", ErrRules, h.Name)
+		}
+		rule.Headers = append(rule.Headers, store.HeaderMatch{Name: http.CanonicalHeaderKey(h.Name), Value: value})
+	}
+	if r.Time != nil {
+		w, err := newTimeWindow(*r.Time)
+		if err != nil {
+			return rule, err
+		}
+		rule.Time = &w
+	}
+	return rule, nil
+}
+
+// newTimeWindow проверяет окно времени правила
+func newTimeWindow(t models.RuleTime) (store.TimeWindow, error) {
+	w := store.TimeWindow{From: t.From, To: t.To, Zone: t.Zone}
+	for _, d := range t.Days {
+		d = strings.ToLower(strings.TrimSpace(d))
+		if _, ok := weekdays[d]; !ok {
+			return w, fmt.Errorf("%w: unknown day %q", ErrRules, d)
+		}
+		w.Days = append(w.Days, d)
+	}
+	from, err := parseClock(w.From, 0)
+	if err != nil {
+		return w, err
+	}
+	to, err := parseClock(w.To, 24*60)
+	if err != nil {
+		return w, err
+	}
+	if from == to {
+		return w, fmt.Errorf("%w: time window is empty", ErrRules)
+	}
+	if w.Zone != "" {
+		if _, err := loadZone(w.Zone); err != nil {
+			return w, fmt.Errorf("%w: unknown time zone %q", ErrRules, w.Zone)
+		}
+	}
+	return w, nil
+}
+
+// parseClock разбирает время суток HH:MM в минуты от полуночи, пустое время дает def
+func parseClock(s string, def int) (int, error) {
+	if s == "" {
+		return def, nil
+	}
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("%w: time %q must be HH:MM", ErrRules, s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// zones часовые пояса окон времени по названиям, чтобы не читать базу поясов на каждом переходе
+var zones sync.Map
+
+// loadZone возвращает часовой пояс по названию из базы IANA
+func loadZone(name string) (*time.Location, error) {
+	if loc, ok := zones.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	zones.Store(name, loc)
+	return loc, nil
+}
+
+// Rules переводит сохраненные правила перехода в вид для ответа
+func Rules(rules []store.Rule) []models.Rule {
+	var res []models.Rule
+	for _, r := range rules {
+		rule := models.Rule{Devices: r.Devices, Languages: r.Languages, URL: r.URL}
+		for _, h := range r.Headers {
+			rule.Headers = append(rule.Headers, models.RuleHeader{Name: h.Name, Value: h.Value})
+		}
+		if r.Time != nil {
+			rule.Time = &models.RuleTime{Days: r.Time.Days, From: r.Time.From, To: r.Time.To, Zone: r.Time.Zone}
+		}
+		res = append(res, rule)
+	}
+	return res
+}
+
+// ruleURL возвращает адрес первого из правил, подходящего запросу с заголовками h в момент now,
+// или nil, если не подошло ни одно
+func ruleURL(rules []store.Rule, h http.Header, now time.Time) (*url.URL, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	device := DeviceClass(h.Get("User-Agent"))
+	var offered []string
+	for _, r := range rules {
+		offered = append(offered, r.Languages...)
+	}
+	lang := preferredLanguage(h.Values("Accept-Language"), offered)
+	for _, r := range rules {
+		if matchDevice(r.Devices, device) && matchLanguage(r.Languages, lang) && matchHeaders(r.Headers, h) &&
+			(r.Time == nil || inWindow(*r.Time, now)) {
+			return url.Parse(r.URL)
+		}
+	}
+	return nil, nil
+}
+
+// DeviceClass определяет класс устройства по заголовку User-Agent: DeviceIOS, DeviceAndroid, DeviceMobile
+// для прочих мобильных клиентов или DeviceDesktop. Для пустого заголовка класс пустой.
+func DeviceClass(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	switch {
+	case ua == "":
+		return ""
+	case strings.Contains(ua, "iphone"), strings.Contains(ua, "ipad"), strings.Contains(ua, "ipod"):
+		return DeviceIOS
+	case strings.Contains(ua, "android"):
+		return DeviceAndroid
+	case strings.Contains(ua, "mobile"):
+		return DeviceMobile
+	default:
+		return DeviceDesktop
+	}
+}
+
+func matchDevice(devices []string, device string) bool {
+	if len(devices) == 0 {
+		return true
+	}
+	for _, d := range devices {
+		if d == device || d == DeviceMobile && (device == DeviceIOS || device == DeviceAndroid) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchLanguage(languages []string, lang string) bool {
+	if len(languages) == 0 {
+		return true
+	}
+	for _, l := range languages {
+		if languageMatch(lang, l) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchHeaders(headers []store.HeaderMatch, h http.Header) bool {
+	for _, m := range headers {
+		values := h.Values(m.Name)
+		if len(values) == 0 {
+			return false
+		}
+		if m.Value == "" {
+			continue
+		}
+		var found bool
+		for _, v := range values {
+			if strings.EqualFold(strings.TrimSpace(v), m.Value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// preferredLanguage выбирает из Accept-Language самый предпочтительный для клиента язык,
+// под который подходит хотя бы один из offered. Пустая строка, если такого нет.
+func preferredLanguage(acceptLanguage []string, offered []string) string {
+	if len(offered) == 0 {
+		return ""
+	}
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var ranges []weighted
+	for _, header := range acceptLanguage {
+		for _, part := range strings.Split(header, ",") {
+			fields := strings.Split(part, ";")
+			tag := strings.TrimSpace(fields[0])
+			if tag == "" || tag == "*" {
+				continue
+			}
+			q := 1.0
+			for _, param := range fields[1:] {
+				param = strings.TrimSpace(param)
+				if strings.HasPrefix(param, "q=") {
+					if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+						q = v
+					}
+				}
+			}
+			if q > 0 {
+				ranges = append(ranges, weighted{tag, q})
+			}
+		}
+	}
+	sort.SliceStable(ranges, func(a, b int) bool { return ranges[a].q > ranges[b].q })
+	for _, r := range ranges {
+		for _, l := range offered {
+			if languageMatch(r.tag, l) {
+				return r.tag
+			}
+		}
+	}
+	return ""
+}
+
+// languageMatch сообщает, совпадают ли языки a и b с точностью до уточняющих частей:
+// en подходит под en-US и наоборот, но en-GB не подходит под en-US
+func languageMatch(a, b string) bool {
+	if a == "" || b == "" {
+		return false
+	}
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	return strings.EqualFold(a, b) || len(b) > len(a) && strings.EqualFold(a, b[:len(a)]) && b[len(a)] == '-'
+}
+
+// inWindow сообщает, попадает ли момент now в окно времени w
+func inWindow(w store.TimeWindow, now time.Time) bool {
+	if w.Zone != "" {
+		loc, err := loadZone(w.Zone)
+		if err != nil {
+			return false
+		}
+		now = now.In(loc)
+	} else {
+		now = now.UTC()
+	}
+	from, err := parseClock(w.From, 0)
+	if err != nil {
+		return false
+	}
+	to, err := parseClock(w.To, 24*60)
+	if err != nil {
+		return false
+	}
+	minute := now.Hour()*60 + now.Minute()
+	day := now.Weekday()
+	switch {
+	case from < to:
+		return from <= minute && minute < to && onDay(w.Days, day)
+	case minute >= from:
+		return onDay(w.Days, day)
+	case minute < to:
+		// the window started the day before
+		return onDay(w.Days, (day+6)%7)
+	default:
+		return false
+	}
+}
+
+func onDay(days []string, day time.Weekday) bool {
+	if len(days) == 0 {
+		return true
+	}
+	for _, d := range days {
+		if weekdays[d] == day {
+			return true
+		}
+	}
+	return false
+}
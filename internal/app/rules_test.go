@@ -0,0 +1,227 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/auth"
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/store"
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/urlpolicy"
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/models"
+)
+
+const (
+	iPhoneUA  = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148"
+	androidUA = "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 Chrome/120.0 Mobile Safari/537.36"
+	desktopUA = "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 Chrome/120.0 Safari/537.36"
+)
+
+func TestDeviceClass(t *testing.T) {
+	assert.Equal(t, DeviceIOS, DeviceClass(iPhoneUA))
+	assert.Equal(t, DeviceIOS, DeviceClass("Mozilla/5.0 (iPad; CPU OS 16_0 like Mac OS X)"))
+	assert.Equal(t, DeviceAndroid, DeviceClass(androidUA))
+	assert.Equal(t, DeviceMobile, DeviceClass("Mozilla/5.0 (Mobile; rv:48.0) Gecko/48.0 Firefox/48.0 KAIOS/2.5"))
+	assert.Equal(t, DeviceDesktop, DeviceClass(desktopUA))
+	assert.Empty(t, DeviceClass(""))
+}
+
+func TestPreferredLanguage(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		offered []string
+		want    string
+	}{
+		{name: "first match", header: "fr-CH, fr;q=0.9, en;q=0.8", offered: []string{"en", "fr"}, want: "fr-CH"},
+		{name: "skips unknown", header: "de, en-US;q=0.5", offered: []string{"en"}, want: "en-US"},
+		{name: "by weight", header: "en;q=0.3, ru;q=0.7", offered: []string{"en", "ru"}, want: "ru"},
+		{name: "wider range", header: "pt", offered: []string{"pt-BR"}, want: "pt"},
+		{name: "other region", header: "en-GB", offered: []string{"en-US"}},
+		{name: "refused", header: "ru;q=0, en;q=0.1", offered: []string{"ru"}},
+		{name: "wildcard", header: "*", offered: []string{"en"}},
+		{name: "no header", offered: []string{"en"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, preferredLanguage([]string{tt.header}, tt.offered))
+		})
+	}
+}
+
+func TestInWindow(t *testing.T) {
+	// 2024-01-05 is a friday
+	at := func(clock string) time.Time {
+		at, err := time.Parse("2006-01-02 15:04", "2024-01-05 "+clock)
+		require.NoError(t, err)
+		return at
+	}
+	business := store.TimeWindow{Days: []string{"mon", "tue", "wed", "thu", "fri"}, From: "09:00", To: "18:00"}
+	night := store.TimeWindow{Days: []string{"fri"}, From: "22:00", To: "06:00"}
+
+	assert.True(t, inWindow(business, at("09:00")))
+	assert.True(t, inWindow(business, at("17:59")))
+	assert.False(t, inWindow(business, at("18:00")))
+	assert.False(t, inWindow(business, at("08:59")))
+	assert.False(t, inWindow(business, at("12:00").AddDate(0, 0, 1)), "saturday")
+
+	assert.True(t, inWindow(night, at("23:00")))
+	assert.True(t, inWindow(night, at("05:00").AddDate(0, 0, 1)), "friday night goes on into saturday")
+	assert.False(t, inWindow(night, at("05:00")), "thursday night")
+	assert.False(t, inWindow(night, at("12:00")))
+
+	// 18:30 UTC is 21:30 in Moscow
+	assert.True(t, inWindow(store.TimeWindow{From: "21:00", Zone: "Europe/Moscow"}, at("18:30")))
+	assert.False(t, inWindow(store.TimeWindow{From: "21:00"}, at("18:30")))
+}
+
+func TestRuleURL(t *testing.T) {
+	rules := []store.Rule{
+		{Devices: []string{DeviceIOS}, URL: "https://apps.apple.com/app/id1"},
+		{Devices: []string{DeviceAndroid}, URL: "https://play.google.com/store/apps/details?id=app"},
+		{Headers: []store.HeaderMatch{{Name: "X-Beta", Value: "on"}}, URL: "https://beta.example/"},
+		{Languages: []string{"ru"}, URL: "https://example.ru/"},
+		{Time: &store.TimeWindow{From: "18:00", To: "09:00"}, URL: "https://example.com/closed"},
+	}
+	day := time.Date(2024, 1, 5, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		header http.Header
+		now    time.Time
+		want   string
+	}{
+		{name: "ios", header: http.Header{"User-Agent": {iPhoneUA}, "Accept-Language": {"ru"}}, want: "https://apps.apple.com/app/id1"},
+		{name: "android", header: http.Header{"User-Agent": {androidUA}}, want: "https://play.google.com/store/apps/details?id=app"},
+		{name: "header", header: http.Header{"User-Agent": {desktopUA}, "X-Beta": {"ON"}}, want: "https://beta.example/"},
+		{name: "header value", header: http.Header{"User-Agent": {desktopUA}, "X-Beta": {"off"}}},
+		{name: "language", header: http.Header{"User-Agent": {desktopUA}, "Accept-Language": {"ru-RU,en;q=0.5"}}, want: "https://example.ru/"},
+		{name: "other language", header: http.Header{"User-Agent": {desktopUA}, "Accept-Language": {"en-US"}}},
+		{name: "after hours", header: http.Header{"User-Agent": {desktopUA}}, now: day.Add(8 * time.Hour), want: "https://example.com/closed"},
+		{name: "default", header: http.Header{"User-Agent": {desktopUA}}},
+		{name: "no headers", header: http.Header{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := tt.now
+			if now.IsZero() {
+				now = day
+			}
+			got, err := ruleURL(rules, tt.header, now)
+			require.NoError(t, err)
+			if tt.want == "" {
+				assert.Nil(t, got)
+				return
+			}
+			require.NotNil(t, got)
+			assert.Equal(t, tt.want, got.String())
+		})
+	}
+
+	got, err := ruleURL([]store.Rule{{Devices: []string{DeviceMobile}, URL: "https://m.example/"}},
+		http.Header{"User-Agent": {androidUA}}, day)
+	require.NoError(t, err)
+	assert.Equal(t, "https://m.example/", got.String(), "mobile takes in android")
+}
+
+func TestInstance_newRules(t *testing.T) {
+	instance := &Instance{BaseURL: "http://localhost:8080", Store: store.NewInMemory()}
+	ctx := context.Background()
+
+	rules, err := instance.newRules(ctx, []models.Rule{{
+		Devices:   []string{" iOS "},
+		Languages: []string{"pt-BR"},
+		Headers:   []models.RuleHeader{{Name: "x-beta", Value: " on "}},
+		Time:      &models.RuleTime{Days: []string{"Mon"}, From: "09:00", To: "18:00", Zone: "Europe/Moscow"},
+		URL:       "https://example.com/app",
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, []store.Rule{{
+		Devices:   []string{DeviceIOS},
+		Languages: []string{"pt-BR"},
+		Headers:   []store.HeaderMatch{{Name: "X-Beta", Value: "on"}},
+		Time:      &store.TimeWindow{Days: []string{"mon"}, From: "09:00", To: "18:00", Zone: "Europe/Moscow"},
+		URL:       "https://example.com/app",
+	}}, rules)
+
+	rules, err = instance.newRules(ctx, nil)
+	require.NoError(t, err)
+	assert.Nil(t, rules)
+
+	invalid := []models.Rule{
+		{URL: "https://example.com/"},
+		{Devices: []string{"tv"}, URL: "https://example.com/"},
+		{Languages: []string{"en_US"}, URL: "https://example.com/"},
+		{Headers: []models.RuleHeader{{Name: "X Beta"}}, URL: "https://example.com/"},
+		{Time: &models.RuleTime{Days: []string{"someday"}}, URL: "https://example.com/"},
+		{Time: &models.RuleTime{From: "9am"}, URL: "https://example.com/"},
+		{Time: &models.RuleTime{From: "10:00", To: "10:00"}, URL: "https://example.com/"},
+		{Time: &models.RuleTime{Zone: "Mars/Olympus"}, URL: "https://example.com/"},
+	}
+	for _, r := range invalid {
+		_, err := instance.newRules(ctx, []models.Rule{r})
+		assert.ErrorIs(t, err, ErrRules, "%+v", r)
+	}
+	var perr *urlpolicy.Error
+	_, err = instance.newRules(ctx, []models.Rule{{Devices: []string{"ios"}, URL: "not a url"}})
+	assert.ErrorAs(t, err, &perr)
+	_, err = instance.newRules(ctx, make([]models.Rule, MaxRules+1))
+	assert.ErrorIs(t, err, ErrRules)
+}
+
+func TestInstance_Rules(t *testing.T) {
+	uid := uuid.Must(uuid.NewV4())
+	ctx := auth.Context(context.Background(), uid)
+	instance := &Instance{BaseURL: "http://localhost:8080", Store: store.NewInMemory()}
+	rules := []models.Rule{{Devices: []string{"ios"}, URL: "https://apps.apple.com/app/id1"}}
+	ios := WithHeader(ctx, http.Header{"User-Agent": {iPhoneUA}})
+
+	shortURL, err := instance.ShortenLink(ctx, models.ShortenRequest{
+		URL:         "https://example.com/",
+		LinkOptions: models.LinkOptions{Rules: rules, Passthrough: "query"},
+	})
+	require.NoError(t, err)
+	id := strings.TrimPrefix(shortURL, instance.BaseURL+"/")
+
+	link, err := instance.ExpandTail(ios, id, "", Tail{RawQuery: "ref=ad"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://apps.apple.com/app/id1?ref=ad", link.URL.String(), "passthrough applies to the rule target")
+	link, err = instance.Expand(ctx, id, "")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/", link.URL.String())
+
+	links, _, err := instance.LoadUsers(ctx, store.LinkFilter{})
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	assert.Equal(t, rules, links[0].Rules)
+
+	// the owner replaces and removes the rules
+	android := []models.Rule{{Devices: []string{"android"}, URL: "https://play.google.com/"}}
+	resp, err := instance.UpdateURL(ctx, id, models.UpdateURLRequest{Rules: &android})
+	require.NoError(t, err)
+	assert.Equal(t, android, resp.Rules)
+	assert.Equal(t, "https://example.com/", resp.OriginalURL)
+	link, err = instance.Expand(ios, id, "")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/", link.URL.String())
+
+	resp, err = instance.UpdateURL(ctx, id, models.UpdateURLRequest{Rules: &[]models.Rule{}})
+	require.NoError(t, err)
+	assert.Empty(t, resp.Rules)
+	stored, err := instance.Store.LoadLink(ctx, id)
+	require.NoError(t, err)
+	assert.Empty(t, stored.Rules)
+
+	_, err = instance.UpdateURL(ctx, id, models.UpdateURLRequest{Rules: &[]models.Rule{{URL: "https://example.com/"}}})
+	assert.ErrorIs(t, err, ErrRules)
+	_, err = instance.ShortenLink(ctx, models.ShortenRequest{
+		URL:         "https://tracker.example/{1}",
+		LinkOptions: models.LinkOptions{Rules: rules, Template: true},
+	})
+	assert.ErrorIs(t, err, ErrRules)
+}
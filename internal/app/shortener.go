@@ -8,18 +8,36 @@ import (
 	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/models"
 	"net"
 	"net/url"
+	"time"
 
 	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/auth"
 	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/store"
 )
 
-// Shorten обработчик сокращения ссылки
+// Shorten обработчик сокращения ссылки. Ссылка создается на домене запроса, на дополнительных доменах
+// каждая ссылка создается заново, а не ищется среди уже сохраненных.
 func (i *Instance) Shorten(ctx context.Context, rawURL string) (shortURL string, err error) {
+	domain, err := i.linkDomain(ctx, "")
+	if err != nil {
+		return "", err
+	}
+	return i.shorten(ctx, domain, rawURL)
+}
+
+// shorten сокращает ссылку без параметров на домене domain
+func (i *Instance) shorten(ctx context.Context, domain, rawURL string) (shortURL string, err error) {
 	uid := auth.UIDFromContext(ctx)
 
-	u, err := url.Parse(rawURL)
+	u, err := i.targetURL(ctx, rawURL)
 	if err != nil {
-		return "", ErrParseURL
+		return "", err
+	}
+	if domain != "" {
+		id, err := i.Store.SaveLink(ctx, store.Link{Domain: domain, URL: u, UserID: uid})
+		if err != nil {
+			return "", fmt.Errorf("cannot save URL to storage: %w", err)
+		}
+		return i.shortURL(id), nil
 	}
 	var id string
 	if uid != nil {
@@ -31,7 +49,92 @@ func (i *Instance) Shorten(ctx context.Context, rawURL string) (shortURL string,
 	if err != nil && !errors.Is(err, store.ErrConflict) {
 		return "", fmt.Errorf("cannot save URL to storage: %w", err)
 	}
-	return fmt.Sprintf("%s/%s", i.BaseURL, id), err
+	return i.shortURL(id), err
+}
+
+// ShortenLink обработчик сокращения ссылки с параметрами из запроса.
+// Ссылка с пользовательским идентификатором или другими параметрами сохраняется
+// отдельно от уже существующих ссылок на тот же адрес.
+func (i *Instance) ShortenLink(ctx context.Context, req models.ShortenRequest) (shortURL string, err error) {
+	link, custom, err := i.newLink(ctx, req.LinkOptions, time.Now())
+	if err != nil {
+		return "", err
+	}
+	if req.Alias == "" && !custom {
+		return i.shorten(ctx, link.Domain, req.URL)
+	}
+	if req.Alias != "" {
+		if err := ValidateAlias(req.Alias); err != nil {
+			return "", err
+		}
+	}
+
+	link.ID = req.Alias
+	link.URL, err = i.targetURL(ctx, req.URL)
+	if err != nil {
+		return "", err
+	}
+	if link.Template {
+		if err := ValidateTemplate(link.URL); err != nil {
+			return "", err
+		}
+	}
+
+	id, err := i.Store.SaveLink(ctx, link)
+	if err != nil {
+		return "", fmt.Errorf("cannot save URL to storage: %w", err)
+	}
+	return i.shortURL(id), nil
+}
+
+// newLink собирает ссылку текущего пользователя из параметров запроса.
+// custom сообщает, что параметры заданы или ссылка создается на дополнительном домене
+// и ее нужно сохранить через SaveLink.
+func (i *Instance) newLink(ctx context.Context, opts models.LinkOptions, now time.Time) (link store.Link, custom bool, err error) {
+	if link.Domain, err = i.linkDomain(ctx, opts.Domain); err != nil {
+		return link, false, err
+	}
+	if link.ExpiresAt, err = ExpiresAt(opts, now); err != nil {
+		return link, false, err
+	}
+	if link.PasswordHash, err = HashPassword(opts.Password); err != nil {
+		return link, false, err
+	}
+	if opts.MaxClicks < 0 {
+		return link, false, fmt.Errorf("%w: max_clicks must be positive", ErrMaxClicks)
+	}
+	link.ClicksLeft = opts.MaxClicks
+	if link.Redirect, err = ParseRedirect(opts.Redirect); err != nil {
+		return link, false, err
+	}
+	if link.Passthrough, err = ParsePassthrough(opts.Passthrough); err != nil {
+		return link, false, err
+	}
+	// the template itself is checked with ValidateTemplate once the address is parsed
+	link.Template = opts.Template
+	if link.Template && link.Passthrough != "" {
+		return link, false, fmt.Errorf("%w: cannot be combined with passthrough", ErrTemplate)
+	}
+	if link.Meta, err = NewMeta(opts.Title, opts.Notes, opts.Tags); err != nil {
+		return link, false, err
+	}
+	if len(opts.Rules) > 0 && link.Template {
+		return link, false, fmt.Errorf("%w: cannot be combined with template", ErrRules)
+	}
+	if link.Rules, err = i.newRules(ctx, opts.Rules); err != nil {
+		return link, false, err
+	}
+	if len(opts.Variants) > 0 && link.Template {
+		return link, false, fmt.Errorf("%w: cannot be combined with template", ErrVariants)
+	}
+	if link.Variants, err = i.newVariants(ctx, opts.Variants); err != nil {
+		return link, false, err
+	}
+	link.UserID = auth.UIDFromContext(ctx)
+	custom = link.ExpiresAt != nil || link.PasswordHash != "" || link.ClicksLeft > 0 ||
+		link.Title != "" || link.Notes != "" || len(link.Tags) > 0 || link.Domain != "" || link.Redirect != "" ||
+		link.Passthrough != "" || link.Template || len(link.Rules) > 0 || len(link.Variants) > 0
+	return link, custom, nil
 }
 
 // ShortenBatch пакетный обработчик сокращения ссылок
@@ -50,7 +153,7 @@ func (i *Instance) ShortenBatch(ctx context.Context, rawURLs []*url.URL) (shortU
 	}
 
 	for _, id := range ids {
-		shortURLs = append(shortURLs, fmt.Sprintf("%s/%s", i.BaseURL, id))
+		shortURLs = append(shortURLs, i.shortURL(id))
 	}
 
 	return shortURLs, nil
@@ -73,31 +176,116 @@ func (i *Instance) Statistics(ctx context.Context, ip string) (models.Statistics
 
 // LoadURL поиск ссылки в хранилище
 func (i *Instance) LoadURL(ctx context.Context, id string) (*url.URL, error) {
-	u, err := i.Store.Load(ctx, id)
+	u, err := i.Store.Load(ctx, i.LinkID(ctx, id))
 	if err != nil {
 		return &url.URL{}, err
 	}
 	return u, nil
 }
 
-// LoadUsers загрузка ссылок пользователя
-func (i *Instance) LoadUsers(ctx context.Context) ([]models.URLResponse, error) {
+// Expand возвращает ссылку с кодом id на домене запроса для перехода по адресу link.URL, проверяя пароль,
+// если ссылка им защищена.
+// У ссылки с ограниченным числом переходов списывается один переход.
+// Для защищенной ссылки без пароля возвращается ErrPasswordRequired, при неверном пароле ErrWrongPassword,
+// а после исчерпания попыток ErrTooManyAttempts. Ссылка на запрещенный адрес дает urlcheck.ErrBlocked.
+// Способ перехода link.Redirect всегда заполнен, для ссылки без заданного способа это store.RedirectTemporary.
+func (i *Instance) Expand(ctx context.Context, id, password string) (link *store.Link, err error) {
+	return i.ExpandTail(ctx, id, password, Tail{})
+}
+
+// ExpandTail работает как Expand и переносит продолжение запроса tail в адрес перехода по режиму ссылки,
+// а у ссылки-шаблона заполняет им подстановки. Если путь после кода ссылкой не принимается, возвращается
+// store.ErrNotFound, а если для шаблона не хватает значений - ErrTemplateValues. Переход при этом не списывается.
+// Если у ссылки есть правила перехода, адрес выбирается по заголовкам запроса из контекста (см. WithHeader).
+// Если ни одно правило не подошло, а у ссылки есть варианты A/B-теста, вариант выбирается по ключу посетителя
+// из контекста (см. WithVisitor) и его название возвращается в link.Variant.
+func (i *Instance) ExpandTail(ctx context.Context, id, password string, tail Tail) (link *store.Link, err error) {
+	id = i.LinkID(ctx, id)
+	link, err = i.Store.LoadLink(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	matched, err := ruleURL(link.Rules, requestHeader(ctx), time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse rule URL: %w", err)
+	}
+	if matched == nil && len(link.Variants) > 0 {
+		variant := pickVariant(link.Variants, id, visitor(ctx))
+		if matched, err = url.Parse(variant.URL); err != nil {
+			return nil, fmt.Errorf("cannot parse variant URL: %w", err)
+		}
+		link.Variant = variant.Name
+	}
+	if matched != nil {
+		link.URL = matched
+	}
+	// links created before their domain was blocked stop working as well
+	if err := i.blocked(ctx, link.URL); err != nil {
+		return nil, err
+	}
+	if _, err := expandedURL(link, tail); err != nil {
+		return nil, err
+	}
+	if err := i.checkPassword(link, password); err != nil {
+		return nil, err
+	}
+	if link.Redirect == "" {
+		link.Redirect = store.RedirectTemporary
+	}
+	if link.ClicksLeft != 0 {
+		// the store counts the expansion atomically and reports an exhausted link as deleted
+		u, err := i.Store.Load(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if matched == nil {
+			link.URL = u
+		}
+	}
+	if link.URL, err = expandedURL(link, tail); err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+// LoadUsers загрузка страницы ссылок пользователя, подходящих под filter, вместе с их описанием.
+// next курсор следующей страницы, пустой для последней. Если у пользователя нет ни одной ссылки,
+// возвращается store.ErrNotFound.
+func (i *Instance) LoadUsers(ctx context.Context, filter store.LinkFilter) (resp []models.URLResponse, next string, err error) {
 	uid := auth.UIDFromContext(ctx)
 	if uid == nil {
-		return []models.URLResponse{}, ErrAuth
+		return []models.URLResponse{}, "", ErrAuth
 	}
-	urls, err := i.Store.LoadUsers(ctx, *uid)
+	limit := filter.Limit
+	if limit > 0 {
+		// one extra link tells whether there is a next page
+		filter.Limit++
+	}
+	links, err := i.Store.LoadUserLinks(ctx, *uid, filter)
 	if err != nil {
-		return []models.URLResponse{}, err
+		return []models.URLResponse{}, "", err
 	}
-	var resp []models.URLResponse
-	for id, u := range urls {
-		resp = append(resp, models.URLResponse{
-			ShortURL:    i.BaseURL + "/" + id,
-			OriginalURL: u.String(),
-		})
+	if limit > 0 && len(links) > limit {
+		links = links[:limit]
+		next = encodeCursor(filter, filter.Cursor(&links[limit-1]))
+	}
+	resp = make([]models.URLResponse, 0, len(links))
+	for _, link := range links {
+		resp = append(resp, i.urlResponse(link.ID, link.URL, link.Meta, link.Rules))
+	}
+	return resp, next, nil
+}
+
+// urlResponse собирает описание короткой ссылки id для ответа
+func (i *Instance) urlResponse(id string, u *url.URL, meta store.Meta, rules []store.Rule) models.URLResponse {
+	return models.URLResponse{
+		ShortURL:    i.shortURL(id),
+		OriginalURL: u.String(),
+		Title:       meta.Title,
+		Notes:       meta.Notes,
+		Tags:        meta.Tags,
+		Rules:       Rules(rules),
 	}
-	return resp, nil
 }
 
 // Ping проверка работоспособности приложения
@@ -110,24 +298,56 @@ func (i *Instance) Ping(ctx context.Context) error {
 	return nil
 }
 
-// BatchShorten пакетное соркащение ссылок
+// BatchShorten пакетное соркащение ссылок.
+// Ссылки с параметрами сохраняются по одной, остальные одним пакетом.
 func (i *Instance) BatchShorten(req []models.BatchShortenRequest, ctx context.Context) ([]models.BatchShortenResponse, error) {
+	now := time.Now()
 	var urls []*url.URL
-	for _, pair := range req {
-		u, errs := url.Parse(pair.OriginalURL)
-		if errs != nil {
-			return []models.BatchShortenResponse{}, ErrParseURL
+	var plain, custom []int
+	var links []store.Link
+	for j, pair := range req {
+		u, err := i.targetURL(ctx, pair.OriginalURL)
+		if err != nil {
+			return []models.BatchShortenResponse{}, fmt.Errorf("url %s: %w", pair.CorrelationID, err)
+		}
+		link, ok, err := i.newLink(ctx, pair.LinkOptions, now)
+		if err != nil {
+			return []models.BatchShortenResponse{}, err
+		}
+		if ok {
+			if link.Template {
+				if err := ValidateTemplate(u); err != nil {
+					return []models.BatchShortenResponse{}, fmt.Errorf("url %s: %w", pair.CorrelationID, err)
+				}
+			}
+			link.URL = u
+			links = append(links, link)
+			custom = append(custom, j)
+			continue
 		}
 		urls = append(urls, u)
+		plain = append(plain, j)
 	}
 
-	shortURLs, err := i.ShortenBatch(ctx, urls)
-	if err != nil {
-		return []models.BatchShortenResponse{}, err
+	shortURLs := make([]string, len(req))
+	if len(urls) > 0 {
+		batch, err := i.ShortenBatch(ctx, urls)
+		if err != nil {
+			return []models.BatchShortenResponse{}, err
+		}
+		if len(batch) != len(urls) {
+			return []models.BatchShortenResponse{}, ErrURLLength
+		}
+		for k, j := range plain {
+			shortURLs[j] = batch[k]
+		}
 	}
-
-	if len(shortURLs) != len(req) {
-		return []models.BatchShortenResponse{}, ErrURLLength
+	for k, link := range links {
+		id, err := i.Store.SaveLink(ctx, link)
+		if err != nil {
+			return []models.BatchShortenResponse{}, fmt.Errorf("cannot save URL to storage: %w", err)
+		}
+		shortURLs[custom[k]] = i.shortURL(id)
 	}
 
 	res := make([]models.BatchShortenResponse, 0, len(shortURLs))
@@ -2,12 +2,19 @@ package app
 
 import (
 	"context"
+	"errors"
 	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/auth"
 	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/config"
 	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/store"
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/urlcheck"
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/urlcheck/urlchecktest"
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/urlpolicy"
 	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/models"
 	"github.com/gofrs/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -62,6 +69,210 @@ func TestInstance_Shorten(t *testing.T) {
 	}
 }
 
+func TestInstance_Shorten_Policy(t *testing.T) {
+	uid := uuid.Must(uuid.NewV4())
+	ctx := auth.Context(context.Background(), uid)
+	instance := &Instance{
+		BaseURL: "http://localhost:8080",
+		Store:   store.NewInMemory(),
+		Policy:  &urlpolicy.Policy{StripTracking: true},
+	}
+
+	// equivalent addresses are shortened once
+	first, err := instance.Shorten(ctx, "https://Practicum.Yandex.ru:443/?utm_source=mail")
+	require.NoError(t, err)
+	second, err := instance.Shorten(ctx, "https://practicum.yandex.ru/")
+	assert.ErrorIs(t, err, store.ErrConflict)
+	assert.Equal(t, first, second)
+	links, _, err := instance.LoadUsers(ctx, store.LinkFilter{})
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	assert.Equal(t, "https://practicum.yandex.ru/", links[0].OriginalURL)
+
+	_, err = instance.ShortenLink(ctx, models.ShortenRequest{URL: "ftp://files.example.com/", Alias: "files"})
+	var perr *urlpolicy.Error
+	require.True(t, errors.As(err, &perr))
+	assert.Equal(t, urlpolicy.FieldScheme, perr.Field)
+
+	_, err = instance.BatchShorten([]models.BatchShortenRequest{
+		{CorrelationID: "ok", OriginalURL: "https://ya.ru"},
+		{CorrelationID: "bad", OriginalURL: "http://"},
+	}, ctx)
+	require.True(t, errors.As(err, &perr))
+	assert.Equal(t, urlpolicy.FieldHost, perr.Field)
+	assert.Contains(t, err.Error(), "url bad")
+}
+
+func TestInstance_URLChecker(t *testing.T) {
+	uid := uuid.Must(uuid.NewV4())
+	ctx := auth.Context(context.Background(), uid)
+	checker := &urlchecktest.Fake{}
+	instance := &Instance{
+		BaseURL:    "http://localhost:8080",
+		Store:      store.NewInMemory(),
+		URLChecker: checker,
+	}
+
+	shortURL, err := instance.Shorten(ctx, "https://evil.example/login")
+	require.NoError(t, err)
+	id := strings.TrimPrefix(shortURL, instance.BaseURL+"/")
+	_, err = instance.Expand(ctx, id, "")
+	require.NoError(t, err)
+
+	// the domain turns out to be malicious after the link was created
+	checker.Block("evil.example")
+	_, err = instance.Expand(ctx, id, "")
+	assert.ErrorIs(t, err, urlcheck.ErrBlocked)
+
+	_, err = instance.Shorten(ctx, "https://evil.example/other")
+	assert.ErrorIs(t, err, urlcheck.ErrBlocked)
+	_, err = instance.ShortenLink(ctx, models.ShortenRequest{URL: "https://evil.example/", Alias: "evil"})
+	assert.ErrorIs(t, err, urlcheck.ErrBlocked)
+	_, err = instance.BatchShorten([]models.BatchShortenRequest{
+		{CorrelationID: "ok", OriginalURL: "https://ya.ru/"},
+		{CorrelationID: "bad", OriginalURL: "https://EVIL.example/"},
+	}, ctx)
+	assert.ErrorIs(t, err, urlcheck.ErrBlocked)
+	assert.Contains(t, err.Error(), "url bad")
+
+	safe, err := instance.Shorten(ctx, "https://practicum.yandex.ru/")
+	require.NoError(t, err)
+	safeID := strings.TrimPrefix(safe, instance.BaseURL+"/")
+	_, err = instance.UpdateURL(ctx, safeID, models.UpdateURLRequest{OriginalURL: "https://evil.example/"})
+	assert.ErrorIs(t, err, urlcheck.ErrBlocked)
+
+	// a failing checker rejects new links but keeps existing ones working
+	checker.Unblock("evil.example")
+	checker.Err = errors.New("checker is down")
+	_, err = instance.Shorten(ctx, "https://ya.ru/")
+	assert.ErrorIs(t, err, checker.Err)
+	assert.NotErrorIs(t, err, urlcheck.ErrBlocked)
+	_, err = instance.Expand(ctx, id, "")
+	assert.NoError(t, err)
+}
+
+func TestInstance_ShortenLink(t *testing.T) {
+	targetURL := "https://praktikum.yandex.ru/"
+	uid := uuid.Must(uuid.NewV4())
+	instance := &Instance{
+		BaseURL: "http://localhost:8080",
+		Store:   store.NewInMemory(),
+	}
+	ctx := auth.Context(context.Background(), uid)
+
+	shortURL, err := instance.Shorten(ctx, targetURL)
+	require.NoError(t, err)
+
+	t.Run("alias", func(t *testing.T) {
+		aliasURL, err := instance.ShortenLink(ctx, models.ShortenRequest{URL: targetURL, Alias: "spring-sale"})
+		require.NoError(t, err)
+		assert.Equal(t, "http://localhost:8080/spring-sale", aliasURL)
+		assert.NotEqual(t, shortURL, aliasURL)
+
+		u, err := instance.LoadURL(ctx, "spring-sale")
+		require.NoError(t, err)
+		assert.Equal(t, targetURL, u.String())
+
+		urls, _, err := instance.LoadUsers(ctx, store.LinkFilter{})
+		require.NoError(t, err)
+		assert.Contains(t, urls, models.URLResponse{ShortURL: aliasURL, OriginalURL: targetURL})
+	})
+	t.Run("alias taken", func(t *testing.T) {
+		_, err := instance.ShortenLink(ctx, models.ShortenRequest{URL: targetURL + "other", Alias: "spring-sale"})
+		assert.ErrorIs(t, err, store.ErrIDTaken)
+	})
+	t.Run("invalid alias", func(t *testing.T) {
+		_, err := instance.ShortenLink(ctx, models.ShortenRequest{URL: targetURL, Alias: "ping"})
+		assert.ErrorIs(t, err, ErrAlias)
+	})
+	t.Run("no alias", func(t *testing.T) {
+		got, err := instance.ShortenLink(ctx, models.ShortenRequest{URL: targetURL})
+		assert.ErrorIs(t, err, store.ErrConflict)
+		assert.Equal(t, shortURL, got)
+	})
+}
+
+func TestInstance_ShortenLink_Expiry(t *testing.T) {
+	targetURL := "https://praktikum.yandex.ru/"
+	instance := &Instance{
+		BaseURL: "http://localhost:8080",
+		Store:   store.NewInMemory(),
+	}
+	ctx := context.Background()
+
+	shortURL, err := instance.Shorten(ctx, targetURL)
+	require.NoError(t, err)
+
+	t.Run("ttl", func(t *testing.T) {
+		got, err := instance.ShortenLink(ctx, models.ShortenRequest{URL: targetURL, LinkOptions: models.LinkOptions{TTL: 60}})
+		require.NoError(t, err)
+		// a link with expiry is never deduplicated with a permanent one
+		assert.NotEqual(t, shortURL, got)
+
+		u, err := instance.LoadURL(ctx, strings.TrimPrefix(got, instance.BaseURL+"/"))
+		require.NoError(t, err)
+		assert.Equal(t, targetURL, u.String())
+	})
+	t.Run("invalid", func(t *testing.T) {
+		_, err := instance.ShortenLink(ctx, models.ShortenRequest{URL: targetURL, LinkOptions: models.LinkOptions{TTL: -1}})
+		assert.ErrorIs(t, err, ErrExpiry)
+	})
+	t.Run("batch keeps order", func(t *testing.T) {
+		res, err := instance.BatchShorten([]models.BatchShortenRequest{
+			{CorrelationID: "1", OriginalURL: targetURL + "1"},
+			{CorrelationID: "2", OriginalURL: targetURL + "2", LinkOptions: models.LinkOptions{TTL: 60}},
+			{CorrelationID: "3", OriginalURL: targetURL + "3"},
+		}, ctx)
+		require.NoError(t, err)
+		require.Len(t, res, 3)
+		for i, r := range res {
+			assert.Equal(t, strconv.Itoa(i+1), r.CorrelationID)
+			u, err := instance.LoadURL(ctx, strings.TrimPrefix(r.ShortURL, instance.BaseURL+"/"))
+			require.NoError(t, err)
+			assert.Equal(t, targetURL+r.CorrelationID, u.String())
+		}
+	})
+	t.Run("batch invalid", func(t *testing.T) {
+		_, err := instance.BatchShorten([]models.BatchShortenRequest{
+			{CorrelationID: "1", OriginalURL: targetURL, LinkOptions: models.LinkOptions{TTL: -1}},
+		}, ctx)
+		assert.ErrorIs(t, err, ErrExpiry)
+	})
+}
+
+func TestInstance_ShortenLink_MaxClicks(t *testing.T) {
+	ctx := context.Background()
+	instance := &Instance{
+		BaseURL: "http://localhost:8080",
+		Store:   store.NewInMemory(),
+	}
+
+	_, err := instance.ShortenLink(ctx, models.ShortenRequest{
+		URL:         "https://praktikum.yandex.ru/",
+		LinkOptions: models.LinkOptions{MaxClicks: -1},
+	})
+	assert.ErrorIs(t, err, ErrMaxClicks)
+
+	shortURL, err := instance.ShortenLink(ctx, models.ShortenRequest{
+		URL:         "https://praktikum.yandex.ru/",
+		LinkOptions: models.LinkOptions{MaxClicks: 2},
+	})
+	require.NoError(t, err)
+	id := strings.TrimPrefix(shortURL, instance.BaseURL+"/")
+
+	// QR codes do not use up the link
+	_, _, err = instance.QRCode(ctx, id, QROptions{Format: QRFormatSVG, Size: DefaultQRSize})
+	require.NoError(t, err)
+
+	for j := 0; j < 2; j++ {
+		link, err := instance.Expand(ctx, id, "")
+		require.NoError(t, err)
+		assert.Equal(t, "https://praktikum.yandex.ru/", link.URL.String())
+	}
+	_, err = instance.Expand(ctx, id, "")
+	assert.ErrorIs(t, err, store.ErrDeleted)
+}
+
 func TestInstance_BatchShorten(t *testing.T) {
 	targetURL := "https://praktikum.yandex.ru/"
 	uid := uuid.Must(uuid.NewV4())
@@ -97,7 +308,7 @@ func TestInstance_BatchShorten(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			ctx := auth.Context(context.Background(), tc.user)
 			shorten, err := instance.BatchShorten([]models.BatchShortenRequest{{CorrelationID: "1", OriginalURL: tc.url}}, ctx)
-			assert.Equal(t, tc.expectedError, err)
+			assert.ErrorIs(t, err, tc.expectedError)
 			assert.Equal(t, len(shorten) == 0, tc.emptyResponse)
 		})
 	}
@@ -192,7 +403,7 @@ func TestInstance_LoadUsers(t *testing.T) {
 
 	t.Run("not found", func(t *testing.T) {
 		ctx := auth.Context(context.Background(), uid)
-		shorten, err := instance.LoadUsers(ctx)
+		shorten, _, err := instance.LoadUsers(ctx, store.LinkFilter{})
 		assert.Error(t, err)
 		assert.Empty(t, shorten)
 	})
@@ -200,8 +411,55 @@ func TestInstance_LoadUsers(t *testing.T) {
 		ctx := auth.Context(context.Background(), uid)
 		_, err := instance.Shorten(ctx, targetURL)
 		assert.NoError(t, err)
-		shorten, err := instance.LoadUsers(ctx)
+		shorten, _, err := instance.LoadUsers(ctx, store.LinkFilter{})
 		assert.NoError(t, err)
 		assert.NotEmpty(t, shorten)
 	})
+	t.Run("by tag", func(t *testing.T) {
+		ctx := auth.Context(context.Background(), uid)
+		_, err := instance.ShortenLink(ctx, models.ShortenRequest{
+			URL:         "https://practicum.yandex.ru/go",
+			LinkOptions: models.LinkOptions{Title: "Go", Tags: []string{" go ", "courses", "go"}},
+		})
+		require.NoError(t, err)
+
+		shorten, _, err := instance.LoadUsers(ctx, store.LinkFilter{Tag: "go"})
+		require.NoError(t, err)
+		require.Len(t, shorten, 1)
+		assert.Equal(t, "https://practicum.yandex.ru/go", shorten[0].OriginalURL)
+		assert.Equal(t, "Go", shorten[0].Title)
+		assert.Equal(t, []string{"go", "courses"}, shorten[0].Tags)
+
+		shorten, _, err = instance.LoadUsers(ctx, store.LinkFilter{Tag: "missing"})
+		require.NoError(t, err)
+		assert.Empty(t, shorten)
+	})
+	t.Run("pages", func(t *testing.T) {
+		ctx := auth.Context(context.Background(), uid)
+		for j := 0; j < 3; j++ {
+			_, err := instance.Shorten(ctx, targetURL+"page/"+strconv.Itoa(j))
+			require.NoError(t, err)
+		}
+		all, next, err := instance.LoadUsers(ctx, store.LinkFilter{})
+		require.NoError(t, err)
+		require.Len(t, all, 5)
+		assert.Empty(t, next)
+
+		var paged []models.URLResponse
+		filter, err := ParseLinkQuery("", "", SortCreated, OrderAsc, "2", "")
+		require.NoError(t, err)
+		for {
+			page, next, err := instance.LoadUsers(ctx, filter)
+			require.NoError(t, err)
+			paged = append(paged, page...)
+			if next == "" {
+				assert.Len(t, page, 1)
+				break
+			}
+			assert.Len(t, page, 2)
+			filter, err = ParseLinkQuery("", "", SortCreated, OrderAsc, "2", next)
+			require.NoError(t, err)
+		}
+		assert.Equal(t, all, paged)
+	})
 }
@@ -0,0 +1,66 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/analytics"
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/auth"
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/store"
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/models"
+)
+
+// Ограничения периода статистики по ссылке
+const (
+	DefaultStatsDays = 30  // DefaultStatsDays за сколько последних дней статистика отдается по умолчанию
+	MaxStatsDays     = 366 // MaxStatsDays самый длинный период статистики в днях
+)
+
+// StatsWindow разбирает период статистики из дат from и to в формате 2006-01-02, обе включительно.
+// Пустой to означает сегодня, пустой from - DefaultStatsDays дней до to.
+// Возвращает начало первого дня и начало дня, следующего за последним, по UTC.
+func StatsWindow(rawFrom, rawTo string, now time.Time) (from, to time.Time, err error) {
+	last := now.UTC().Truncate(24 * time.Hour)
+	if rawTo != "" {
+		if last, err = time.Parse(analytics.DateLayout, rawTo); err != nil {
+			return from, to, fmt.Errorf("%w: bad to date %q", ErrStatsWindow, rawTo)
+		}
+	}
+	first := last.AddDate(0, 0, -(DefaultStatsDays - 1))
+	if rawFrom != "" {
+		if first, err = time.Parse(analytics.DateLayout, rawFrom); err != nil {
+			return from, to, fmt.Errorf("%w: bad from date %q", ErrStatsWindow, rawFrom)
+		}
+	}
+
+	to = last.AddDate(0, 0, 1)
+	if !first.Before(to) {
+		return from, to, fmt.Errorf("%w: from must not be after to", ErrStatsWindow)
+	}
+	if first.AddDate(0, 0, MaxStatsDays).Before(to) {
+		return from, to, fmt.Errorf("%w: period must not exceed %d days", ErrStatsWindow, MaxStatsDays)
+	}
+	return first, to, nil
+}
+
+// LinkStats статистика переходов по ссылке id за период с from по to.
+// Статистика доступна только владельцу ссылки, в том числе после удаления и истечения срока ссылки.
+func (i *Instance) LinkStats(ctx context.Context, id string, from, to time.Time) (models.LinkStats, error) {
+	uid := auth.UIDFromContext(ctx)
+	if uid == nil {
+		return models.LinkStats{}, ErrAuth
+	}
+	key := i.LinkID(ctx, id)
+	_, err := i.Store.LoadUser(ctx, *uid, key)
+	if err != nil && !errors.Is(err, store.ErrDeleted) && !errors.Is(err, store.ErrExpired) {
+		return models.LinkStats{}, err
+	}
+
+	clicks, err := i.Store.LoadClicks(ctx, key, from, to)
+	if err != nil {
+		return models.LinkStats{}, fmt.Errorf("cannot load clicks: %w", err)
+	}
+	return analytics.Summarize(id, clicks, from, to), nil
+}
@@ -0,0 +1,93 @@
+package app
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/auth"
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/store"
+)
+
+func TestStatsWindow(t *testing.T) {
+	now := time.Date(2024, 3, 15, 13, 30, 0, 0, time.UTC)
+	day := func(d string) time.Time {
+		res, _ := time.Parse("2006-01-02", d)
+		return res
+	}
+
+	tests := []struct {
+		name     string
+		from, to string
+		wantFrom time.Time
+		wantTo   time.Time
+		wantErr  bool
+	}{
+		{name: "default", wantFrom: day("2024-02-15"), wantTo: day("2024-03-16")},
+		{name: "single day", from: "2024-03-01", to: "2024-03-01", wantFrom: day("2024-03-01"), wantTo: day("2024-03-02")},
+		{name: "only to", to: "2024-01-30", wantFrom: day("2024-01-01"), wantTo: day("2024-01-31")},
+		{name: "max period", from: "2023-03-16", to: "2024-03-15", wantFrom: day("2023-03-16"), wantTo: day("2024-03-16")},
+		{name: "too long", from: "2023-03-15", to: "2024-03-15", wantErr: true},
+		{name: "reversed", from: "2024-03-02", to: "2024-03-01", wantErr: true},
+		{name: "bad from", from: "01.03.2024", wantErr: true},
+		{name: "bad to", to: "yesterday", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to, err := StatsWindow(tt.from, tt.to, now)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrStatsWindow)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantFrom, from)
+			assert.Equal(t, tt.wantTo, to)
+		})
+	}
+}
+
+func TestInstance_LinkStats(t *testing.T) {
+	owner := uuid.Must(uuid.NewV4())
+	stranger := uuid.Must(uuid.NewV4())
+	instance := &Instance{
+		BaseURL: "http://localhost:8080",
+		Store:   store.NewInMemory(),
+	}
+	u, _ := url.Parse("https://praktikum.yandex.ru/")
+	id, err := instance.Store.SaveUser(context.Background(), owner, u)
+	require.NoError(t, err)
+
+	from := time.Now().UTC().Truncate(24 * time.Hour)
+	to := from.AddDate(0, 0, 1)
+	require.NoError(t, instance.Store.SaveClicks(context.Background(), []store.Click{
+		{Time: time.Now(), ID: id, IPHash: "a"},
+		{Time: time.Now(), ID: id, IPHash: "a"},
+	}))
+
+	t.Run("owner", func(t *testing.T) {
+		stats, err := instance.LinkStats(auth.Context(context.Background(), owner), id, from, to)
+		require.NoError(t, err)
+		assert.Equal(t, 2, stats.TotalClicks)
+		assert.Equal(t, 1, stats.UniqueVisitors)
+		assert.Len(t, stats.Daily, 1)
+	})
+	t.Run("stranger", func(t *testing.T) {
+		_, err := instance.LinkStats(auth.Context(context.Background(), stranger), id, from, to)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+	})
+	t.Run("anonymous", func(t *testing.T) {
+		_, err := instance.LinkStats(context.Background(), id, from, to)
+		assert.ErrorIs(t, err, ErrAuth)
+	})
+	t.Run("deleted", func(t *testing.T) {
+		require.NoError(t, instance.Store.DeleteUsers(context.Background(), owner, id))
+		stats, err := instance.LinkStats(auth.Context(context.Background(), owner), id, from, to)
+		require.NoError(t, err)
+		assert.Equal(t, 2, stats.TotalClicks)
+	})
+}
@@ -0,0 +1,149 @@
+package app
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/store"
+)
+
+// placeholderName допустимое имя подстановки: номер сегмента пути с 1 или имя параметра запроса
+var placeholderName = regexp.MustCompile(`^(?:[1-9][0-9]*|[A-Za-z_][A-Za-z0-9_]*)$`)
+
+// ValidateTemplate проверяет адрес ссылки-шаблона: подстановки допускаются только в пути и параметрах запроса
+// и в адресе должна быть хотя бы одна подстановка.
+func ValidateTemplate(u *url.URL) error {
+	password, _ := u.User.Password()
+	if strings.ContainsAny(u.User.Username()+password+u.Fragment, "{}") {
+		return fmt.Errorf("%w: placeholders are only allowed in path and query", ErrTemplate)
+	}
+	n := 0
+	for _, s := range []string{templatePath(u), templateQuery(u)} {
+		_, err := fillPlaceholders(s, func(string) (string, error) {
+			n++
+			return "", nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: no placeholders", ErrTemplate)
+	}
+	return nil
+}
+
+// fillTemplate заполняет подстановки в адресе ссылки-шаблона target из продолжения запроса tail.
+// Подстановка {1}, {2} и так далее заполняется сегментом пути после кода ссылки с этим номером,
+// а {name} - параметром запроса name. Например, https://tracker/issues/{1} по запросу /jira/42
+// ведет на https://tracker/issues/42, а https://search/?q={q} по запросу /find?q=go на https://search/?q=go.
+// Значения экранируются для своей части адреса, так что подставленное значение не может поменять
+// хост адреса, добавить сегменты пути или параметры запроса. Значения . и .. в пути не допускаются.
+// Если для подстановки нет значения, возвращается ErrTemplateValues, а при лишних сегментах пути store.ErrNotFound.
+func fillTemplate(target *url.URL, tail Tail) (*url.URL, error) {
+	var segments []string
+	if extra := strings.Trim(tail.Path, "/"); extra != "" {
+		segments = strings.Split(extra, "/")
+	}
+	query, err := url.ParseQuery(tail.RawQuery)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTemplateValues, err)
+	}
+	used := 0
+	value := func(name string) (string, error) {
+		n, err := strconv.Atoi(name)
+		if err != nil {
+			if v := query.Get(name); v != "" {
+				return v, nil
+			}
+			return "", fmt.Errorf("%w: no value for {%s}", ErrTemplateValues, name)
+		}
+		if n > used {
+			used = n
+		}
+		if n > len(segments) {
+			return "", fmt.Errorf("%w: no value for {%s}", ErrTemplateValues, name)
+		}
+		v, err := url.PathUnescape(segments[n-1])
+		if err != nil || v == "" {
+			return "", fmt.Errorf("%w: bad value for {%s}", ErrTemplateValues, name)
+		}
+		return v, nil
+	}
+
+	res := *target
+	path, err := fillPlaceholders(templatePath(target), func(name string) (string, error) {
+		v, err := value(name)
+		if err != nil {
+			return "", err
+		}
+		if v == "." || v == ".." {
+			return "", fmt.Errorf("%w: bad value for {%s}", ErrTemplateValues, name)
+		}
+		return url.PathEscape(v), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	res.RawQuery, err = fillPlaceholders(templateQuery(target), func(name string) (string, error) {
+		v, err := value(name)
+		return url.QueryEscape(v), err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if used < len(segments) {
+		return nil, store.ErrNotFound
+	}
+	if res.Path, err = url.PathUnescape(path); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTemplateValues, err)
+	}
+	res.RawPath = path
+	return &res, nil
+}
+
+// fillPlaceholders заменяет подстановки в строке s значениями value
+func fillPlaceholders(s string, value func(name string) (string, error)) (string, error) {
+	var b strings.Builder
+	for {
+		open := strings.IndexAny(s, "{}")
+		if open < 0 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+		if s[open] == '}' {
+			return "", fmt.Errorf("%w: unexpected }", ErrTemplate)
+		}
+		end := strings.IndexAny(s[open+1:], "{}")
+		if end < 0 || s[open+1+end] != '}' {
+			return "", fmt.Errorf("%w: unclosed {", ErrTemplate)
+		}
+		name := s[open+1 : open+1+end]
+		if !placeholderName.MatchString(name) {
+			return "", fmt.Errorf("%w: bad placeholder {%s}", ErrTemplate, name)
+		}
+		v, err := value(name)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(s[:open])
+		b.WriteString(v)
+		s = s[open+end+2:]
+	}
+}
+
+// braces возвращает фигурным скобкам подстановок вид, который они теряют при экранировании адреса
+var braces = strings.NewReplacer("%7B", "{", "%7b", "{", "%7D", "}", "%7d", "}")
+
+// templatePath возвращает путь адреса в экранированном виде с подстановками в фигурных скобках
+func templatePath(u *url.URL) string {
+	return braces.Replace(u.EscapedPath())
+}
+
+// templateQuery возвращает параметры адреса с подстановками в фигурных скобках
+func templateQuery(u *url.URL) string {
+	return braces.Replace(u.RawQuery)
+}
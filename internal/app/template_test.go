@@ -0,0 +1,163 @@
+package app
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/auth"
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/store"
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/models"
+)
+
+func TestValidateTemplate(t *testing.T) {
+	tests := []struct {
+		template string
+		wantErr  bool
+	}{
+		{template: "https://tracker/issues/{1}"},
+		{template: "https://search/?q={q}&page={2}"},
+		{template: "https://tracker/{1}/issues/{2}-{title}"},
+		{template: "https://tracker/issues/%7B1%7D"},
+		{template: "https://tracker/issues/", wantErr: true},
+		{template: "https://tracker/issues/{0}", wantErr: true},
+		{template: "https://tracker/issues/{a-b}", wantErr: true},
+		{template: "https://tracker/issues/{1", wantErr: true},
+		{template: "https://tracker/issues/1}", wantErr: true},
+		{template: "https://tracker/issues/{{1}}", wantErr: true},
+		{template: "https://tracker/issues#{1}", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			u, err := url.Parse(tt.template)
+			require.NoError(t, err)
+			err = ValidateTemplate(u)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrTemplate)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+
+	u := &url.URL{Scheme: "https", User: url.User("{user}"), Host: "tracker", Path: "/issues/{1}"}
+	assert.ErrorIs(t, ValidateTemplate(u), ErrTemplate)
+}
+
+func TestFillTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		tail     Tail
+		want     string
+		wantErr  error
+	}{
+		{name: "path", template: "https://tracker/issues/{1}", tail: Tail{Path: "/42"}, want: "https://tracker/issues/42"},
+		{
+			name:     "query",
+			template: "https://search/?q={q}&lang=ru",
+			tail:     Tail{RawQuery: "q=go+generics&x=1"},
+			want:     "https://search/?q=go+generics&lang=ru",
+		},
+		{
+			name:     "both",
+			template: "https://tracker/{1}/issues/{2}?from={from}",
+			tail:     Tail{Path: "/GO/42/", RawQuery: "from=short"},
+			want:     "https://tracker/GO/issues/42?from=short",
+		},
+		{
+			name:     "stored escaped",
+			template: "https://tracker/issues/%7B1%7D",
+			tail:     Tail{Path: "/42"},
+			want:     "https://tracker/issues/42",
+		},
+		{
+			name:     "path injection escaped",
+			template: "https://tracker/issues/{1}",
+			tail:     Tail{Path: "/%2F%2Fevil.example%3Fx=1%23"},
+			want:     "https://tracker/issues/%2F%2Fevil.example%3Fx=1%23",
+		},
+		{
+			name:     "query injection escaped",
+			template: "https://search/?q={q}",
+			tail:     Tail{RawQuery: "q=" + url.QueryEscape("go&redirect=https://evil.example/#x")},
+			want:     "https://search/?q=go%26redirect%3Dhttps%3A%2F%2Fevil.example%2F%23x",
+		},
+		{
+			name:     "host is fixed",
+			template: "https://tracker/{1}",
+			tail:     Tail{Path: "/@evil.example"},
+			want:     "https://tracker/@evil.example",
+		},
+		{name: "dot dot", template: "https://tracker/issues/{1}", tail: Tail{Path: "/.."}, wantErr: ErrTemplateValues},
+		{name: "escaped dot dot", template: "https://tracker/issues/{1}", tail: Tail{Path: "/%2E%2e"}, wantErr: ErrTemplateValues},
+		{name: "missing segment", template: "https://tracker/issues/{1}", wantErr: ErrTemplateValues},
+		{name: "missing param", template: "https://search/?q={q}", tail: Tail{RawQuery: "p=1"}, wantErr: ErrTemplateValues},
+		{name: "extra segment", template: "https://tracker/issues/{1}", tail: Tail{Path: "/42/43"}, wantErr: store.ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, err := url.Parse(tt.template)
+			require.NoError(t, err)
+			got, err := fillTemplate(target, tt.tail)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.String())
+			assert.Equal(t, target.Host, got.Host)
+		})
+	}
+}
+
+func TestInstance_ShortenLink_Template(t *testing.T) {
+	uid := uuid.Must(uuid.NewV4())
+	ctx := auth.Context(context.Background(), uid)
+	instance := &Instance{
+		BaseURL: "http://localhost:8080",
+		Store:   store.NewInMemory(),
+	}
+	shorten := func(target string, opts models.LinkOptions) (string, error) {
+		shortURL, err := instance.ShortenLink(ctx, models.ShortenRequest{URL: target, LinkOptions: opts})
+		return strings.TrimPrefix(shortURL, instance.BaseURL+"/"), err
+	}
+
+	id, err := shorten("https://tracker.example/issues/{1}", models.LinkOptions{Template: true})
+	require.NoError(t, err)
+	link, err := instance.ExpandTail(ctx, id, "", Tail{Path: "/42"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://tracker.example/issues/42", link.URL.String())
+	_, err = instance.ExpandTail(ctx, id, "", Tail{})
+	assert.ErrorIs(t, err, ErrTemplateValues)
+
+	// braces are only placeholders in template links
+	plain, err := shorten("https://tracker.example/issues/{1}", models.LinkOptions{})
+	require.NoError(t, err)
+	link, err = instance.Expand(ctx, plain, "")
+	require.NoError(t, err)
+	assert.Equal(t, "https://tracker.example/issues/%7B1%7D", link.URL.String())
+
+	_, err = shorten("https://tracker.example/issues/", models.LinkOptions{Template: true})
+	assert.ErrorIs(t, err, ErrTemplate)
+	_, err = shorten("https://tracker.example/issues/{1}", models.LinkOptions{Template: true, Passthrough: "path"})
+	assert.ErrorIs(t, err, ErrTemplate)
+	_, err = instance.BatchShorten([]models.BatchShortenRequest{
+		{CorrelationID: "1", OriginalURL: "https://tracker.example/{", LinkOptions: models.LinkOptions{Template: true}},
+	}, ctx)
+	assert.ErrorIs(t, err, ErrTemplate)
+
+	// a new address of a template link must be a template as well
+	_, err = instance.UpdateURL(ctx, id, models.UpdateURLRequest{OriginalURL: "https://tracker.example/"})
+	assert.ErrorIs(t, err, ErrTemplate)
+	_, err = instance.UpdateURL(ctx, id, models.UpdateURLRequest{OriginalURL: "https://tracker.example/browse/{1}"})
+	require.NoError(t, err)
+	link, err = instance.ExpandTail(ctx, id, "", Tail{Path: "/GO-1"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://tracker.example/browse/GO-1", link.URL.String())
+}
@@ -0,0 +1,108 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/analytics"
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/config"
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/store"
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/models"
+)
+
+// Ограничения A/B-теста
+const (
+	MaxVariants          = 10    // MaxVariants наибольшее число вариантов у ссылки
+	MaxVariantWeight     = 10000 // MaxVariantWeight наибольший вес варианта
+	MaxVariantNameLength = 64    // MaxVariantNameLength наибольшая длина названия варианта в символах
+)
+
+// visitorKey ключ контекста с ключом посетителя
+type visitorKey struct{}
+
+// WithVisitor возвращает контекст с ключом посетителя. Посетитель с одним и тем же ключом
+// всегда попадает в один и тот же вариант ссылки.
+func WithVisitor(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, visitorKey{}, key)
+}
+
+// VisitorKey возвращает ключ посетителя, у которого своего ключа еще нет, по хешу его адреса
+func VisitorKey(ip string) string {
+	return analytics.HashIP(config.AuthSecret, ip)
+}
+
+// newVariants проверяет варианты A/B-теста. Вариантов должно быть не меньше двух,
+// безымянные варианты называются по порядку буквами A, B, C..., нулевой вес считается единицей.
+// Для пустого списка возвращается nil.
+func (i *Instance) newVariants(ctx context.Context, variants []models.Variant) ([]store.Variant, error) {
+	if len(variants) == 0 {
+		return nil, nil
+	}
+	if len(variants) == 1 {
+		return nil, fmt.Errorf("%w: at least two variants are needed", ErrVariants)
+	}
+	if len(variants) > MaxVariants {
+		return nil, fmt.Errorf("%w: more than %d variants", ErrVariants, MaxVariants)
+	}
+	res := make([]store.Variant, 0, len(variants))
+	seen := make(map[string]bool, len(variants))
+	for n, v := range variants {
+		name := strings.TrimSpace(v.Name)
+		if name == "" {
+			name = string(rune('A' + n))
+		}
+		if utf8.RuneCountInString(name) > MaxVariantNameLength {
+			return nil, fmt.Errorf("%w: name %q is longer than %d characters", ErrVariants, name, MaxVariantNameLength)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("%w: duplicate name %q", ErrVariants, name)
+		}
+		seen[name] = true
+		weight := v.Weight
+		if weight == 0 {
+			weight = 1
+		}
+		if weight < 0 || weight > MaxVariantWeight {
+			return nil, fmt.Errorf("%w: weight of %s must be between 1 and %d", ErrVariants, name, MaxVariantWeight)
+		}
+		u, err := i.targetURL(ctx, v.URL)
+		if err != nil {
+			return nil, fmt.Errorf("variant %s: %w", name, err)
+		}
+		res = append(res, store.Variant{Name: name, URL: u.String(), Weight: weight})
+	}
+	return res, nil
+}
+
+// pickVariant выбирает вариант ссылки id для посетителя с ключом key. Выбор зависит только от ссылки,
+// ключа и вариантов, а доля посетителей каждого варианта пропорциональна его весу.
+func pickVariant(variants []store.Variant, id, key string) store.Variant {
+	var total uint64
+	for _, v := range variants {
+		total += uint64(v.Weight)
+	}
+	if total == 0 {
+		return variants[0]
+	}
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(id))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(key))
+	n := h.Sum64() % total
+	for _, v := range variants {
+		if n < uint64(v.Weight) {
+			return v
+		}
+		n -= uint64(v.Weight)
+	}
+	return variants[len(variants)-1]
+}
+
+// visitor ключ посетителя из контекста
+func visitor(ctx context.Context) string {
+	key, _ := ctx.Value(visitorKey{}).(string)
+	return key
+}
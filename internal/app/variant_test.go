@@ -0,0 +1,174 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/analytics"
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/auth"
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/store"
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/models"
+)
+
+func TestPickVariant(t *testing.T) {
+	variants := []store.Variant{
+		{Name: "A", URL: "https://example.com/a", Weight: 3},
+		{Name: "B", URL: "https://example.com/b", Weight: 1},
+	}
+
+	// the same visitor always gets the same variant
+	first := pickVariant(variants, "abc", "visitor")
+	for j := 0; j < 10; j++ {
+		assert.Equal(t, first, pickVariant(variants, "abc", "visitor"))
+	}
+
+	counts := make(map[string]int)
+	const visitors = 10000
+	for j := 0; j < visitors; j++ {
+		counts[pickVariant(variants, "abc", strconv.Itoa(j)).Name]++
+	}
+	assert.InDelta(t, visitors*3/4, counts["A"], visitors/20)
+	assert.InDelta(t, visitors/4, counts["B"], visitors/20)
+
+	single := []store.Variant{{Name: "A", Weight: 1}, {Name: "B", Weight: 0}}
+	for j := 0; j < 100; j++ {
+		assert.Equal(t, "A", pickVariant(single, "abc", strconv.Itoa(j)).Name)
+	}
+}
+
+func TestInstance_newVariants(t *testing.T) {
+	instance := &Instance{BaseURL: "http://localhost:8080", Store: store.NewInMemory()}
+	ctx := context.Background()
+
+	variants, err := instance.newVariants(ctx, []models.Variant{
+		{URL: "https://example.com/a"},
+		{Name: " new ", URL: "https://example.com/b", Weight: 3},
+		{URL: "https://example.com/c"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []store.Variant{
+		{Name: "A", URL: "https://example.com/a", Weight: 1},
+		{Name: "new", URL: "https://example.com/b", Weight: 3},
+		{Name: "C", URL: "https://example.com/c", Weight: 1},
+	}, variants)
+
+	variants, err = instance.newVariants(ctx, nil)
+	require.NoError(t, err)
+	assert.Nil(t, variants)
+
+	invalid := [][]models.Variant{
+		{{URL: "https://example.com/a"}},
+		make([]models.Variant, MaxVariants+1),
+		{{Name: "A", URL: "https://example.com/a"}, {Name: "A", URL: "https://example.com/b"}},
+		{{URL: "https://example.com/a", Weight: -1}, {URL: "https://example.com/b"}},
+		{{URL: "https://example.com/a", Weight: MaxVariantWeight + 1}, {URL: "https://example.com/b"}},
+		{{Name: strings.Repeat("x", MaxVariantNameLength+1), URL: "https://example.com/a"}, {URL: "https://example.com/b"}},
+	}
+	for _, v := range invalid {
+		_, err := instance.newVariants(ctx, v)
+		assert.ErrorIs(t, err, ErrVariants, "%+v", v)
+	}
+	_, err = instance.newVariants(ctx, []models.Variant{{URL: "https://example.com/a"}, {URL: "not a url"}})
+	assert.Error(t, err)
+}
+
+func TestInstance_Variants(t *testing.T) {
+	uid := uuid.Must(uuid.NewV4())
+	ctx := auth.Context(context.Background(), uid)
+	instance := &Instance{BaseURL: "http://localhost:8080", Store: store.NewInMemory()}
+
+	shortURL, err := instance.ShortenLink(ctx, models.ShortenRequest{
+		URL: "https://example.com/",
+		LinkOptions: models.LinkOptions{
+			Variants: []models.Variant{{URL: "https://example.com/a"}, {URL: "https://example.com/b"}},
+			Rules:    []models.Rule{{Devices: []string{"ios"}, URL: "https://apps.apple.com/app/id1"}},
+		},
+	})
+	require.NoError(t, err)
+	id := strings.TrimPrefix(shortURL, instance.BaseURL+"/")
+
+	seen := make(map[string]bool)
+	for j := 0; j < 50; j++ {
+		visitor := WithVisitor(ctx, strconv.Itoa(j))
+		link, err := instance.Expand(visitor, id, "")
+		require.NoError(t, err)
+		require.Contains(t, []string{"A", "B"}, link.Variant)
+		assert.Equal(t, "https://example.com/"+strings.ToLower(link.Variant), link.URL.String())
+		seen[link.Variant] = true
+
+		again, err := instance.Expand(visitor, id, "")
+		require.NoError(t, err)
+		assert.Equal(t, link.Variant, again.Variant, "assignment is sticky")
+	}
+	assert.Len(t, seen, 2)
+
+	// rules are checked before the split
+	ios := WithHeader(WithVisitor(ctx, "1"), http.Header{"User-Agent": {iPhoneUA}})
+	link, err := instance.Expand(ios, id, "")
+	require.NoError(t, err)
+	assert.Equal(t, "https://apps.apple.com/app/id1", link.URL.String())
+	assert.Empty(t, link.Variant)
+
+	_, err = instance.ShortenLink(ctx, models.ShortenRequest{
+		URL: "https://tracker.example/{1}",
+		LinkOptions: models.LinkOptions{
+			Template: true,
+			Variants: []models.Variant{{URL: "https://example.com/a"}, {URL: "https://example.com/b"}},
+		},
+	})
+	assert.ErrorIs(t, err, ErrVariants)
+}
+
+func TestInstance_LinkStats_Variants(t *testing.T) {
+	uid := uuid.Must(uuid.NewV4())
+	ctx := auth.Context(context.Background(), uid)
+	s := store.NewInMemory()
+	instance := &Instance{
+		BaseURL: "http://localhost:8080",
+		Store:   s,
+		Clicks:  analytics.NewRecorder(s, 0, time.Hour),
+	}
+	recorder, stop := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		instance.Clicks.Run(recorder)
+		close(done)
+	}()
+
+	shortURL, err := instance.ShortenLink(ctx, models.ShortenRequest{
+		URL: "https://example.com/",
+		LinkOptions: models.LinkOptions{
+			Variants: []models.Variant{{Name: "old", URL: "https://example.com/a"}, {Name: "new", URL: "https://example.com/b"}},
+		},
+	})
+	require.NoError(t, err)
+	id := strings.TrimPrefix(shortURL, instance.BaseURL+"/")
+
+	want := make(map[string]int)
+	for j := 0; j < 20; j++ {
+		link, err := instance.Expand(WithVisitor(ctx, strconv.Itoa(j)), id, "")
+		require.NoError(t, err)
+		instance.RecordClick(ctx, id, Visit{IP: "127.0.0.1", Variant: link.Variant})
+		want[link.Variant]++
+	}
+	stop()
+	<-done
+
+	from := time.Now().UTC().Truncate(24 * time.Hour)
+	stats, err := instance.LinkStats(ctx, id, from, from.AddDate(0, 0, 1))
+	require.NoError(t, err)
+	assert.Equal(t, 20, stats.TotalClicks)
+	got := make(map[string]int)
+	for _, v := range stats.Variants {
+		got[v.Value] = v.Clicks
+	}
+	assert.Equal(t, want, got)
+}
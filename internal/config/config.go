@@ -3,6 +3,7 @@ package config
 import (
 	"encoding/json"
 	"flag"
+	"fmt"
 	"os"
 	"strconv"
 	"strings"
@@ -14,6 +15,7 @@ var (
 	RunPort         = ":8080"                                    // RunPort порт для запуска приложения
 	GrpcPort        = ":4080"                                    // GrpcPort порт для запуска grpc сервера приложения
 	BaseURL         = "http://localhost" + RunPort               // BaseURL базовый URL для приложения
+	ShortDomains    = ""                                         // ShortDomains базовые URL дополнительных доменов коротких ссылок через запятую
 	PersistFile     = ""                                         // PersistFile файл для хранилища
 	AuthSecret      = []byte("ololo-trololo-shimba-boomba-look") // AuthSecret сикрет для авторизации пользователя
 	DatabaseDSN     = ""                                         // DatabaseDSN строка подключения к БД
@@ -23,6 +25,27 @@ var (
 	ConfigFile      = ""                                         // ConfigFile путь к файлу с конфигурацией приложения
 	ShutdownTimeout = 10 * time.Second                           // ShutdownTimeout время ожидания для graceful shutdown
 	TrustedSubnet   = ""                                         // TrustedSubnet маска подсети
+
+	SnapshotInterval  = 5 * time.Minute // SnapshotInterval периодичность снимков и сжатия файлового хранилища
+	SnapshotThreshold = int64(1 << 20)  // SnapshotThreshold сколько байт журнала должно накопиться для сжатия
+
+	IDStrategy = "counter"        // IDStrategy стратегия генерации коротких идентификаторов: counter, random или hashids
+	IDLength   = 0                // IDLength длина коротких идентификаторов, 0 - длина по умолчанию для стратегии
+	IDSalt     = "shortener-salt" // IDSalt соль для стратегии hashids
+
+	ReaperInterval   = time.Hour          // ReaperInterval периодичность удаления просроченных ссылок
+	ExpiredRetention = 7 * 24 * time.Hour // ExpiredRetention сколько хранить просроченные ссылки перед удалением
+
+	ClickBatchSize     = 100                  // ClickBatchSize сколько переходов по ссылкам сохранять за раз
+	ClickFlushInterval = time.Second          // ClickFlushInterval как часто сохранять неполный пакет переходов
+	ClickRetention     = 400 * 24 * time.Hour // ClickRetention сколько хранить переходы по ссылкам, 0 - бессрочно
+
+	URLSchemes          = "http,https" // URLSchemes разрешенные схемы сокращаемых адресов через запятую
+	MaxURLLength        = 2048         // MaxURLLength наибольшая длина сокращаемого адреса
+	StripTrackingParams = false        // StripTrackingParams удалять из адресов utm_* и другие параметры отслеживания
+
+	BlocklistFile           = ""               // BlocklistFile файл списка запрещенных доменов и шаблонов адресов
+	BlocklistReloadInterval = 30 * time.Second // BlocklistReloadInterval как часто проверять изменения списка запрещенных адресов
 )
 
 // AppConfig структура для конфигурации приложения
@@ -30,6 +53,7 @@ type AppConfig struct {
 	RunPort         string `json:"run_port"`         // RunPort порт для запуска приложения
 	GrpcPort        string `json:"grpc_port"`        // GrpcPort порт для запуска grpc сервера приложения
 	BaseURL         string `json:"base_url"`         // BaseURL базовый URL для приложения
+	ShortDomains    string `json:"short_domains"`    // ShortDomains базовые URL дополнительных доменов через запятую
 	PersistFile     string `json:"persist_file"`     // PersistFile файл для хранилища
 	DatabaseDSN     string `json:"database_dsn"`     // DatabaseDSN строка подключения к БД
 	UseTLS          bool   `json:"use_tls"`          // UseTLS флаг использования TLS
@@ -37,12 +61,61 @@ type AppConfig struct {
 	KeyFile         string `json:"key_file"`         // KeyFile путь к файлу с приватным ключом
 	ShutdownTimeout int    `json:"shutdown_timeout"` // ShutdownTimeout время ожидания для graceful shutdown
 	TrustedSubnet   string `json:"trusted_subnet"`   // TrustedSubnet маска подсети
+
+	SnapshotInterval  Duration `json:"snapshot_interval"`  // SnapshotInterval периодичность снимков файлового хранилища, число - в секундах
+	SnapshotThreshold int64    `json:"snapshot_threshold"` // SnapshotThreshold сколько байт журнала должно накопиться для сжатия
+
+	IDStrategy string `json:"id_strategy"` // IDStrategy стратегия генерации коротких идентификаторов
+	IDLength   int    `json:"id_length"`   // IDLength длина коротких идентификаторов
+	IDSalt     string `json:"id_salt"`     // IDSalt соль для стратегии hashids
+
+	ReaperInterval   Duration `json:"reaper_interval"`   // ReaperInterval периодичность удаления просроченных ссылок, число - в секундах
+	ExpiredRetention Duration `json:"expired_retention"` // ExpiredRetention сколько хранить просроченные ссылки, число - в секундах
+
+	ClickBatchSize     int      `json:"click_batch_size"`     // ClickBatchSize сколько переходов по ссылкам сохранять за раз
+	ClickFlushInterval Duration `json:"click_flush_interval"` // ClickFlushInterval как часто сохранять неполный пакет переходов, число - в миллисекундах
+	ClickRetention     Duration `json:"click_retention"`      // ClickRetention сколько хранить переходы по ссылкам, число - в секундах
+
+	URLSchemes          string `json:"url_schemes"`           // URLSchemes разрешенные схемы сокращаемых адресов через запятую
+	MaxURLLength        int    `json:"max_url_length"`        // MaxURLLength наибольшая длина сокращаемого адреса
+	StripTrackingParams bool   `json:"strip_tracking_params"` // StripTrackingParams удалять из адресов параметры отслеживания
+
+	BlocklistFile           string   `json:"blocklist_file"`            // BlocklistFile файл списка запрещенных адресов
+	BlocklistReloadInterval Duration `json:"blocklist_reload_interval"` // BlocklistReloadInterval как часто проверять изменения списка, число - в секундах
+}
+
+// Duration длительность в файле конфигурации: строка в формате time.ParseDuration, например "30s",
+// или число в единицах, указанных у поля
+type Duration string
+
+// UnmarshalJSON принимает длительность строкой или числом
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*d = Duration(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("duration must be a string or a number: %w", err)
+	}
+	*d = Duration(n)
+	return nil
+}
+
+// parseDuration разбирает длительность в формате time.ParseDuration или целое число в единицах unit
+func parseDuration(s string, unit time.Duration) (time.Duration, error) {
+	if n, err := strconv.Atoi(s); err == nil {
+		return time.Duration(n) * unit, nil
+	}
+	return time.ParseDuration(s)
 }
 
 // Parse разбарает папаметры запуска приложения
 func Parse() {
 	flag.StringVar(&RunPort, "a", RunPort, "port to run server")
 	flag.StringVar(&BaseURL, "b", BaseURL, "base URL for shorten URL response")
+	flag.StringVar(&ShortDomains, "sd", ShortDomains, "comma separated base URLs of additional short domains")
 	flag.StringVar(&PersistFile, "f", PersistFile, "file to store shorten URLs")
 	flag.StringVar(&DatabaseDSN, "d", DatabaseDSN, "connection string to database")
 	flag.BoolVar(&UseTLS, "s", UseTLS, "use TLS for server")
@@ -52,6 +125,21 @@ func Parse() {
 	flag.DurationVar(&ShutdownTimeout, "gst", ShutdownTimeout, "graceful shutdown timeout")
 	flag.StringVar(&TrustedSubnet, "t", TrustedSubnet, "CIDR")
 	flag.StringVar(&GrpcPort, "gp", GrpcPort, "port for grpc server")
+	flag.DurationVar(&SnapshotInterval, "si", SnapshotInterval, "file storage snapshot interval")
+	flag.Int64Var(&SnapshotThreshold, "st", SnapshotThreshold, "file storage journal size to trigger compaction")
+	flag.StringVar(&IDStrategy, "ids", IDStrategy, "short id strategy: counter, random or hashids")
+	flag.IntVar(&IDLength, "idl", IDLength, "short id length")
+	flag.StringVar(&IDSalt, "idsalt", IDSalt, "salt for hashids short id strategy")
+	flag.DurationVar(&ReaperInterval, "ri", ReaperInterval, "expired links purge interval")
+	flag.DurationVar(&ExpiredRetention, "er", ExpiredRetention, "how long to keep expired links before purge")
+	flag.IntVar(&ClickBatchSize, "cbs", ClickBatchSize, "number of clicks saved at once")
+	flag.DurationVar(&ClickFlushInterval, "cfi", ClickFlushInterval, "clicks flush interval")
+	flag.DurationVar(&ClickRetention, "cr", ClickRetention, "how long to keep clicks, 0 keeps them forever")
+	flag.StringVar(&URLSchemes, "us", URLSchemes, "comma separated URL schemes allowed for shortening")
+	flag.IntVar(&MaxURLLength, "ul", MaxURLLength, "max length of URL allowed for shortening")
+	flag.BoolVar(&StripTrackingParams, "strip", StripTrackingParams, "strip utm_* and other tracking parameters from URLs")
+	flag.StringVar(&BlocklistFile, "bl", BlocklistFile, "file with blocked domains and URL patterns")
+	flag.DurationVar(&BlocklistReloadInterval, "blr", BlocklistReloadInterval, "blocklist file change check interval")
 
 	flag.Parse()
 	if ConfigFile != "" {
@@ -70,6 +158,9 @@ func Parse() {
 	if val := os.Getenv("BASE_URL"); val != "" {
 		BaseURL = val
 	}
+	if val := os.Getenv("SHORT_DOMAINS"); val != "" {
+		ShortDomains = val
+	}
 	if val := os.Getenv("FILE_STORAGE_PATH"); val != "" {
 		PersistFile = val
 	}
@@ -100,6 +191,84 @@ func Parse() {
 	if val := os.Getenv("TRUSTED_SUBNET"); val != "" {
 		TrustedSubnet = val
 	}
+	if val := os.Getenv("SNAPSHOT_INTERVAL"); val != "" {
+		interval, err := parseDuration(val, time.Second)
+		if err == nil {
+			SnapshotInterval = interval
+		}
+	}
+	if val := os.Getenv("SNAPSHOT_THRESHOLD"); val != "" {
+		threshold, err := strconv.ParseInt(val, 10, 64)
+		if err == nil {
+			SnapshotThreshold = threshold
+		}
+	}
+	if val := os.Getenv("ID_STRATEGY"); val != "" {
+		IDStrategy = val
+	}
+	if val := os.Getenv("ID_LENGTH"); val != "" {
+		length, err := strconv.Atoi(val)
+		if err == nil {
+			IDLength = length
+		}
+	}
+	if val := os.Getenv("ID_SALT"); val != "" {
+		IDSalt = val
+	}
+	if val := os.Getenv("REAPER_INTERVAL"); val != "" {
+		interval, err := parseDuration(val, time.Second)
+		if err == nil {
+			ReaperInterval = interval
+		}
+	}
+	if val := os.Getenv("EXPIRED_RETENTION"); val != "" {
+		interval, err := parseDuration(val, time.Second)
+		if err == nil {
+			ExpiredRetention = interval
+		}
+	}
+	if val := os.Getenv("CLICK_BATCH_SIZE"); val != "" {
+		size, err := strconv.Atoi(val)
+		if err == nil {
+			ClickBatchSize = size
+		}
+	}
+	if val := os.Getenv("CLICK_FLUSH_INTERVAL"); val != "" {
+		interval, err := parseDuration(val, time.Millisecond)
+		if err == nil {
+			ClickFlushInterval = interval
+		}
+	}
+	if val := os.Getenv("CLICK_RETENTION"); val != "" {
+		retention, err := parseDuration(val, time.Second)
+		if err == nil {
+			ClickRetention = retention
+		}
+	}
+	if val := os.Getenv("URL_SCHEMES"); val != "" {
+		URLSchemes = val
+	}
+	if val := os.Getenv("MAX_URL_LENGTH"); val != "" {
+		length, err := strconv.Atoi(val)
+		if err == nil {
+			MaxURLLength = length
+		}
+	}
+	if val := os.Getenv("STRIP_TRACKING_PARAMS"); val != "" {
+		parsedVal, err := strconv.ParseBool(val)
+		if err == nil {
+			StripTrackingParams = parsedVal
+		}
+	}
+	if val := os.Getenv("BLOCKLIST_FILE"); val != "" {
+		BlocklistFile = val
+	}
+	if val := os.Getenv("BLOCKLIST_RELOAD_INTERVAL"); val != "" {
+		interval, err := parseDuration(val, time.Second)
+		if err == nil {
+			BlocklistReloadInterval = interval
+		}
+	}
 
 	BaseURL = strings.TrimRight(BaseURL, "/")
 }
@@ -121,6 +290,9 @@ func ParseJSON() error {
 	if BaseURL == "" {
 		BaseURL = cfg.BaseURL
 	}
+	if ShortDomains == "" {
+		ShortDomains = cfg.ShortDomains
+	}
 	if PersistFile == "" {
 		PersistFile = cfg.PersistFile
 	}
@@ -140,6 +312,72 @@ func ParseJSON() error {
 	if GrpcPort == "" {
 		GrpcPort = cfg.GrpcPort
 	}
+	// values given on the command line take precedence over the file
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	durations := []struct {
+		flag  string
+		value Duration
+		unit  time.Duration
+		dst   *time.Duration
+	}{
+		{"si", cfg.SnapshotInterval, time.Second, &SnapshotInterval},
+		{"ri", cfg.ReaperInterval, time.Second, &ReaperInterval},
+		{"er", cfg.ExpiredRetention, time.Second, &ExpiredRetention},
+		{"cfi", cfg.ClickFlushInterval, time.Millisecond, &ClickFlushInterval},
+		{"cr", cfg.ClickRetention, time.Second, &ClickRetention},
+		{"blr", cfg.BlocklistReloadInterval, time.Second, &BlocklistReloadInterval},
+	}
+	for _, d := range durations {
+		if set[d.flag] || d.value == "" {
+			continue
+		}
+		value, err := parseDuration(string(d.value), d.unit)
+		if err != nil {
+			return fmt.Errorf("bad duration %q for -%s: %w", d.value, d.flag, err)
+		}
+		*d.dst = value
+	}
+	if !set["st"] && cfg.SnapshotThreshold > 0 {
+		SnapshotThreshold = cfg.SnapshotThreshold
+	}
+	if !set["ids"] && cfg.IDStrategy != "" {
+		IDStrategy = cfg.IDStrategy
+	}
+	if !set["idl"] && cfg.IDLength > 0 {
+		IDLength = cfg.IDLength
+	}
+	if !set["idsalt"] && cfg.IDSalt != "" {
+		IDSalt = cfg.IDSalt
+	}
+	if !set["cbs"] && cfg.ClickBatchSize > 0 {
+		ClickBatchSize = cfg.ClickBatchSize
+	}
+	if !set["us"] && cfg.URLSchemes != "" {
+		URLSchemes = cfg.URLSchemes
+	}
+	if !set["ul"] && cfg.MaxURLLength > 0 {
+		MaxURLLength = cfg.MaxURLLength
+	}
+	if !set["strip"] && cfg.StripTrackingParams {
+		StripTrackingParams = true
+	}
+	if !set["bl"] && cfg.BlocklistFile != "" {
+		BlocklistFile = cfg.BlocklistFile
+	}
 
 	return nil
 }
+
+// Schemes возвращает разрешенные схемы сокращаемых адресов из URLSchemes в нижнем регистре
+func Schemes() []string {
+	var schemes []string
+	for _, s := range strings.Split(URLSchemes, ",") {
+		if s = strings.ToLower(strings.TrimSpace(s)); s != "" {
+			schemes = append(schemes, s)
+		}
+	}
+	return schemes
+}
@@ -0,0 +1,549 @@
+// Package qr кодирует данные в QR-код (ISO/IEC 18004) и рисует его в PNG и SVG.
+//
+// Поддерживается только байтовый режим, которого достаточно для ссылок:
+// версия подбирается минимальная, в которую помещаются данные с заданным уровнем коррекции,
+// маска выбирается по штрафным баллам стандарта.
+package qr
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrTooLong данные не помещаются в QR-код максимальной версии
+var ErrTooLong = errors.New("data too long for QR code")
+
+// Level уровень коррекции ошибок
+type Level int
+
+// Уровни коррекции ошибок, в скобках доля восстанавливаемых кодовых слов
+const (
+	L Level = iota // L (~7%)
+	M              // M (~15%)
+	Q              // Q (~25%)
+	H              // H (~30%)
+)
+
+// ParseLevel разбирает уровень коррекции из строки L, M, Q или H без учета регистра
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToUpper(s) {
+	case "L":
+		return L, nil
+	case "M":
+		return M, nil
+	case "Q":
+		return Q, nil
+	case "H":
+		return H, nil
+	}
+	return 0, fmt.Errorf("unknown error correction level %q", s)
+}
+
+// String возвращает название уровня коррекции
+func (l Level) String() string {
+	return [...]string{"L", "M", "Q", "H"}[l]
+}
+
+// formatBits биты уровня в служебной информации о формате
+func (l Level) formatBits() int {
+	return [...]int{1, 0, 3, 2}[l]
+}
+
+const (
+	minVersion = 1
+	maxVersion = 40
+)
+
+// eccPerBlock число кодовых слов коррекции в блоке по уровню и версии
+var eccPerBlock = [4][maxVersion + 1]int{
+	{-1, 7, 10, 15, 20, 26, 18, 20, 24, 30, 18, 20, 24, 26, 30, 22, 24, 28, 30, 28, 28, 28, 28, 30, 30, 26, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	{-1, 10, 16, 26, 18, 24, 16, 18, 22, 22, 26, 30, 22, 22, 24, 24, 28, 28, 26, 26, 26, 26, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28},
+	{-1, 13, 22, 18, 26, 18, 24, 18, 22, 20, 24, 28, 26, 24, 20, 30, 24, 28, 28, 26, 30, 28, 30, 30, 30, 30, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	{-1, 17, 28, 22, 16, 22, 28, 26, 26, 24, 28, 24, 28, 22, 24, 24, 30, 28, 28, 26, 28, 30, 24, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+}
+
+// eccBlocks число блоков коррекции по уровню и версии
+var eccBlocks = [4][maxVersion + 1]int{
+	{-1, 1, 1, 1, 1, 1, 2, 2, 2, 2, 4, 4, 4, 4, 4, 6, 6, 6, 6, 7, 8, 8, 9, 9, 10, 12, 12, 12, 13, 14, 15, 16, 17, 18, 19, 19, 20, 21, 22, 24, 25},
+	{-1, 1, 1, 1, 2, 2, 4, 4, 4, 5, 5, 5, 8, 9, 9, 10, 10, 11, 13, 14, 16, 17, 17, 18, 20, 21, 23, 25, 26, 28, 29, 31, 33, 35, 37, 38, 40, 43, 45, 47, 49},
+	{-1, 1, 1, 2, 2, 4, 4, 6, 6, 8, 8, 8, 10, 12, 16, 12, 17, 16, 18, 21, 20, 23, 23, 25, 27, 29, 34, 34, 35, 38, 40, 43, 45, 48, 51, 53, 56, 59, 62, 65, 68},
+	{-1, 1, 1, 2, 4, 4, 4, 5, 6, 8, 8, 11, 11, 16, 16, 18, 16, 19, 21, 25, 25, 25, 34, 30, 32, 35, 37, 40, 42, 45, 48, 51, 54, 57, 60, 63, 66, 70, 74, 77, 81},
+}
+
+// Code QR-код: квадрат из темных и светлых модулей
+type Code struct {
+	version int
+	level   Level
+	mask    int
+	size    int
+	modules []bool // modules цвета модулей построчно, true - темный
+}
+
+// Encode кодирует data в QR-код минимальной подходящей версии с уровнем коррекции level
+func Encode(data []byte, level Level) (*Code, error) {
+	if level < L || level > H {
+		return nil, fmt.Errorf("unknown error correction level %d", level)
+	}
+	version := minVersion
+	for ; version <= maxVersion; version++ {
+		if dataBits(version, len(data)) <= numDataCodewords(version, level)*8 {
+			break
+		}
+	}
+	if version > maxVersion {
+		return nil, ErrTooLong
+	}
+
+	codewords := addECC(encodeData(data, version, level), version, level)
+
+	c := newCode(version, level)
+	isFunction := c.drawFunctionPatterns()
+	c.drawCodewords(codewords, isFunction)
+
+	// the mask with the lowest penalty makes the code easiest to scan
+	best, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		c.applyMask(mask, isFunction)
+		c.drawFormatBits(mask)
+		if p := c.penalty(); bestPenalty < 0 || p < bestPenalty {
+			best, bestPenalty = mask, p
+		}
+		// masks are xor, so applying it again reverts it
+		c.applyMask(mask, isFunction)
+	}
+	c.mask = best
+	c.applyMask(best, isFunction)
+	c.drawFormatBits(best)
+	return c, nil
+}
+
+// Size число модулей по стороне кода без отступа
+func (c *Code) Size() int {
+	return c.size
+}
+
+// Version версия кода от 1 до 40
+func (c *Code) Version() int {
+	return c.version
+}
+
+// Level уровень коррекции ошибок кода
+func (c *Code) Level() Level {
+	return c.level
+}
+
+// Dark сообщает, темный ли модуль в столбце x и строке y.
+// Для координат за пределами кода возвращает false.
+func (c *Code) Dark(x, y int) bool {
+	if x < 0 || y < 0 || x >= c.size || y >= c.size {
+		return false
+	}
+	return c.modules[y*c.size+x]
+}
+
+func newCode(version int, level Level) *Code {
+	size := version*4 + 17
+	return &Code{
+		version: version,
+		level:   level,
+		size:    size,
+		modules: make([]bool, size*size),
+	}
+}
+
+func (c *Code) set(x, y int, dark bool) {
+	c.modules[y*c.size+x] = dark
+}
+
+// dataBits число бит для сохранения n байт в байтовом режиме
+func dataBits(version, n int) int {
+	return 4 + charCountBits(version) + n*8
+}
+
+// charCountBits размер поля длины в байтовом режиме
+func charCountBits(version int) int {
+	if version <= 9 {
+		return 8
+	}
+	return 16
+}
+
+// numRawDataModules число модулей под данные и коррекцию, без служебных узоров
+func numRawDataModules(version int) int {
+	res := (16*version+128)*version + 64
+	if version >= 2 {
+		numAlign := version/7 + 2
+		res -= (25*numAlign-10)*numAlign - 55
+		if version >= 7 {
+			res -= 36
+		}
+	}
+	return res
+}
+
+// numDataCodewords число кодовых слов данных для версии и уровня
+func numDataCodewords(version int, level Level) int {
+	return numRawDataModules(version)/8 - eccPerBlock[level][version]*eccBlocks[level][version]
+}
+
+// encodeData кодирует данные в байтовом режиме и дополняет до емкости версии
+func encodeData(data []byte, version int, level Level) []byte {
+	capacity := numDataCodewords(version, level) * 8
+	var bb bitBuffer
+	bb.append(0x4, 4) // byte mode
+	bb.append(len(data), charCountBits(version))
+	for _, b := range data {
+		bb.append(int(b), 8)
+	}
+
+	terminator := capacity - bb.len()
+	if terminator > 4 {
+		terminator = 4
+	}
+	bb.append(0, terminator)
+	bb.append(0, (8-bb.len()%8)%8)
+	for pad := 0xEC; bb.len() < capacity; pad ^= 0xEC ^ 0x11 {
+		bb.append(pad, 8)
+	}
+	return bb.bytes()
+}
+
+// addECC разбивает данные на блоки, добавляет к ним коды коррекции и перемежает блоки
+func addECC(data []byte, version int, level Level) []byte {
+	numBlocks := eccBlocks[level][version]
+	blockECCLen := eccPerBlock[level][version]
+	rawCodewords := numRawDataModules(version) / 8
+	numShortBlocks := numBlocks - rawCodewords%numBlocks
+	shortBlockLen := rawCodewords / numBlocks
+
+	divisor := rsDivisor(blockECCLen)
+	blocks := make([][]byte, 0, numBlocks)
+	for i, k := 0, 0; i < numBlocks; i++ {
+		datLen := shortBlockLen - blockECCLen
+		if i >= numShortBlocks {
+			datLen++
+		}
+		dat := append([]byte(nil), data[k:k+datLen]...)
+		k += datLen
+		ecc := rsRemainder(dat, divisor)
+		if i < numShortBlocks {
+			// placeholder keeps blocks aligned, it is skipped while interleaving
+			dat = append(dat, 0)
+		}
+		blocks = append(blocks, append(dat, ecc...))
+	}
+
+	res := make([]byte, 0, rawCodewords)
+	for i := range blocks[0] {
+		for j, block := range blocks {
+			if i != shortBlockLen-blockECCLen || j >= numShortBlocks {
+				res = append(res, block[i])
+			}
+		}
+	}
+	return res
+}
+
+// alignmentPositions координаты центров выравнивающих узоров по одной оси
+func alignmentPositions(version int) []int {
+	if version == 1 {
+		return nil
+	}
+	numAlign := version/7 + 2
+	step := (version*4 + numAlign*2 + 1) / (numAlign*2 - 2) * 2
+	if version == 32 {
+		step = 26
+	}
+	res := make([]int, numAlign)
+	res[0] = 6
+	for i, pos := numAlign-1, version*4+17-7; i >= 1; i, pos = i-1, pos-step {
+		res[i] = pos
+	}
+	return res
+}
+
+// drawFunctionPatterns рисует поисковые, синхронизирующие и выравнивающие узоры,
+// резервирует место под служебную информацию и возвращает маску служебных модулей
+func (c *Code) drawFunctionPatterns() []bool {
+	isFunction := make([]bool, c.size*c.size)
+	set := func(x, y int, dark bool) {
+		c.set(x, y, dark)
+		isFunction[y*c.size+x] = true
+	}
+
+	for i := 0; i < c.size; i++ {
+		set(6, i, i%2 == 0)
+		set(i, 6, i%2 == 0)
+	}
+
+	// finder patterns together with their light separators
+	for _, center := range [][2]int{{3, 3}, {c.size - 4, 3}, {3, c.size - 4}} {
+		for dy := -4; dy <= 4; dy++ {
+			for dx := -4; dx <= 4; dx++ {
+				x, y := center[0]+dx, center[1]+dy
+				if x < 0 || y < 0 || x >= c.size || y >= c.size {
+					continue
+				}
+				dist := max(abs(dx), abs(dy))
+				set(x, y, dist != 2 && dist != 4)
+			}
+		}
+	}
+
+	positions := alignmentPositions(c.version)
+	last := len(positions) - 1
+	for i, cx := range positions {
+		for j, cy := range positions {
+			// these corners are occupied by finder patterns
+			if i == 0 && j == 0 || i == 0 && j == last || i == last && j == 0 {
+				continue
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					set(cx+dx, cy+dy, max(abs(dx), abs(dy)) != 1)
+				}
+			}
+		}
+	}
+
+	// format bits are redrawn for each mask, here their modules are only reserved
+	for i := 0; i < 9; i++ {
+		isFunction[8*c.size+i] = true
+		isFunction[i*c.size+8] = true
+	}
+	for i := 0; i < 8; i++ {
+		isFunction[8*c.size+c.size-1-i] = true
+		isFunction[(c.size-1-i)*c.size+8] = true
+	}
+
+	if c.version >= 7 {
+		bits := versionBits(c.version)
+		for i := 0; i < 18; i++ {
+			dark := bits>>i&1 == 1
+			a, b := c.size-11+i%3, i/3
+			set(a, b, dark)
+			set(b, a, dark)
+		}
+	}
+	return isFunction
+}
+
+// formatBits служебная информация об уровне коррекции и маске с кодом БЧХ
+func formatBits(level Level, mask int) int {
+	data := level.formatBits()<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = rem<<1 ^ (rem>>9)*0x537
+	}
+	return (data<<10 | rem) ^ 0x5412
+}
+
+// versionBits информация о версии с кодом БЧХ, есть в кодах с версии 7
+func versionBits(version int) int {
+	rem := version
+	for i := 0; i < 12; i++ {
+		rem = rem<<1 ^ (rem>>11)*0x1F25
+	}
+	return version<<12 | rem
+}
+
+// drawFormatBits рисует обе копии служебной информации о формате
+func (c *Code) drawFormatBits(mask int) {
+	bits := formatBits(c.level, mask)
+	bit := func(i int) bool {
+		return bits>>i&1 == 1
+	}
+
+	for i := 0; i <= 5; i++ {
+		c.set(8, i, bit(i))
+	}
+	c.set(8, 7, bit(6))
+	c.set(8, 8, bit(7))
+	c.set(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		c.set(14-i, 8, bit(i))
+	}
+
+	for i := 0; i < 8; i++ {
+		c.set(c.size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		c.set(8, c.size-15+i, bit(i))
+	}
+	// the dark module is always present
+	c.set(8, c.size-8, true)
+}
+
+// zigzag обходит модули данных в порядке размещения кодовых слов:
+// парами столбцов справа налево, попеременно снизу вверх и сверху вниз
+func (c *Code) zigzag(isFunction []bool, fn func(x, y int)) {
+	for right := c.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			// skip the vertical timing pattern
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vert := 0; vert < c.size; vert++ {
+			y := vert
+			if upward {
+				y = c.size - 1 - vert
+			}
+			for j := 0; j < 2; j++ {
+				x := right - j
+				if !isFunction[y*c.size+x] {
+					fn(x, y)
+				}
+			}
+		}
+	}
+}
+
+// drawCodewords размещает кодовые слова, оставшиеся модули остаются светлыми
+func (c *Code) drawCodewords(codewords []byte, isFunction []bool) {
+	i := 0
+	c.zigzag(isFunction, func(x, y int) {
+		if i < len(codewords)*8 {
+			c.set(x, y, codewords[i>>3]>>(7-i&7)&1 == 1)
+			i++
+		}
+	})
+}
+
+// maskFunc условие инверсии модуля для каждой из восьми масок
+var maskFunc = [8]func(x, y int) bool{
+	func(x, y int) bool { return (x+y)%2 == 0 },
+	func(x, y int) bool { return y%2 == 0 },
+	func(x, y int) bool { return x%3 == 0 },
+	func(x, y int) bool { return (x+y)%3 == 0 },
+	func(x, y int) bool { return (x/3+y/2)%2 == 0 },
+	func(x, y int) bool { return x*y%2+x*y%3 == 0 },
+	func(x, y int) bool { return (x*y%2+x*y%3)%2 == 0 },
+	func(x, y int) bool { return ((x+y)%2+x*y%3)%2 == 0 },
+}
+
+// applyMask инвертирует модули данных по маске
+func (c *Code) applyMask(mask int, isFunction []bool) {
+	fn := maskFunc[mask]
+	for y := 0; y < c.size; y++ {
+		for x := 0; x < c.size; x++ {
+			if !isFunction[y*c.size+x] && fn(x, y) {
+				c.modules[y*c.size+x] = !c.modules[y*c.size+x]
+			}
+		}
+	}
+}
+
+// Штрафные баллы для выбора маски
+const (
+	penaltyRun     = 3
+	penaltyBlock   = 3
+	penaltyFinder  = 40
+	penaltyBalance = 10
+)
+
+// penalty считает штрафные баллы кода по правилам стандарта:
+// длинные одноцветные ряды, одноцветные квадраты 2x2,
+// участки, похожие на поисковый узор, и перекос темных модулей
+func (c *Code) penalty() int {
+	res := 0
+	for i := 0; i < c.size; i++ {
+		row := func(j int) bool { return c.Dark(j, i) }
+		col := func(j int) bool { return c.Dark(i, j) }
+		res += c.linePenalty(row) + c.linePenalty(col)
+	}
+
+	dark := 0
+	for y := 0; y < c.size; y++ {
+		for x := 0; x < c.size; x++ {
+			v := c.Dark(x, y)
+			if v {
+				dark++
+			}
+			if x < c.size-1 && y < c.size-1 && v == c.Dark(x+1, y) && v == c.Dark(x, y+1) && v == c.Dark(x+1, y+1) {
+				res += penaltyBlock
+			}
+		}
+	}
+
+	total := c.size * c.size
+	k := (abs(dark*20-total*10)+total-1)/total - 1
+	if k > 0 {
+		res += k * penaltyBalance
+	}
+	return res
+}
+
+// finderLike участок 1:1:3:1:1 с четырьмя светлыми модулями с одной из сторон
+var finderLike = [2][]bool{
+	{true, false, true, true, true, false, true, false, false, false, false},
+	{false, false, false, false, true, false, true, true, true, false, true},
+}
+
+func (c *Code) linePenalty(at func(j int) bool) int {
+	res := 0
+	run := 1
+	for j := 1; j <= c.size; j++ {
+		if j < c.size && at(j) == at(j-1) {
+			run++
+			continue
+		}
+		if run >= 5 {
+			res += penaltyRun + run - 5
+		}
+		run = 1
+	}
+
+	for j := 0; j+len(finderLike[0]) <= c.size; j++ {
+		for _, pattern := range finderLike {
+			match := true
+			for k, dark := range pattern {
+				if at(j+k) != dark {
+					match = false
+					break
+				}
+			}
+			if match {
+				res += penaltyFinder
+			}
+		}
+	}
+	return res
+}
+
+// bitBuffer последовательность бит, старший бит первым
+type bitBuffer []bool
+
+func (bb *bitBuffer) append(val, n int) {
+	for i := n - 1; i >= 0; i-- {
+		*bb = append(*bb, val>>i&1 == 1)
+	}
+}
+
+func (bb bitBuffer) len() int {
+	return len(bb)
+}
+
+func (bb bitBuffer) bytes() []byte {
+	res := make([]byte, (len(bb)+7)/8)
+	for i, bit := range bb {
+		if bit {
+			res[i>>3] |= 1 << (7 - i&7)
+		}
+	}
+	return res
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
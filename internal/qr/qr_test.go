@@ -0,0 +1,232 @@
+package qr
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRSRemainder(t *testing.T) {
+	// "HELLO WORLD" 1-M from the standard walkthrough
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	assert.Equal(t, want, rsRemainder(data, rsDivisor(10)))
+}
+
+func TestFormatBits(t *testing.T) {
+	assert.Equal(t, 0b111011111000100, formatBits(L, 0))
+	assert.Equal(t, 0b101010000010010, formatBits(M, 0))
+	assert.Equal(t, 0b011010101011111, formatBits(Q, 0))
+	assert.Equal(t, 0b001011010001001, formatBits(H, 0))
+	assert.Equal(t, 0x07C94, versionBits(7))
+	assert.Equal(t, 0x28C69, versionBits(40))
+}
+
+func TestAlignmentPositions(t *testing.T) {
+	assert.Empty(t, alignmentPositions(1))
+	assert.Equal(t, []int{6, 18}, alignmentPositions(2))
+	assert.Equal(t, []int{6, 22, 38}, alignmentPositions(7))
+	assert.Equal(t, []int{6, 34, 60, 86, 112, 138}, alignmentPositions(32))
+	assert.Equal(t, []int{6, 30, 58, 86, 114, 142, 170}, alignmentPositions(40))
+}
+
+func TestEncode_Version(t *testing.T) {
+	tests := []struct {
+		name    string
+		n       int
+		level   Level
+		version int
+	}{
+		{name: "1-L full", n: 17, level: L, version: 1},
+		{name: "1-L overflow", n: 18, level: L, version: 2},
+		{name: "1-H full", n: 7, level: H, version: 1},
+		{name: "10-M full", n: 213, level: M, version: 10},
+		{name: "40-L full", n: 2953, level: L, version: 40},
+		{name: "40-H full", n: 1273, level: H, version: 40},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := Encode(bytes.Repeat([]byte("a"), tt.n), tt.level)
+			require.NoError(t, err)
+			assert.Equal(t, tt.version, c.Version())
+			assert.Equal(t, tt.version*4+17, c.Size())
+		})
+	}
+
+	_, err := Encode(bytes.Repeat([]byte("a"), 2954), L)
+	assert.ErrorIs(t, err, ErrTooLong)
+}
+
+// TestEncode_Decode читает код обратно: служебную информацию, кодовые слова и данные
+func TestEncode_Decode(t *testing.T) {
+	inputs := []string{
+		"http://localhost:8080/1C",
+		"https://practicum.yandex.ru/" + strings.Repeat("long-path/", 30),
+		"",
+	}
+	for _, input := range inputs {
+		for level := L; level <= H; level++ {
+			c, err := Encode([]byte(input), level)
+			require.NoError(t, err)
+			assert.Equal(t, []byte(input), decode(t, c), "level %s version %d", level, c.Version())
+		}
+	}
+}
+
+func decode(t *testing.T, c *Code) []byte {
+	t.Helper()
+
+	var format int
+	for i := 0; i <= 5; i++ {
+		format |= b2i(c.Dark(8, i)) << i
+	}
+	format |= b2i(c.Dark(8, 7))<<6 | b2i(c.Dark(8, 8))<<7 | b2i(c.Dark(7, 8))<<8
+	for i := 9; i < 15; i++ {
+		format |= b2i(c.Dark(14-i, 8)) << i
+	}
+	var second int
+	for i := 0; i < 8; i++ {
+		second |= b2i(c.Dark(c.size-1-i, 8)) << i
+	}
+	for i := 8; i < 15; i++ {
+		second |= b2i(c.Dark(8, c.size-15+i)) << i
+	}
+	require.Equal(t, format, second, "format copies differ")
+	require.True(t, c.Dark(8, c.size-8), "dark module")
+	require.Equal(t, formatBits(c.level, c.mask), format)
+
+	// function patterns are the same for any code of this version
+	isFunction := newCode(c.version, c.level).drawFunctionPatterns()
+	unmasked := *c
+	unmasked.modules = append([]bool(nil), c.modules...)
+	unmasked.applyMask(c.mask, isFunction)
+
+	var bb bitBuffer
+	unmasked.zigzag(isFunction, func(x, y int) {
+		bb = append(bb, unmasked.Dark(x, y))
+	})
+	raw := bb.bytes()[:numRawDataModules(c.version)/8]
+
+	// de-interleave blocks and verify error correction
+	numBlocks := eccBlocks[c.level][c.version]
+	blockECCLen := eccPerBlock[c.level][c.version]
+	numShortBlocks := numBlocks - len(raw)%numBlocks
+	shortDataLen := len(raw)/numBlocks - blockECCLen
+	blocks := make([][]byte, numBlocks)
+	k := 0
+	for i := 0; i <= shortDataLen; i++ {
+		for j := range blocks {
+			if i < shortDataLen || j >= numShortBlocks {
+				blocks[j] = append(blocks[j], raw[k])
+				k++
+			}
+		}
+	}
+	divisor := rsDivisor(blockECCLen)
+	var data []byte
+	for i := 0; i < blockECCLen; i++ {
+		for j := range blocks {
+			blocks[j] = append(blocks[j], raw[k])
+			k++
+		}
+	}
+	for _, block := range blocks {
+		dat, ecc := block[:len(block)-blockECCLen], block[len(block)-blockECCLen:]
+		require.Equal(t, rsRemainder(dat, divisor), ecc, "error correction codewords")
+		data = append(data, dat...)
+	}
+
+	bits := bitBuffer{}
+	for _, b := range data {
+		bits.append(int(b), 8)
+	}
+	read := func(n int) int {
+		v := 0
+		for i := 0; i < n; i++ {
+			v = v<<1 | b2i(bits[i])
+		}
+		bits = bits[n:]
+		return v
+	}
+	require.Equal(t, 0x4, read(4), "byte mode")
+	n := read(charCountBits(c.version))
+	res := make([]byte, n)
+	for i := range res {
+		res[i] = byte(read(8))
+	}
+	return res
+}
+
+func b2i(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func TestFunctionPatterns(t *testing.T) {
+	c, err := Encode([]byte("http://localhost:8080/1C"), M)
+	require.NoError(t, err)
+	for _, corner := range [][2]int{{0, 0}, {c.size - 7, 0}, {0, c.size - 7}} {
+		for i := 0; i < 7; i++ {
+			assert.True(t, c.Dark(corner[0]+i, corner[1]), "finder top edge")
+			assert.True(t, c.Dark(corner[0], corner[1]+i), "finder left edge")
+		}
+		assert.False(t, c.Dark(corner[0]+1, corner[1]+1), "finder inner ring")
+		assert.True(t, c.Dark(corner[0]+3, corner[1]+3), "finder center")
+	}
+	for i := 8; i < c.size-8; i++ {
+		assert.Equal(t, i%2 == 0, c.Dark(i, 6), "timing row")
+		assert.Equal(t, i%2 == 0, c.Dark(6, i), "timing column")
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for _, s := range []string{"L", "m", "Q", "h"} {
+		level, err := ParseLevel(s)
+		require.NoError(t, err)
+		assert.Equal(t, strings.ToUpper(s), level.String())
+	}
+	_, err := ParseLevel("X")
+	assert.Error(t, err)
+}
+
+func TestCode_PNG(t *testing.T) {
+	c, err := Encode([]byte("http://localhost:8080/1C"), M)
+	require.NoError(t, err)
+
+	b, err := c.PNG(256)
+	require.NoError(t, err)
+	img, err := png.Decode(bytes.NewReader(b))
+	require.NoError(t, err)
+	assert.Equal(t, 256, img.Bounds().Dx())
+	assert.Equal(t, 256, img.Bounds().Dy())
+
+	// 29 modules with quiet zone fit 8 pixels each, the rest is split around the code
+	scale := 256 / (c.Size() + 2*QuietZone)
+	offset := (256-scale*(c.Size()+2*QuietZone))/2 + QuietZone*scale
+	r, _, _, _ := img.At(offset, offset).RGBA()
+	assert.Zero(t, r, "top-left finder is dark")
+	r, _, _, _ = img.At(offset-1, offset-1).RGBA()
+	assert.NotZero(t, r, "quiet zone is light")
+
+	b, err = c.PNG(1)
+	require.NoError(t, err)
+	img, err = png.Decode(bytes.NewReader(b))
+	require.NoError(t, err)
+	assert.Equal(t, c.Size()+2*QuietZone, img.Bounds().Dx())
+}
+
+func TestCode_SVG(t *testing.T) {
+	c, err := Encode([]byte("http://localhost:8080/1C"), M)
+	require.NoError(t, err)
+
+	svg := string(c.SVG(200))
+	assert.True(t, strings.HasPrefix(svg, `<svg xmlns="http://www.w3.org/2000/svg" width="200" height="200" viewBox="0 0 33 33"`))
+	assert.Contains(t, svg, "M4,4h1v1h-1z")
+	assert.True(t, strings.HasSuffix(svg, "</svg>"))
+}
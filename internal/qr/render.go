@@ -0,0 +1,62 @@
+package qr
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+// QuietZone ширина светлого отступа вокруг кода в модулях
+const QuietZone = 4
+
+// PNG рисует код в квадратное изображение со стороной size пикселей.
+// Модули масштабируются целым числом пикселей, код центрируется.
+// Если size меньше кода с отступом, сторона увеличивается до одного пикселя на модуль.
+func (c *Code) PNG(size int) ([]byte, error) {
+	modules := c.size + 2*QuietZone
+	if size < modules {
+		size = modules
+	}
+	scale := size / modules
+	offset := (size-scale*modules)/2 + QuietZone*scale
+
+	img := image.NewPaletted(image.Rect(0, 0, size, size), color.Palette{color.White, color.Black})
+	for y := 0; y < c.size; y++ {
+		for x := 0; x < c.size; x++ {
+			if !c.Dark(x, y) {
+				continue
+			}
+			for dy := 0; dy < scale; dy++ {
+				for dx := 0; dx < scale; dx++ {
+					img.SetColorIndex(offset+x*scale+dx, offset+y*scale+dy, 1)
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("cannot encode png: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// SVG рисует код в векторное изображение со стороной size пикселей
+func (c *Code) SVG(size int) []byte {
+	modules := c.size + 2*QuietZone
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		size, size, modules, modules)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="#fff"/><path fill="#000" d="`, modules, modules)
+	for y := 0; y < c.size; y++ {
+		for x := 0; x < c.size; x++ {
+			if c.Dark(x, y) {
+				fmt.Fprintf(&buf, "M%d,%dh1v1h-1z", x+QuietZone, y+QuietZone)
+			}
+		}
+	}
+	buf.WriteString(`"/></svg>`)
+	return buf.Bytes()
+}
@@ -0,0 +1,43 @@
+package qr
+
+// rsDivisor порождающий многочлен кода Рида-Соломона степени degree над GF(2^8),
+// коэффициенты от старшего к младшему без старшей единицы
+func rsDivisor(degree int) []byte {
+	res := make([]byte, degree)
+	res[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range res {
+			res[j] = gfMul(res[j], root)
+			if j+1 < len(res) {
+				res[j] ^= res[j+1]
+			}
+		}
+		root = gfMul(root, 0x02)
+	}
+	return res
+}
+
+// rsRemainder остаток от деления data на порождающий многочлен - кодовые слова коррекции
+func rsRemainder(data, divisor []byte) []byte {
+	res := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ res[0]
+		copy(res, res[1:])
+		res[len(res)-1] = 0
+		for i, d := range divisor {
+			res[i] ^= gfMul(d, factor)
+		}
+	}
+	return res
+}
+
+// gfMul умножение в GF(2^8) по модулю x^8 + x^4 + x^3 + x^2 + 1
+func gfMul(x, y byte) byte {
+	z := 0
+	for i := 7; i >= 0; i-- {
+		z = z<<1 ^ (z>>7)*0x11D
+		z ^= int(y>>i&1) * int(x)
+	}
+	return byte(z)
+}
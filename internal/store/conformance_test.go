@@ -0,0 +1,63 @@
+package store_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/store"
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/store/storetest"
+)
+
+// runConformance прогоняет общий контракт для каждой стратегии генерации идентификаторов
+func runConformance(t *testing.T, factory func(t *testing.T, opts ...store.Option) store.AuthStore) {
+	for _, strategy := range []string{store.IDStrategyCounter, store.IDStrategyRandom, store.IDStrategyHashids} {
+		t.Run(strategy, func(t *testing.T) {
+			ids, err := store.NewIDGenerator(strategy, 0, "conformance")
+			require.NoError(t, err)
+			storetest.Run(t, func(t *testing.T) store.AuthStore {
+				return factory(t, store.WithIDGenerator(ids))
+			})
+		})
+	}
+}
+
+func TestInMemory_Conformance(t *testing.T) {
+	runConformance(t, func(t *testing.T, opts ...store.Option) store.AuthStore {
+		return store.NewInMemory(opts...)
+	})
+}
+
+func TestFileStore_Conformance(t *testing.T) {
+	runConformance(t, func(t *testing.T, opts ...store.Option) store.AuthStore {
+		s, err := store.NewFileStore(filepath.Join(t.TempDir(), "store"), opts...)
+		require.NoError(t, err)
+		return s
+	})
+}
+
+func TestSQLite_Conformance(t *testing.T) {
+	runConformance(t, func(t *testing.T, opts ...store.Option) store.AuthStore {
+		conn, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "store.db"))
+		require.NoError(t, err)
+		conn.SetMaxOpenConns(1)
+		s := store.NewSQLite(conn, opts...)
+		require.NoError(t, s.Bootstrap(context.Background()))
+		return s
+	})
+}
+
+func TestRDB_Conformance(t *testing.T) {
+	runConformance(t, func(t *testing.T, opts ...store.Option) store.AuthStore {
+		conn, err := sql.Open("postgres", store.PostgresDSN())
+		require.NoError(t, err)
+		s := store.NewRDB(conn, opts...)
+		require.NoError(t, s.Bootstrap(context.Background()))
+		return s
+	})
+}
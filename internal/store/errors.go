@@ -8,4 +8,5 @@ import (
 var (
 	ErrNotFound = errors.New("not found") // ErrNotFound пользователь или ссылки не найдены
 	ErrConflict = errors.New("conflict")  // ErrConflict конфликт обновления\создания записи
+	ErrIDTaken  = errors.New("id taken")  // ErrIDTaken идентификатор ссылки уже занят
 )
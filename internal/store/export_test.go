@@ -0,0 +1,6 @@
+package store
+
+// PostgresDSN строка подключения к тестовой БД, поднятой в TestMain
+func PostgresDSN() string {
+	return pgDSN
+}
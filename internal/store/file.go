@@ -1,13 +1,20 @@
 package store
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"encoding/binary"
 	"encoding/gob"
 	"errors"
 	"fmt"
+	"io"
+	"log"
 	"net/url"
 	"os"
+	"path/filepath"
 	"sync"
+	"time"
 
 	"github.com/gofrs/uuid"
 )
@@ -24,161 +31,488 @@ type gobStore struct {
 	UserHot map[string]map[string]*url.URL
 }
 
-// FileStore структура для файлового хранилища ссылок
+// journalOp тип операции, записанной в журнал
+type journalOp uint8
+
+// Операции журнала
+const (
+	opSave        journalOp = iota + 1 // opSave сохранение ссылок без пользователя
+	opSaveUser                         // opSaveUser сохранение ссылок пользователя
+	opDelete                           // opDelete удаление ссылок пользователя
+	opSnapshot                         // opSnapshot полный снимок состояния хранилища
+	opSaveLink                         // opSaveLink сохранение ссылки с параметрами
+	opPurge                            // opPurge окончательное удаление просроченных ссылок
+	opClicks                           // opClicks сохранение переходов по ссылкам
+	opConsume                          // opConsume списание перехода по ссылке с ограничением
+	opUpdate                           // opUpdate замена адреса ссылки пользователя
+	opMeta                             // opMeta замена описания ссылки пользователя
+	opRules                            // opRules замена правил перехода ссылки пользователя
+	opPurgeClicks                      // opPurgeClicks окончательное удаление переходов, совершенных раньше Time
+	opEdit                             // opEdit несколько изменений ссылки пользователя, применяемых вместе
+)
+
+// maxFrameSize наибольший размер кадра журнала, кадр с большей длиной считается поврежденным
+const maxFrameSize = 1 << 30
+
+// errTornFrame последний кадр журнала записан не полностью, например из-за сбоя во время записи
+var errTornFrame = errors.New("journal frame is cut short")
+
+// journalRecord запись журнала изменений файлового хранилища
+type journalRecord struct {
+	Op       journalOp
+	UID      string
+	IDs      []string
+	URLs     []string
+	Link     *linkRecord
+	Clicks   []Click
+	Time     time.Time // Time время изменения: создания ссылок или замены адреса
+	Snapshot *snapshot
+	Edits    []journalRecord // Edits изменения ссылки в записи opEdit
+}
+
+// linkRecord параметры ссылки, сохраненной через SaveLink
+type linkRecord struct {
+	UID          string
+	ExpiresAt    *time.Time
+	PasswordHash string
+	ClicksLeft   int
+	Redirect     RedirectMode
+	Passthrough  PassthroughMode
+	Template     bool
+	Rules        []Rule
+	Variants     []Variant
+	Title        string
+	Notes        string
+	Tags         []string
+}
+
+// revisionRecord прежний адрес ссылки в снимке состояния
+type revisionRecord struct {
+	URL        string
+	ReplacedAt time.Time
+}
+
+// snapshotURL ссылка в снимке состояния, включая удаленные
+type snapshotURL struct {
+	URL     string
+	Deleted bool
+}
+
+// snapshot снимок состояния Hot/UserHot в виде, пригодном для gob
+type snapshot struct {
+	Hot     map[string]snapshotURL
+	UserHot map[string]map[string]snapshotURL
+	Index   map[string]string // Index идентификаторы ссылок по адресу, в старых снимках отсутствует
+	Links   map[string]linkRecord
+	Clicks  []Click
+	History map[string][]revisionRecord
+	Created map[string]time.Time // Created время создания ссылок, в старых снимках отсутствует
+	Counts  map[string]int64     // Counts число переходов по ссылкам, в старых снимках отсутствует
+}
+
+// FileStore структура для файлового хранилища ссылок.
+//
+// Каждое изменение дописывается в конец файла отдельной записью журнала,
+// при старте журнал проигрывается заново и восстанавливает состояние в памяти.
+// Журнал периодически сжимается: файл переписывается снимком текущего состояния.
 type FileStore struct {
-	mu      sync.RWMutex
+	mu      sync.RWMutex // mu защищает состояние в памяти и дескриптор файла
+	wmu     sync.Mutex   // wmu упорядочивает запись в журнал
+	cmu     sync.Mutex   // cmu не дает запустить несколько сжатий одновременно
 	store   *gobStore
-	enc     *gob.Encoder
+	index   map[string]string // index идентификаторы неудаленных ссылок по их адресу
+	links   map[string]*Link  // links параметры ссылок, сохраненных через SaveLink
+	history map[string][]Revision
+	created map[string]time.Time // created время создания ссылок
+	counts  map[string]int64     // counts число переходов по ссылкам
+	clicks  []Click
+	ids     IDGenerator
+	path    string
 	persist *os.File
+
+	size          int64 // size размер журнала в байтах
+	sinceSnapshot int64 // sinceSnapshot сколько байт дописано после последнего снимка
 }
 
 // NewFileStore create new NewFileStore instance
-func NewFileStore(filepath string) (*FileStore, error) {
-	fd, err := os.OpenFile(filepath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+func NewFileStore(filepath string, opts ...Option) (*FileStore, error) {
+	o := newOptions(opts)
+	fd, err := os.OpenFile(filepath, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
 	if err != nil {
 		return nil, fmt.Errorf("cannot open file at path %s: %w", filepath, err)
 	}
 
-	gs := gobStore{
-		Hot:     make(map[string]*url.URL),
-		UserHot: make(map[string]map[string]*url.URL),
+	f := &FileStore{
+		store: &gobStore{
+			Hot:     make(map[string]*url.URL),
+			UserHot: make(map[string]map[string]*url.URL),
+		},
+		index:   make(map[string]string),
+		links:   make(map[string]*Link),
+		history: make(map[string][]Revision),
+		created: make(map[string]time.Time),
+		counts:  make(map[string]int64),
+		ids:     o.ids,
+		path:    filepath,
+		persist: fd,
 	}
 
-	dec := gob.NewDecoder(fd)
-	if err := dec.Decode(&gs); err != nil {
-		// truncate bad file for reuse
-		if err := fd.Truncate(0); err != nil {
+	offset, err := f.replay()
+	if errors.Is(err, errTornFrame) {
+		// only the final frame can be torn, drop it for reuse
+		if err := fd.Truncate(offset); err != nil {
+			_ = fd.Close()
 			return nil, fmt.Errorf("cannot truncate broken storage file: %w", err)
 		}
+	} else if err != nil {
+		// a broken frame in the middle would take all later records with it
+		_ = fd.Close()
+		return nil, fmt.Errorf("storage file %s is corrupted at offset %d: %w", filepath, offset, err)
 	}
+	f.size = offset
 
-	return &FileStore{
-		store:   &gs,
-		enc:     gob.NewEncoder(fd),
-		persist: fd,
-	}, nil
+	return f, nil
 }
 
 // Save сохраняем ссылку в файловом хранилище.
 func (f *FileStore) Save(_ context.Context, u *url.URL) (id string, err error) {
-	f.mu.Lock()
-	id = fmt.Sprintf("%x", len(f.store.Hot))
-	f.store.Hot[id] = u
-	f.mu.Unlock()
-	return id, f.flush()
+	f.wmu.Lock()
+	defer f.wmu.Unlock()
+
+	rec, ids, err := f.record(opSave, "", []*url.URL{u})
+	if err != nil {
+		return "", err
+	}
+	if len(rec.IDs) == 0 {
+		return ids[0], ErrConflict
+	}
+	if err := f.commit(rec); err != nil {
+		return "", err
+	}
+	return ids[0], nil
 }
 
 // SaveBatch сохраняем ссылки в файловом хранилище.
 func (f *FileStore) SaveBatch(_ context.Context, urls []*url.URL) (ids []string, err error) {
-	f.mu.Lock()
-	for _, u := range urls {
-		id := fmt.Sprintf("%x", len(f.store.Hot))
-		f.store.Hot[id] = u
-		ids = append(ids, id)
+	f.wmu.Lock()
+	defer f.wmu.Unlock()
+
+	rec, ids, err := f.record(opSave, "", urls)
+	if err != nil {
+		return nil, err
 	}
-	f.mu.Unlock()
-	if len(ids) != len(urls) {
-		return nil, errors.New("not all URLs have been saved")
+	if err := f.commit(rec); err != nil {
+		return nil, err
 	}
-	return ids, f.flush()
+	return ids, nil
 }
 
 // Load загружаем ссылки из файлового хранилища по идентификатору.
+// Переход по ссылке с ограничением списывается отдельной записью журнала.
 func (f *FileStore) Load(_ context.Context, id string) (u *url.URL, err error) {
 	f.mu.RLock()
-	u, ok := f.store.Hot[id]
+	link, limited := f.links[id]
+	if !limited || link.ClicksLeft == 0 {
+		defer f.mu.RUnlock()
+		u, ok := f.store.Hot[id]
+		if !ok {
+			return nil, ErrNotFound
+		}
+		return f.check(id, u)
+	}
 	f.mu.RUnlock()
+
+	f.wmu.Lock()
+	defer f.wmu.Unlock()
+	// state only changes under wmu, so it can be read without mu here
+	u, ok := f.store.Hot[id]
 	if !ok {
 		return nil, ErrNotFound
 	}
-	if u == nil {
-		return nil, ErrDeleted
+	if u, err = f.check(id, u); err != nil {
+		return nil, err
+	}
+	if link, ok := f.links[id]; !ok || link.ClicksLeft == 0 {
+		return u, nil
+	}
+	if err := f.commit(journalRecord{Op: opConsume, IDs: []string{id}}); err != nil {
+		return nil, err
 	}
 	return u, nil
 }
 
 // SaveUser сохраняем ссылку для пользователя
-func (f *FileStore) SaveUser(ctx context.Context, uid uuid.UUID, u *url.URL) (id string, err error) {
-	id, err = f.Save(ctx, u)
+func (f *FileStore) SaveUser(_ context.Context, uid uuid.UUID, u *url.URL) (id string, err error) {
+	f.wmu.Lock()
+	defer f.wmu.Unlock()
+
+	rec, ids, err := f.record(opSaveUser, uid.String(), []*url.URL{u})
 	if err != nil {
-		return "", fmt.Errorf("cannot save URL to shared store: %w", err)
+		return "", err
 	}
-	f.mu.Lock()
-	if _, ok := f.store.UserHot[uid.String()]; !ok {
-		f.store.UserHot[uid.String()] = make(map[string]*url.URL)
+	if len(rec.IDs) == 0 {
+		return ids[0], ErrConflict
 	}
-	f.store.UserHot[uid.String()][id] = u
-	f.mu.Unlock()
-	return id, f.flush()
+	if err := f.commit(rec); err != nil {
+		return "", err
+	}
+	return ids[0], nil
 }
 
 // SaveUserBatch сохраняем ссылки для пользователя
-func (f *FileStore) SaveUserBatch(ctx context.Context, uid uuid.UUID, urls []*url.URL) (ids []string, err error) {
-	ids, err = f.SaveBatch(ctx, urls)
+func (f *FileStore) SaveUserBatch(_ context.Context, uid uuid.UUID, urls []*url.URL) (ids []string, err error) {
+	f.wmu.Lock()
+	defer f.wmu.Unlock()
+
+	rec, ids, err := f.record(opSaveUser, uid.String(), urls)
 	if err != nil {
-		return nil, fmt.Errorf("cannot save URL to shared store: %w", err)
-	}
-	f.mu.Lock()
-	if _, ok := f.store.UserHot[uid.String()]; !ok {
-		f.store.UserHot[uid.String()] = make(map[string]*url.URL)
+		return nil, err
 	}
-	for i, id := range ids {
-		f.store.UserHot[uid.String()][id] = urls[i]
+	if err := f.commit(rec); err != nil {
+		return nil, err
 	}
-	f.mu.Unlock()
-	return ids, f.flush()
+	return ids, nil
 }
 
 // LoadUser загружаем ссылку для пользователя по ее идентификатору
-func (f *FileStore) LoadUser(ctx context.Context, uid uuid.UUID, id string) (u *url.URL, err error) {
-	urls, err := f.LoadUsers(ctx, uid)
-	if err != nil {
-		return nil, fmt.Errorf("cannot load user urls: %w", err)
-	}
-	u, ok := urls[id]
+func (f *FileStore) LoadUser(_ context.Context, uid uuid.UUID, id string) (u *url.URL, err error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	u, ok := f.store.UserHot[uid.String()][id]
 	if !ok {
 		return nil, ErrNotFound
 	}
-	if u == nil {
-		return nil, ErrDeleted
-	}
-	return u, nil
+	return f.check(id, u)
 }
 
 // LoadUsers загружаем ссылки для пользователя по их идентификаторам
 func (f *FileStore) LoadUsers(_ context.Context, uid uuid.UUID) (urls map[string]*url.URL, err error) {
 	f.mu.RLock()
-	urls, ok := f.store.UserHot[uid.String()]
-	f.mu.RUnlock()
-	if !ok {
-		return nil, ErrNotFound
-	}
+	defer f.mu.RUnlock()
 	res := make(map[string]*url.URL)
-	for k, v := range urls {
+	for k, v := range f.store.UserHot[uid.String()] {
 		if v != nil {
 			res[k] = v
 		}
 	}
+	if len(res) == 0 {
+		return nil, ErrNotFound
+	}
 	return res, nil
 }
 
 // DeleteUsers удаляем ссылки для пользователя.
 func (f *FileStore) DeleteUsers(_ context.Context, uid uuid.UUID, ids ...string) error {
-	userID := uid.String()
-	f.mu.Lock()
-	for _, id := range ids {
-		if _, ok := f.store.UserHot[userID]; ok {
-			f.store.Hot[id] = nil
-			f.store.UserHot[userID][id] = nil
+	f.wmu.Lock()
+	defer f.wmu.Unlock()
+
+	return f.commit(journalRecord{Op: opDelete, UID: uid.String(), IDs: ids})
+}
+
+// SaveLink сохраняем ссылку с параметрами
+func (f *FileStore) SaveLink(_ context.Context, link Link) (id string, err error) {
+	f.wmu.Lock()
+	defer f.wmu.Unlock()
+
+	// state only changes under wmu, so it can be read without mu here
+	taken := func(id string) (bool, error) {
+		_, ok := f.store.Hot[id]
+		return ok, nil
+	}
+	id, ids := scopedID(link, f.ids)
+	if id == "" {
+		id, err = nextID(ids, uint64(len(f.store.Hot)), taken)
+		if err != nil {
+			return "", err
 		}
+	} else if busy, _ := taken(id); busy {
+		return "", ErrIDTaken
+	}
+
+	rec := journalRecord{
+		Op:   opSaveLink,
+		IDs:  []string{id},
+		URLs: []string{link.URL.String()},
+		Link: newLinkRecord(&link),
+		Time: time.Now(),
+	}
+	if link.UserID != nil {
+		rec.UID = link.UserID.String()
+	}
+	if err := f.commit(rec); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// LoadLink загружаем ссылку с параметрами
+func (f *FileStore) LoadLink(_ context.Context, id string) (link *Link, err error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	u, ok := f.store.Hot[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if _, err := f.check(id, u); err != nil {
+		return nil, err
+	}
+	link = &Link{ID: id, URL: u}
+	if l, ok := f.links[id]; ok {
+		res := *l
+		link = &res
+	}
+	link.CreatedAt = f.created[id]
+	return link, nil
+}
+
+// UpdateUser заменяем адрес ссылки пользователя, сохраняя прежний в истории
+func (f *FileStore) UpdateUser(ctx context.Context, uid uuid.UUID, id string, u *url.URL) error {
+	return f.UpdateLink(ctx, uid, id, LinkUpdate{URL: u})
+}
+
+// SetMeta заменяем описание ссылки пользователя
+func (f *FileStore) SetMeta(ctx context.Context, uid uuid.UUID, id string, meta Meta) error {
+	return f.UpdateLink(ctx, uid, id, LinkUpdate{Meta: &meta})
+}
+
+// SetRules заменяем правила перехода ссылки пользователя
+func (f *FileStore) SetRules(ctx context.Context, uid uuid.UUID, id string, rules []Rule) error {
+	return f.UpdateLink(ctx, uid, id, LinkUpdate{Rules: &rules})
+}
+
+// UpdateLink изменяем ссылку пользователя. Несколько изменений пишутся в журнал одной записью.
+func (f *FileStore) UpdateLink(_ context.Context, uid uuid.UUID, id string, upd LinkUpdate) error {
+	f.wmu.Lock()
+	defer f.wmu.Unlock()
+
+	// state only changes under wmu, so it can be read without mu here
+	old, ok := f.store.UserHot[uid.String()][id]
+	if !ok {
+		return ErrNotFound
+	}
+	if _, err := f.check(id, old); err != nil {
+		return err
+	}
+
+	var edits []journalRecord
+	if upd.URL != nil {
+		edits = append(edits, journalRecord{Op: opUpdate, URLs: []string{upd.URL.String()}, Time: time.Now()})
+	}
+	if m := upd.Meta; m != nil {
+		edits = append(edits, journalRecord{Op: opMeta, Link: &linkRecord{Title: m.Title, Notes: m.Notes, Tags: m.Tags}})
+	}
+	if upd.Rules != nil {
+		edits = append(edits, journalRecord{Op: opRules, Link: &linkRecord{Rules: *upd.Rules}})
+	}
+	for i := range edits {
+		edits[i].UID = uid.String()
+		edits[i].IDs = []string{id}
+	}
+	switch len(edits) {
+	case 0:
+		return nil
+	case 1:
+		return f.commit(edits[0])
+	default:
+		return f.commit(journalRecord{Op: opEdit, UID: uid.String(), IDs: []string{id}, Edits: edits})
 	}
-	f.mu.Unlock()
-	return f.flush()
+}
+
+// LoadUserLinks загружаем ссылки пользователя с параметрами
+func (f *FileStore) LoadUserLinks(_ context.Context, uid uuid.UUID, filter LinkFilter) (links []Link, err error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	var found bool
+	for id, u := range f.store.UserHot[uid.String()] {
+		if u == nil {
+			continue
+		}
+		found = true
+		link := Link{ID: id, URL: u, UserID: &uid}
+		if l, ok := f.links[id]; ok {
+			link = *l
+		}
+		link.CreatedAt, link.Clicks = f.created[id], f.counts[id]
+		if filter.Match(&link) {
+			links = append(links, link)
+		}
+	}
+	if !found {
+		return nil, ErrNotFound
+	}
+	return filter.page(links), nil
+}
+
+// LoadHistory загружаем прежние адреса ссылки пользователя
+func (f *FileStore) LoadHistory(_ context.Context, uid uuid.UUID, id string) (revisions []Revision, err error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if _, ok := f.store.UserHot[uid.String()][id]; !ok {
+		return nil, ErrNotFound
+	}
+	return append([]Revision(nil), f.history[id]...), nil
+}
+
+// PurgeExpired окончательно удаляем ссылки, срок действия которых истек раньше before
+func (f *FileStore) PurgeExpired(_ context.Context, before time.Time) (n int64, err error) {
+	f.wmu.Lock()
+	defer f.wmu.Unlock()
+
+	rec := journalRecord{Op: opPurge}
+	for id, link := range f.links {
+		if link.Expired(before) {
+			rec.IDs = append(rec.IDs, id)
+		}
+	}
+	if len(rec.IDs) == 0 {
+		return 0, nil
+	}
+	if err := f.commit(rec); err != nil {
+		return 0, err
+	}
+	return int64(len(rec.IDs)), nil
+}
+
+// PurgeClicks окончательно удаляем переходы, совершенные раньше before
+func (f *FileStore) PurgeClicks(_ context.Context, before time.Time) (n int64, err error) {
+	f.wmu.Lock()
+	defer f.wmu.Unlock()
+
+	for _, c := range f.clicks {
+		if c.Time.Before(before) {
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	if err := f.commit(journalRecord{Op: opPurgeClicks, Time: before}); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// SaveClicks сохраняем переходы по ссылкам одной записью журнала
+func (f *FileStore) SaveClicks(_ context.Context, clicks []Click) error {
+	f.wmu.Lock()
+	defer f.wmu.Unlock()
+
+	return f.commit(journalRecord{Op: opClicks, Clicks: clicks})
+}
+
+// LoadClicks загружаем переходы по ссылке за период
+func (f *FileStore) LoadClicks(_ context.Context, id string, from, to time.Time) (clicks []Click, err error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return filterClicks(f.clicks, id, from, to), nil
 }
 
 // Close закрываем файловое хранилище
 func (f *FileStore) Close() error {
-	if err := f.flush(); err != nil {
+	f.wmu.Lock()
+	defer f.wmu.Unlock()
+
+	if err := f.persist.Sync(); err != nil {
 		return fmt.Errorf("cannot flush data to file: %w", err)
 	}
 	return f.persist.Close()
@@ -186,22 +520,597 @@ func (f *FileStore) Close() error {
 
 // Ping проверка работоспособности хранилища
 func (f *FileStore) Ping(_ context.Context) error {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
 	if f.persist.Fd() == ^(uintptr(0)) {
 		return errors.New("underlying file has been closed")
 	}
 	return nil
 }
 
-func (f *FileStore) flush() error {
-	return f.enc.Encode(f.store)
-}
-
 // Users статистика по пользователям
 func (f *FileStore) Users(_ context.Context) int {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
 	return len(f.store.UserHot)
 }
 
 // Urls статистика по ссылкам
 func (f *FileStore) Urls(_ context.Context) int {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
 	return len(f.store.Hot)
 }
+
+// check проверяет, что ссылка не удалена и не просрочена. Вызывается под блокировкой на чтение.
+func (f *FileStore) check(id string, u *url.URL) (*url.URL, error) {
+	if u == nil {
+		return nil, ErrDeleted
+	}
+	if link, ok := f.links[id]; ok && link.Expired(time.Now()) {
+		return nil, ErrExpired
+	}
+	return u, nil
+}
+
+// record готовит запись журнала для сохранения ссылок и возвращает идентификаторы
+// для всех переданных ссылок. Уже сохраненные ссылки в запись не попадают,
+// для них возвращаются существующие идентификаторы. Вызывается под блокировкой wmu.
+func (f *FileStore) record(op journalOp, uid string, urls []*url.URL) (rec journalRecord, ids []string, err error) {
+	rec = journalRecord{Op: op, UID: uid, Time: time.Now()}
+	batch := make(map[string]string)
+	pending := make(map[string]struct{})
+	taken := func(id string) (bool, error) {
+		_, saved := f.store.Hot[id]
+		_, busy := pending[id]
+		return saved || busy, nil
+	}
+	for _, u := range urls {
+		rawURL := u.String()
+		id, ok := f.index[rawURL]
+		if !ok {
+			id, ok = batch[rawURL]
+		}
+		if !ok {
+			id, err = nextID(f.ids, uint64(len(f.store.Hot)+len(rec.IDs)), taken)
+			if err != nil {
+				return rec, nil, err
+			}
+			batch[rawURL] = id
+			pending[id] = struct{}{}
+			rec.IDs = append(rec.IDs, id)
+			rec.URLs = append(rec.URLs, rawURL)
+		}
+		ids = append(ids, id)
+	}
+	return rec, ids, nil
+}
+
+// commit дописывает запись в журнал и применяет ее к состоянию в памяти.
+// Вызывается под блокировкой wmu, поэтому чтение не ждет записи на диск.
+func (f *FileStore) commit(rec journalRecord) error {
+	if len(rec.IDs) == 0 && len(rec.Clicks) == 0 && rec.Snapshot == nil && rec.Op != opPurgeClicks {
+		return nil
+	}
+	n, err := f.append(rec)
+	if err != nil {
+		return fmt.Errorf("cannot write journal record: %w", err)
+	}
+	f.size += n
+	f.sinceSnapshot += n
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.apply(rec)
+}
+
+// append записывает запись в конец журнала и возвращает размер записанного кадра
+func (f *FileStore) append(rec journalRecord) (int64, error) {
+	n, err := writeRecord(f.persist, rec)
+	if err == nil {
+		err = f.persist.Sync()
+	}
+	if err != nil {
+		// drop partially written frame so that it doesn't break the journal
+		_ = f.persist.Truncate(f.size)
+		return 0, err
+	}
+	return n, nil
+}
+
+// apply применяет запись журнала к состоянию в памяти
+func (f *FileStore) apply(rec journalRecord) error {
+	switch rec.Op {
+	case opSave, opSaveUser, opSaveLink:
+		if len(rec.IDs) != len(rec.URLs) {
+			return errors.New("not all URLs have been saved")
+		}
+		var userURLs map[string]*url.URL
+		if rec.UID != "" {
+			if _, ok := f.store.UserHot[rec.UID]; !ok {
+				f.store.UserHot[rec.UID] = make(map[string]*url.URL)
+			}
+			userURLs = f.store.UserHot[rec.UID]
+		}
+		for i, id := range rec.IDs {
+			u, err := url.Parse(rec.URLs[i])
+			if err != nil {
+				return fmt.Errorf("cannot parse URL: %w", err)
+			}
+			f.store.Hot[id] = u
+			// records written before creation time was journaled have zero Time
+			f.created[id] = rec.Time
+			// links with parameters are never deduplicated by address
+			if rec.Op != opSaveLink {
+				f.index[rec.URLs[i]] = id
+			}
+			if userURLs != nil {
+				userURLs[id] = u
+			}
+			if rec.Op == opSaveLink {
+				// records written before link parameters were journaled have no Link
+				lr := linkRecord{UID: rec.UID}
+				if rec.Link != nil {
+					lr = *rec.Link
+					lr.UID = rec.UID
+				}
+				f.links[id] = lr.link(id, u)
+			}
+		}
+	case opPurge:
+		purged := make(map[string]bool, len(rec.IDs))
+		for _, id := range rec.IDs {
+			purged[id] = true
+			if link, ok := f.links[id]; ok && link.UserID != nil {
+				delete(f.store.UserHot[link.UserID.String()], id)
+			}
+			delete(f.store.Hot, id)
+			delete(f.links, id)
+			delete(f.history, id)
+			delete(f.created, id)
+			delete(f.counts, id)
+		}
+		f.clicks = keepClicks(f.clicks, func(c Click) bool { return !purged[c.ID] })
+	case opPurgeClicks:
+		f.clicks = keepClicks(f.clicks, func(c Click) bool { return !c.Time.Before(rec.Time) })
+	case opClicks:
+		f.clicks = append(f.clicks, rec.Clicks...)
+		for _, c := range rec.Clicks {
+			f.counts[c.ID]++
+		}
+	case opUpdate:
+		if len(rec.IDs) != 1 || len(rec.URLs) != 1 {
+			return errors.New("update record must contain exactly one URL")
+		}
+		id := rec.IDs[0]
+		u, err := url.Parse(rec.URLs[0])
+		if err != nil {
+			return fmt.Errorf("cannot parse URL: %w", err)
+		}
+		userURLs := f.store.UserHot[rec.UID]
+		old := userURLs[id]
+		if old == nil {
+			return fmt.Errorf("cannot update missing link %s", id)
+		}
+		f.history[id] = append(f.history[id], Revision{URL: old, ReplacedAt: rec.Time})
+		// an edited link is no longer deduplicated by address
+		if f.index[old.String()] == id {
+			delete(f.index, old.String())
+		}
+		f.store.Hot[id] = u
+		userURLs[id] = u
+		if link, ok := f.links[id]; ok {
+			link.URL = u
+		}
+	case opMeta, opRules:
+		if len(rec.IDs) != 1 || rec.Link == nil {
+			return errors.New("meta record must contain exactly one link")
+		}
+		id := rec.IDs[0]
+		link, ok := f.links[id]
+		if !ok {
+			// plain links get their parameters on first description
+			u := f.store.UserHot[rec.UID][id]
+			if u == nil {
+				return fmt.Errorf("cannot describe missing link %s", id)
+			}
+			link = linkRecord{UID: rec.UID}.link(id, u)
+			f.links[id] = link
+		}
+		if rec.Op == opRules {
+			link.Rules = rec.Link.Rules
+		} else {
+			link.Meta = Meta{Title: rec.Link.Title, Notes: rec.Link.Notes, Tags: rec.Link.Tags}
+		}
+	case opEdit:
+		for _, e := range rec.Edits {
+			if err := f.apply(e); err != nil {
+				return err
+			}
+		}
+	case opConsume:
+		for _, id := range rec.IDs {
+			link, ok := f.links[id]
+			if !ok || link.ClicksLeft == 0 {
+				continue
+			}
+			link.ClicksLeft--
+			if link.ClicksLeft > 0 {
+				continue
+			}
+			// an exhausted link behaves like a deleted one
+			f.store.Hot[id] = nil
+			if link.UserID != nil {
+				if userURLs, ok := f.store.UserHot[link.UserID.String()]; ok {
+					userURLs[id] = nil
+				}
+			}
+		}
+	case opDelete:
+		userURLs := f.store.UserHot[rec.UID]
+		for _, id := range rec.IDs {
+			u, ok := userURLs[id]
+			if !ok || u == nil {
+				continue
+			}
+			if f.index[u.String()] == id {
+				delete(f.index, u.String())
+			}
+			f.store.Hot[id] = nil
+			userURLs[id] = nil
+		}
+	case opSnapshot:
+		gs, err := rec.Snapshot.restore()
+		if err != nil {
+			return fmt.Errorf("cannot restore snapshot: %w", err)
+		}
+		links := make(map[string]*Link, len(rec.Snapshot.Links))
+		for id, lr := range rec.Snapshot.Links {
+			links[id] = lr.link(id, gs.Hot[id])
+		}
+		history := make(map[string][]Revision, len(rec.Snapshot.History))
+		for id, records := range rec.Snapshot.History {
+			for _, r := range records {
+				u, err := url.Parse(r.URL)
+				if err != nil {
+					return fmt.Errorf("cannot parse URL: %w", err)
+				}
+				history[id] = append(history[id], Revision{URL: u, ReplacedAt: r.ReplacedAt})
+			}
+		}
+		f.store = gs
+		f.links = links
+		f.history = history
+		f.clicks = rec.Snapshot.Clicks
+		f.counts = rec.Snapshot.Counts
+		if f.counts == nil {
+			f.counts = make(map[string]int64)
+			for _, c := range f.clicks {
+				f.counts[c.ID]++
+			}
+		}
+		f.created = rec.Snapshot.Created
+		if f.created == nil {
+			f.created = make(map[string]time.Time, len(gs.Hot))
+		}
+		f.index = rec.Snapshot.Index
+		if f.index == nil {
+			f.index = make(map[string]string, len(gs.Hot))
+			for id, u := range gs.Hot {
+				if u != nil {
+					f.index[u.String()] = id
+				}
+			}
+		}
+	default:
+		return fmt.Errorf("unknown journal operation %d", rec.Op)
+	}
+	return nil
+}
+
+// replay проигрывает журнал с начала файла и возвращает смещение
+// после последней успешно примененной записи. Недописанный последний кадр дает errTornFrame.
+func (f *FileStore) replay() (offset int64, err error) {
+	if _, err := f.persist.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	r := bufio.NewReader(f.persist)
+	for {
+		rec, n, err := readRecord(r)
+		if errors.Is(err, io.EOF) {
+			return offset, nil
+		}
+		if err != nil {
+			return offset, err
+		}
+		if err := f.apply(rec); err != nil {
+			return offset, err
+		}
+		offset += n
+		f.sinceSnapshot += n
+		if rec.Op == opSnapshot {
+			f.sinceSnapshot = 0
+		}
+	}
+}
+
+// Compact сжимает журнал: записывает снимок текущего состояния и дописанные
+// после него записи во временный файл, который затем атомарно заменяет журнал.
+// Чтение во время сжатия не блокируется, запись блокируется только на время
+// копирования хвоста журнала и замены файла.
+func (f *FileStore) Compact() error {
+	f.cmu.Lock()
+	defer f.cmu.Unlock()
+
+	// пока удерживается wmu, состояние в памяти не меняется
+	f.wmu.Lock()
+	snap := newSnapshot(f.store, f.index, f.links, f.history, f.created, f.counts)
+	// clicks are only appended or replaced by a new slice, so the snapshot can share the backing array
+	snap.Clicks = f.clicks[:len(f.clicks):len(f.clicks)]
+	offset := f.size
+	f.wmu.Unlock()
+
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".compact-*")
+	if err != nil {
+		return fmt.Errorf("cannot create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	snapSize, err := writeRecord(tmp, journalRecord{Op: opSnapshot, Snapshot: snap})
+	if err != nil {
+		return fmt.Errorf("cannot write snapshot: %w", err)
+	}
+
+	f.wmu.Lock()
+	defer f.wmu.Unlock()
+
+	tail, err := io.Copy(tmp, io.NewSectionReader(f.persist, offset, f.size-offset))
+	if err != nil {
+		return fmt.Errorf("cannot copy journal tail: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return fmt.Errorf("cannot sync temporary file: %w", err)
+	}
+	// the new journal is opened before it replaces the old one: once renamed,
+	// the old descriptor points at an unlinked file and appends to it are lost
+	fd, err := openJournal(tmp.Name())
+	if err != nil {
+		return fmt.Errorf("cannot open compacted storage file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), f.path); err != nil {
+		fd.Close()
+		return fmt.Errorf("cannot replace storage file: %w", err)
+	}
+	f.mu.Lock()
+	old := f.persist
+	f.persist = fd
+	f.mu.Unlock()
+	old.Close()
+
+	f.size = snapSize + tail
+	f.sinceSnapshot = tail
+	if err := syncDir(filepath.Dir(f.path)); err != nil {
+		return fmt.Errorf("cannot sync storage directory: %w", err)
+	}
+	return nil
+}
+
+// openJournal открывает файл журнала для дописывания, в тестах подменяется
+var openJournal = func(name string) (*os.File, error) {
+	return os.OpenFile(name, os.O_RDWR|os.O_APPEND, 0666)
+}
+
+// RunCompaction раз в interval сжимает журнал, если после последнего снимка
+// в него было дописано не меньше threshold байт. Работает до отмены контекста.
+func (f *FileStore) RunCompaction(ctx context.Context, interval time.Duration, threshold int64) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			f.wmu.Lock()
+			grown := f.sinceSnapshot
+			f.wmu.Unlock()
+			if grown < threshold || grown == 0 {
+				continue
+			}
+			if err := f.Compact(); err != nil {
+				log.Printf("cannot compact storage file: %v\n", err)
+			}
+		}
+	}
+}
+
+// newSnapshot создает снимок состояния хранилища
+func newSnapshot(gs *gobStore, index map[string]string, links map[string]*Link, history map[string][]Revision,
+	created map[string]time.Time, counts map[string]int64) *snapshot {
+	snap := &snapshot{
+		Hot:     make(map[string]snapshotURL, len(gs.Hot)),
+		UserHot: make(map[string]map[string]snapshotURL, len(gs.UserHot)),
+		Index:   make(map[string]string, len(index)),
+		Links:   make(map[string]linkRecord, len(links)),
+		History: make(map[string][]revisionRecord, len(history)),
+		Created: make(map[string]time.Time, len(created)),
+		Counts:  make(map[string]int64, len(counts)),
+	}
+	for id, t := range created {
+		snap.Created[id] = t
+	}
+	for id, n := range counts {
+		snap.Counts[id] = n
+	}
+	for id, revisions := range history {
+		records := make([]revisionRecord, 0, len(revisions))
+		for _, r := range revisions {
+			records = append(records, revisionRecord{URL: r.URL.String(), ReplacedAt: r.ReplacedAt})
+		}
+		snap.History[id] = records
+	}
+	for rawURL, id := range index {
+		snap.Index[rawURL] = id
+	}
+	for id, link := range links {
+		snap.Links[id] = *newLinkRecord(link)
+	}
+	for id, u := range gs.Hot {
+		snap.Hot[id] = newSnapshotURL(u)
+	}
+	for uid, urls := range gs.UserHot {
+		userURLs := make(map[string]snapshotURL, len(urls))
+		for id, u := range urls {
+			userURLs[id] = newSnapshotURL(u)
+		}
+		snap.UserHot[uid] = userURLs
+	}
+	return snap
+}
+
+func newSnapshotURL(u *url.URL) snapshotURL {
+	if u == nil {
+		return snapshotURL{Deleted: true}
+	}
+	return snapshotURL{URL: u.String()}
+}
+
+func newLinkRecord(link *Link) *linkRecord {
+	lr := &linkRecord{
+		ExpiresAt:    link.ExpiresAt,
+		PasswordHash: link.PasswordHash,
+		ClicksLeft:   link.ClicksLeft,
+		Redirect:     link.Redirect,
+		Passthrough:  link.Passthrough,
+		Template:     link.Template,
+		Rules:        link.Rules,
+		Variants:     link.Variants,
+		Title:        link.Title,
+		Notes:        link.Notes,
+		Tags:         link.Tags,
+	}
+	if link.UserID != nil {
+		lr.UID = link.UserID.String()
+	}
+	return lr
+}
+
+// link восстанавливает параметры ссылки из записи
+func (lr linkRecord) link(id string, u *url.URL) *Link {
+	link := &Link{
+		ID:           id,
+		URL:          u,
+		ExpiresAt:    lr.ExpiresAt,
+		PasswordHash: lr.PasswordHash,
+		ClicksLeft:   lr.ClicksLeft,
+		Redirect:     lr.Redirect,
+		Passthrough:  lr.Passthrough,
+		Template:     lr.Template,
+		Rules:        lr.Rules,
+		Variants:     lr.Variants,
+		Meta:         Meta{Title: lr.Title, Notes: lr.Notes, Tags: lr.Tags},
+	}
+	if uid, err := uuid.FromString(lr.UID); err == nil {
+		link.UserID = &uid
+	}
+	return link
+}
+
+// restore восстанавливает состояние хранилища из снимка
+func (s *snapshot) restore() (*gobStore, error) {
+	gs := &gobStore{
+		Hot:     make(map[string]*url.URL, len(s.Hot)),
+		UserHot: make(map[string]map[string]*url.URL, len(s.UserHot)),
+	}
+	for id, su := range s.Hot {
+		u, err := su.parse()
+		if err != nil {
+			return nil, err
+		}
+		gs.Hot[id] = u
+	}
+	for uid, urls := range s.UserHot {
+		userURLs := make(map[string]*url.URL, len(urls))
+		for id, su := range urls {
+			// общая ссылка с Hot, как и при сохранении
+			if u := gs.Hot[id]; u != nil && !su.Deleted && u.String() == su.URL {
+				userURLs[id] = u
+				continue
+			}
+			u, err := su.parse()
+			if err != nil {
+				return nil, err
+			}
+			userURLs[id] = u
+		}
+		gs.UserHot[uid] = userURLs
+	}
+	return gs, nil
+}
+
+func (su snapshotURL) parse() (*url.URL, error) {
+	if su.Deleted {
+		return nil, nil
+	}
+	u, err := url.Parse(su.URL)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse URL: %w", err)
+	}
+	return u, nil
+}
+
+// writeRecord записывает кадр журнала: длину кадра и саму запись в формате gob
+func writeRecord(w io.Writer, rec journalRecord) (int64, error) {
+	var buf bytes.Buffer
+	buf.Write(make([]byte, 4))
+	if err := gob.NewEncoder(&buf).Encode(rec); err != nil {
+		return 0, fmt.Errorf("cannot encode record: %w", err)
+	}
+	frame := buf.Bytes()
+	if len(frame)-4 > maxFrameSize {
+		return 0, fmt.Errorf("record of %d bytes exceeds journal frame limit", len(frame)-4)
+	}
+	binary.BigEndian.PutUint32(frame, uint32(len(frame)-4))
+
+	n, err := w.Write(frame)
+	return int64(n), err
+}
+
+// readRecord читает из журнала очередной кадр и возвращает его размер в байтах.
+// В конце журнала возвращает io.EOF, для кадра, оборванного концом файла, - errTornFrame.
+func readRecord(r io.Reader) (rec journalRecord, n int64, err error) {
+	var size [4]byte
+	if _, err := io.ReadFull(r, size[:]); errors.Is(err, io.ErrUnexpectedEOF) {
+		return rec, 0, errTornFrame
+	} else if err != nil {
+		return rec, 0, err
+	}
+	length := binary.BigEndian.Uint32(size[:])
+	if length > maxFrameSize {
+		return rec, 0, fmt.Errorf("frame length %d exceeds limit", length)
+	}
+	frame := make([]byte, length)
+	if _, err := io.ReadFull(r, frame); errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return rec, 0, errTornFrame
+	} else if err != nil {
+		return rec, 0, err
+	}
+	if err := gob.NewDecoder(bytes.NewReader(frame)).Decode(&rec); err != nil {
+		// not wrapped: an io.EOF from gob must not pass for the end of the journal
+		return rec, 0, fmt.Errorf("cannot decode record: %v", err)
+	}
+	return rec, int64(len(size) + len(frame)), nil
+}
+
+// syncDir сбрасывает на диск содержимое каталога, чтобы переименование пережило сбой
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -2,18 +2,21 @@ package store
 
 import (
 	"context"
-	"encoding/gob"
+	"errors"
 	"fmt"
 	"net/url"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/gofrs/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestFileStore_Close(t *testing.T) {
-	store, _ := NewFileStore("./store")
+	store, _ := NewFileStore(filepath.Join(t.TempDir(), "store"))
 	t.Run("create file store", func(t *testing.T) {
 		err := store.Close()
 		assert.NoError(t, err)
@@ -52,7 +55,7 @@ func TestFileStore_Close(t *testing.T) {
 } */
 
 func TestFileStore_Load(t *testing.T) {
-	store, _ := NewFileStore("./store")
+	store, _ := NewFileStore(filepath.Join(t.TempDir(), "store"))
 	ctx := context.Background()
 	urlToStore, _ := url.Parse("https://practicum.yandex.ru/user")
 	idFromStore, _ := store.Save(ctx, urlToStore)
@@ -65,7 +68,7 @@ func TestFileStore_Load(t *testing.T) {
 }
 
 func TestFileStore_LoadUser(t *testing.T) {
-	store, _ := NewFileStore("./store")
+	store, _ := NewFileStore(filepath.Join(t.TempDir(), "store"))
 	ctx := context.Background()
 	urlToStore, _ := url.Parse("https://practicum.yandex.ru/user")
 	uuidToStore, _ := uuid.NewV4()
@@ -80,7 +83,6 @@ func TestFileStore_LoadUser(t *testing.T) {
 func TestFileStore_LoadUsers(t *testing.T) {
 	type fields struct {
 		store   *gobStore
-		enc     *gob.Encoder
 		persist *os.File
 	}
 	type args struct {
@@ -100,7 +102,6 @@ func TestFileStore_LoadUsers(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			f := &FileStore{
 				store:   tt.fields.store,
-				enc:     tt.fields.enc,
 				persist: tt.fields.persist,
 			}
 			gotUrls, err := f.LoadUsers(tt.args.in0, tt.args.uid)
@@ -143,7 +144,7 @@ func TestFileStore_Ping(t *testing.T) {
 }*/
 
 func TestFileStore_Save(t *testing.T) {
-	store, _ := NewFileStore("./store")
+	store, _ := NewFileStore(filepath.Join(t.TempDir(), "store"))
 	ctx := context.Background()
 	urlToStore, _ := url.Parse("https://practicum.yandex.ru/")
 	t.Run("save regular", func(t *testing.T) {
@@ -155,7 +156,7 @@ func TestFileStore_Save(t *testing.T) {
 }
 
 func TestFileStore_SaveBatch(t *testing.T) {
-	store, _ := NewFileStore("./store")
+	store, _ := NewFileStore(filepath.Join(t.TempDir(), "store"))
 	ctx := context.Background()
 	urlToStore, _ := url.Parse("https://practicum.yandex.ru/")
 	urls := make([]*url.URL, 1000)
@@ -169,7 +170,7 @@ func TestFileStore_SaveBatch(t *testing.T) {
 }
 
 func TestFileStore_SaveUser(t *testing.T) {
-	store, _ := NewFileStore("./store")
+	store, _ := NewFileStore(filepath.Join(t.TempDir(), "store"))
 	ctx := context.Background()
 	urlToStore, _ := url.Parse("https://practicum.yandex.ru/user")
 	uuidToStore, _ := uuid.NewV4()
@@ -183,7 +184,7 @@ func TestFileStore_SaveUser(t *testing.T) {
 }
 
 func TestFileStore_SaveUserBatch(t *testing.T) {
-	store, _ := NewFileStore("./store")
+	store, _ := NewFileStore(filepath.Join(t.TempDir(), "store"))
 	ctx := context.Background()
 	urlToStore, _ := url.Parse("https://practicum.yandex.ru/user")
 	uuidToStore, _ := uuid.NewV4()
@@ -225,13 +226,13 @@ func TestNewFileStore(t *testing.T) {
 
 func TestFileStore_Urls(t *testing.T) {
 	ctx := context.Background()
-	store, _ := NewFileStore("./store")
+	store, _ := NewFileStore(filepath.Join(t.TempDir(), "store"))
 	urls := make([]*url.URL, 10)
 	urlToStore, _ := url.Parse("https://practicum.yandex.ru/")
 	uuidToStore := uuid.Must(uuid.NewV4())
 	ids := make([]string, 10)
 	for i := 0; i < 10; i++ {
-		urls[i] = urlToStore
+		urls[i], _ = urlToStore.Parse(fmt.Sprint(i))
 		ids[i] = fmt.Sprintf("%d", i)
 	}
 	_, _ = store.SaveUserBatch(ctx, uuidToStore, urls)
@@ -245,7 +246,7 @@ func TestFileStore_Urls(t *testing.T) {
 
 func TestFileStore_Users(t *testing.T) {
 	ctx := context.Background()
-	store, _ := NewFileStore("./store")
+	store, _ := NewFileStore(filepath.Join(t.TempDir(), "store"))
 	urls := make([]*url.URL, 10)
 	urlToStore, _ := url.Parse("https://practicum.yandex.ru/")
 	uuidToStore := uuid.Must(uuid.NewV4())
@@ -262,3 +263,314 @@ func TestFileStore_Users(t *testing.T) {
 		assert.Equal(t, 1, count)
 	})
 }
+
+func TestFileStore_Replay(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "store")
+	uuidToStore := uuid.Must(uuid.NewV4())
+	urlToStore, _ := url.Parse("https://practicum.yandex.ru/")
+	userURL, _ := url.Parse("https://practicum.yandex.ru/user")
+	deletedURL, _ := url.Parse("https://practicum.yandex.ru/deleted")
+
+	store, err := NewFileStore(path)
+	assert.NoError(t, err)
+	id, _ := store.Save(ctx, urlToStore)
+	userID, _ := store.SaveUser(ctx, uuidToStore, userURL)
+	deletedIDs, _ := store.SaveUserBatch(ctx, uuidToStore, []*url.URL{deletedURL})
+	assert.NoError(t, store.DeleteUsers(ctx, uuidToStore, deletedIDs...))
+	assert.NoError(t, store.Close())
+
+	t.Run("restore after restart", func(t *testing.T) {
+		restored, err := NewFileStore(path)
+		assert.NoError(t, err)
+		defer restored.Close()
+		assert.Equal(t, store.store, restored.store)
+
+		u, err := restored.Load(ctx, id)
+		assert.NoError(t, err)
+		assert.Equal(t, urlToStore, u)
+		u, err = restored.LoadUser(ctx, uuidToStore, userID)
+		assert.NoError(t, err)
+		assert.Equal(t, userURL, u)
+		_, err = restored.Load(ctx, deletedIDs[0])
+		assert.ErrorIs(t, err, ErrDeleted)
+
+		conflictID, err := restored.Save(ctx, urlToStore)
+		assert.ErrorIs(t, err, ErrConflict)
+		assert.Equal(t, id, conflictID)
+
+		nextID, err := restored.Save(ctx, deletedURL)
+		assert.NoError(t, err)
+		assert.Equal(t, "3", nextID)
+	})
+	t.Run("truncate broken tail", func(t *testing.T) {
+		fd, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0666)
+		assert.NoError(t, err)
+		_, _ = fd.Write([]byte{0, 0, 1})
+		_ = fd.Close()
+
+		restored, err := NewFileStore(path)
+		assert.NoError(t, err)
+		defer restored.Close()
+		assert.Equal(t, 4, restored.Urls(ctx))
+	})
+}
+
+func TestFileStore_ReplayCorrupted(t *testing.T) {
+	ctx := context.Background()
+	u, _ := url.Parse("https://practicum.yandex.ru/")
+
+	// journal returns a copy of a journal with three records and the offset of the second one
+	journal := func(t *testing.T) (path string, second int64) {
+		path = filepath.Join(t.TempDir(), "store")
+		store, err := NewFileStore(path)
+		require.NoError(t, err)
+		_, err = store.Save(ctx, u)
+		require.NoError(t, err)
+		second = store.size
+		for _, raw := range []string{"https://ya.ru/", "https://go.dev/"} {
+			u, _ := url.Parse(raw)
+			_, err = store.Save(ctx, u)
+			require.NoError(t, err)
+		}
+		require.NoError(t, store.Close())
+		return path, second
+	}
+	corrupt := func(t *testing.T, path string, offset int64, data []byte) int64 {
+		fd, err := os.OpenFile(path, os.O_WRONLY, 0666)
+		require.NoError(t, err)
+		defer fd.Close()
+		_, err = fd.WriteAt(data, offset)
+		require.NoError(t, err)
+		info, err := fd.Stat()
+		require.NoError(t, err)
+		return info.Size()
+	}
+
+	t.Run("broken middle frame", func(t *testing.T) {
+		path, second := journal(t)
+		size := corrupt(t, path, second+4, []byte{0xff, 0xff, 0xff, 0xff})
+
+		_, err := NewFileStore(path)
+		assert.Error(t, err)
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, size, info.Size(), "later records are kept")
+	})
+	t.Run("oversized frame", func(t *testing.T) {
+		path, second := journal(t)
+		corrupt(t, path, second, []byte{0x7f, 0xff, 0xff, 0xff})
+
+		_, err := NewFileStore(path)
+		assert.Error(t, err)
+	})
+	t.Run("torn last frame", func(t *testing.T) {
+		path, _ := journal(t)
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		require.NoError(t, os.Truncate(path, info.Size()-3))
+
+		restored, err := NewFileStore(path)
+		require.NoError(t, err)
+		defer restored.Close()
+		assert.Equal(t, 2, restored.Urls(ctx))
+	})
+}
+
+func TestFileStore_Compact(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "store")
+	uuidToStore := uuid.Must(uuid.NewV4())
+
+	store, err := NewFileStore(path)
+	assert.NoError(t, err)
+	var ids []string
+	for i := 0; i < 100; i++ {
+		urlToStore, _ := url.Parse(fmt.Sprintf("https://practicum.yandex.ru/%d", i))
+		id, err := store.SaveUser(ctx, uuidToStore, urlToStore)
+		assert.NoError(t, err)
+		ids = append(ids, id)
+	}
+	assert.NoError(t, store.DeleteUsers(ctx, uuidToStore, ids[:50]...))
+	before, _ := os.Stat(path)
+
+	t.Run("compact journal", func(t *testing.T) {
+		err := store.Compact()
+		assert.NoError(t, err)
+		after, _ := os.Stat(path)
+		assert.Less(t, after.Size(), before.Size())
+		assert.Zero(t, store.sinceSnapshot)
+	})
+	t.Run("append after compaction", func(t *testing.T) {
+		urlToStore, _ := url.Parse("https://practicum.yandex.ru/after")
+		id, err := store.SaveUser(ctx, uuidToStore, urlToStore)
+		assert.NoError(t, err)
+		assert.NoError(t, store.Close())
+
+		restored, err := NewFileStore(path)
+		assert.NoError(t, err)
+		defer restored.Close()
+		assert.Equal(t, store.store, restored.store)
+
+		u, err := restored.Load(ctx, id)
+		assert.NoError(t, err)
+		assert.Equal(t, urlToStore, u)
+		_, err = restored.Load(ctx, ids[0])
+		assert.ErrorIs(t, err, ErrDeleted)
+	})
+}
+
+func TestFileStore_CompactReopenFailure(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "store")
+	uuidToStore := uuid.Must(uuid.NewV4())
+
+	store, err := NewFileStore(path)
+	require.NoError(t, err)
+	before, _ := url.Parse("https://practicum.yandex.ru/before")
+	beforeID, err := store.SaveUser(ctx, uuidToStore, before)
+	require.NoError(t, err)
+
+	open := openJournal
+	openJournal = func(string) (*os.File, error) { return nil, errors.New("too many open files") }
+	err = store.Compact()
+	openJournal = open
+	assert.Error(t, err)
+
+	after, _ := url.Parse("https://practicum.yandex.ru/after")
+	afterID, err := store.SaveUser(ctx, uuidToStore, after)
+	require.NoError(t, err)
+	require.NoError(t, store.Close())
+
+	restored, err := NewFileStore(path)
+	require.NoError(t, err)
+	defer restored.Close()
+	for id, want := range map[string]*url.URL{beforeID: before, afterID: after} {
+		u, err := restored.Load(ctx, id)
+		assert.NoError(t, err)
+		assert.Equal(t, want, u)
+	}
+}
+
+func TestFileStore_SaveLink(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "store")
+	uuidToStore := uuid.Must(uuid.NewV4())
+	urlToStore, _ := url.Parse("https://practicum.yandex.ru/")
+
+	store, err := NewFileStore(path)
+	require.NoError(t, err)
+	id, err := store.Save(ctx, urlToStore)
+	require.NoError(t, err)
+	_, err = store.SaveLink(ctx, Link{ID: "spring-sale", URL: urlToStore, UserID: &uuidToStore, PasswordHash: "hash"})
+	require.NoError(t, err)
+	otherURL, _ := url.Parse("https://practicum.yandex.ru/learn/")
+	editedID, err := store.SaveUser(ctx, uuidToStore, otherURL)
+	require.NoError(t, err)
+	// both changes go into a single journal record
+	learn := Meta{Title: "Learn", Tags: []string{"go"}}
+	require.NoError(t, store.UpdateLink(ctx, uuidToStore, editedID, LinkUpdate{URL: urlToStore, Meta: &learn}))
+	_, err = store.SaveLink(ctx, Link{ID: "invite", URL: urlToStore, ClicksLeft: 2})
+	require.NoError(t, err)
+	_, err = store.Load(ctx, "invite")
+	require.NoError(t, err)
+	_, err = store.SaveLink(ctx, Link{ID: "reset", URL: urlToStore, UserID: &uuidToStore, ClicksLeft: 1})
+	require.NoError(t, err)
+	_, err = store.Load(ctx, "reset")
+	require.NoError(t, err)
+	past := time.Now().Add(-time.Minute)
+	longAgo := time.Now().Add(-48 * time.Hour)
+	_, err = store.SaveLink(ctx, Link{ID: "last-week", URL: urlToStore, UserID: &uuidToStore, ExpiresAt: &past})
+	require.NoError(t, err)
+	_, err = store.SaveLink(ctx, Link{ID: "last-month", URL: urlToStore, ExpiresAt: &longAgo})
+	require.NoError(t, err)
+	n, err := store.PurgeExpired(ctx, time.Now().Add(-24*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	click := Click{Time: time.Now().UTC(), ID: "spring-sale", Referrer: "https://ya.ru/", UserAgent: "curl/8.0", IPHash: "abc"}
+	stale := Click{Time: longAgo, ID: "spring-sale"}
+	require.NoError(t, store.SaveClicks(ctx, []Click{stale, click}))
+	n, err = store.PurgeClicks(ctx, time.Now().Add(-24*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	byClicks := LinkFilter{Order: OrderClicks, Desc: true}
+	before, err := store.LoadUserLinks(ctx, uuidToStore, byClicks)
+	require.NoError(t, err)
+	require.NoError(t, store.Close())
+
+	check := func(t *testing.T, restored *FileStore) {
+		u, err := restored.LoadUser(ctx, uuidToStore, "spring-sale")
+		require.NoError(t, err)
+		assert.Equal(t, urlToStore.String(), u.String())
+
+		// the alias does not take over deduplication of the address
+		conflictID, err := restored.Save(ctx, urlToStore)
+		assert.ErrorIs(t, err, ErrConflict)
+		assert.Equal(t, id, conflictID)
+
+		_, err = restored.SaveLink(ctx, Link{ID: "spring-sale", URL: urlToStore})
+		assert.ErrorIs(t, err, ErrIDTaken)
+
+		link, err := restored.LoadLink(ctx, "spring-sale")
+		require.NoError(t, err)
+		assert.Equal(t, "hash", link.PasswordHash)
+
+		u, err = restored.Load(ctx, editedID)
+		require.NoError(t, err)
+		assert.Equal(t, urlToStore.String(), u.String())
+		history, err := restored.LoadHistory(ctx, uuidToStore, editedID)
+		require.NoError(t, err)
+		require.Len(t, history, 1)
+		assert.Equal(t, otherURL.String(), history[0].URL.String())
+		links, err := restored.LoadUserLinks(ctx, uuidToStore, LinkFilter{Tag: "go"})
+		require.NoError(t, err)
+		require.Len(t, links, 1)
+		assert.Equal(t, editedID, links[0].ID)
+		assert.Equal(t, "Learn", links[0].Title)
+		assert.Equal(t, urlToStore.String(), links[0].URL.String())
+
+		// creation times and click counters survive restarts
+		links, err = restored.LoadUserLinks(ctx, uuidToStore, byClicks)
+		require.NoError(t, err)
+		require.Len(t, links, len(before))
+		assert.Equal(t, "spring-sale", links[0].ID)
+		assert.Equal(t, int64(2), links[0].Clicks, "purged clicks are still counted")
+		for i := range links {
+			assert.Equal(t, before[i].ID, links[i].ID)
+			assert.True(t, before[i].CreatedAt.Equal(links[i].CreatedAt))
+		}
+
+		// the previous address no longer resolves to the edited link
+		otherID, _ := restored.Save(ctx, otherURL)
+		assert.NotEqual(t, editedID, otherID)
+
+		link, err = restored.LoadLink(ctx, "invite")
+		require.NoError(t, err)
+		assert.Equal(t, 1, link.ClicksLeft)
+		_, err = restored.LoadUser(ctx, uuidToStore, "reset")
+		assert.ErrorIs(t, err, ErrDeleted)
+
+		_, err = restored.LoadUser(ctx, uuidToStore, "last-week")
+		assert.ErrorIs(t, err, ErrExpired)
+		_, err = restored.Load(ctx, "last-month")
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		require.Len(t, restored.clicks, 1)
+		assert.True(t, click.Time.Equal(restored.clicks[0].Time))
+		assert.Equal(t, click.Referrer, restored.clicks[0].Referrer)
+	}
+
+	t.Run("replay", func(t *testing.T) {
+		restored, err := NewFileStore(path)
+		require.NoError(t, err)
+		defer restored.Close()
+		check(t, restored)
+		require.NoError(t, restored.Compact())
+	})
+	t.Run("snapshot", func(t *testing.T) {
+		restored, err := NewFileStore(path)
+		require.NoError(t, err)
+		defer restored.Close()
+		check(t, restored)
+	})
+}
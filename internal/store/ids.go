@@ -0,0 +1,217 @@
+package store
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// Стратегии генерации коротких идентификаторов
+const (
+	IDStrategyCounter = "counter" // IDStrategyCounter счетчик в base62
+	IDStrategyRandom  = "random"  // IDStrategyRandom случайный идентификатор фиксированной длины
+	IDStrategyHashids = "hashids" // IDStrategyHashids обфусцированный счетчик в стиле hashids
+)
+
+// defaultRandomIDLength длина случайного идентификатора, если она не задана
+const defaultRandomIDLength = 8
+
+// maxIDAttempts сколько раз хранилище пробует подобрать незанятый идентификатор
+const maxIDAttempts = 10
+
+// ErrIDCollision не удалось подобрать незанятый идентификатор
+var ErrIDCollision = errors.New("cannot generate unique id")
+
+// base62Alphabet символы для коротких идентификаторов
+const base62Alphabet = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+// IDGenerator генерирует короткие идентификаторы ссылок.
+// seq порядковый номер ссылки в хранилище, генератор может его не использовать.
+// Хранилище само проверяет, что идентификатор не занят, и при коллизии
+// повторяет генерацию со следующим номером.
+type IDGenerator interface {
+	Generate(seq uint64) (string, error)
+}
+
+// IDGeneratorFunc адаптер, позволяющий использовать функцию как IDGenerator
+type IDGeneratorFunc func(seq uint64) (string, error)
+
+// Generate вызывает fn(seq)
+func (fn IDGeneratorFunc) Generate(seq uint64) (string, error) {
+	return fn(seq)
+}
+
+// NewIDGenerator создает генератор по названию стратегии.
+// length минимальная длина идентификатора, для случайной стратегии точная длина.
+// salt используется для перемешивания алфавита в стратегии hashids.
+func NewIDGenerator(strategy string, length int, salt string) (IDGenerator, error) {
+	if length < 0 {
+		return nil, fmt.Errorf("bad id length %d", length)
+	}
+	switch strategy {
+	case "", IDStrategyCounter:
+		return CounterGenerator{Length: length}, nil
+	case IDStrategyRandom:
+		if length == 0 {
+			length = defaultRandomIDLength
+		}
+		return RandomGenerator{Length: length}, nil
+	case IDStrategyHashids:
+		return NewHashidsGenerator(salt, length), nil
+	default:
+		return nil, fmt.Errorf("unknown id strategy %q", strategy)
+	}
+}
+
+// CounterGenerator кодирует порядковый номер в base62
+type CounterGenerator struct {
+	Length int // Length минимальная длина, короткие идентификаторы дополняются нулями слева
+}
+
+// Generate возвращает seq в base62
+func (g CounterGenerator) Generate(seq uint64) (string, error) {
+	id := encodeBase(seq, []byte(base62Alphabet))
+	for len(id) < g.Length {
+		id = append([]byte{base62Alphabet[0]}, id...)
+	}
+	return string(id), nil
+}
+
+// RandomGenerator генерирует криптографически случайные идентификаторы
+type RandomGenerator struct {
+	Length int // Length длина идентификатора
+}
+
+// Generate возвращает случайный идентификатор, seq не используется
+func (g RandomGenerator) Generate(uint64) (string, error) {
+	// bytes above the largest multiple of alphabet size are dropped to keep distribution uniform
+	const limit = 256 - 256%len(base62Alphabet)
+
+	id := make([]byte, 0, g.Length)
+	buf := make([]byte, g.Length)
+	for len(id) < g.Length {
+		if _, err := rand.Read(buf); err != nil {
+			return "", fmt.Errorf("cannot read random bytes: %w", err)
+		}
+		for _, b := range buf {
+			if int(b) >= limit || len(id) == g.Length {
+				continue
+			}
+			id = append(id, base62Alphabet[int(b)%len(base62Alphabet)])
+		}
+	}
+	return string(id), nil
+}
+
+// HashidsGenerator кодирует порядковый номер в перемешанном солью алфавите, как hashids.
+// Идентификаторы однозначно соответствуют номерам, но не выдают их порядок.
+type HashidsGenerator struct {
+	alphabet []byte // alphabet символы для кодирования номера
+	guards   []byte // guards разделители, отделяющие дополнение до минимальной длины
+	salt     []byte
+	length   int
+}
+
+// NewHashidsGenerator создает генератор с солью salt и минимальной длиной length
+func NewHashidsGenerator(salt string, length int) *HashidsGenerator {
+	alphabet := consistentShuffle([]byte(base62Alphabet), []byte(salt))
+	guardCount := len(alphabet) / 12
+	return &HashidsGenerator{
+		alphabet: alphabet[guardCount:],
+		guards:   alphabet[:guardCount],
+		salt:     []byte(salt),
+		length:   length,
+	}
+}
+
+// Generate возвращает обфусцированный seq.
+// Код состоит из символа-лотереи, задающего порядок алфавита, и номера в этом алфавите.
+// Короткий код дополняется слева наполнителем и разделителем, которого нет в самом коде.
+func (g *HashidsGenerator) Generate(seq uint64) (string, error) {
+	lottery := g.alphabet[seq%uint64(len(g.alphabet))]
+	alphabet := consistentShuffle(g.alphabet, append([]byte{lottery}, g.salt...))
+	body := append([]byte{lottery}, encodeBase(seq, alphabet)...)
+	if len(body) >= g.length {
+		return string(body), nil
+	}
+
+	filler := consistentShuffle(g.alphabet, body)
+	id := make([]byte, 0, g.length)
+	for i := 0; len(id) < g.length-len(body)-1; i++ {
+		id = append(id, filler[i%len(filler)])
+	}
+	id = append(id, g.guards[(seq+uint64(lottery))%uint64(len(g.guards))])
+	return string(append(id, body...)), nil
+}
+
+// encodeBase записывает n в системе счисления с цифрами alphabet
+func encodeBase(n uint64, alphabet []byte) []byte {
+	base := uint64(len(alphabet))
+	var res []byte
+	for {
+		res = append([]byte{alphabet[n%base]}, res...)
+		n /= base
+		if n == 0 {
+			return res
+		}
+	}
+}
+
+// consistentShuffle детерминированно перемешивает алфавит солью, как в hashids
+func consistentShuffle(alphabet, salt []byte) []byte {
+	res := append([]byte(nil), alphabet...)
+	if len(salt) == 0 {
+		return res
+	}
+	for i, v, p := len(res)-1, 0, 0; i > 0; i, v = i-1, v+1 {
+		v %= len(salt)
+		n := int(salt[v])
+		p += n
+		j := (n + v + p) % i
+		res[i], res[j] = res[j], res[i]
+	}
+	return res
+}
+
+// nextID подбирает незанятый идентификатор начиная с номера seq
+func nextID(gen IDGenerator, seq uint64, taken func(id string) (bool, error)) (string, error) {
+	for i := uint64(0); i < maxIDAttempts; i++ {
+		id, err := gen.Generate(seq + i)
+		if err != nil {
+			return "", err
+		}
+		busy, err := taken(id)
+		if err != nil {
+			return "", err
+		}
+		if !busy {
+			return id, nil
+		}
+	}
+	return "", ErrIDCollision
+}
+
+// Option настройка хранилища
+type Option func(*options)
+
+// options общие настройки хранилищ
+type options struct {
+	ids IDGenerator
+}
+
+// WithIDGenerator задает генератор коротких идентификаторов
+func WithIDGenerator(gen IDGenerator) Option {
+	return func(o *options) {
+		o.ids = gen
+	}
+}
+
+func newOptions(opts []Option) options {
+	o := options{
+		ids: CounterGenerator{},
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
@@ -0,0 +1,137 @@
+package store
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCounterGenerator_Generate(t *testing.T) {
+	tests := []struct {
+		name   string
+		length int
+		seq    uint64
+		want   string
+	}{
+		{name: "zero", seq: 0, want: "0"},
+		{name: "single digit", seq: 61, want: "Z"},
+		{name: "two digits", seq: 62, want: "10"},
+		{name: "padded", length: 4, seq: 62, want: "0010"},
+		{name: "longer than length", length: 1, seq: 3844, want: "100"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := CounterGenerator{Length: tt.length}.Generate(tt.seq)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, id)
+		})
+	}
+}
+
+func TestRandomGenerator_Generate(t *testing.T) {
+	gen := RandomGenerator{Length: 10}
+	seen := make(map[string]struct{})
+	for i := 0; i < 1000; i++ {
+		id, err := gen.Generate(0)
+		require.NoError(t, err)
+		require.Len(t, id, 10)
+		for _, c := range id {
+			require.True(t, strings.ContainsRune(base62Alphabet, c), "unexpected char %q", c)
+		}
+		seen[id] = struct{}{}
+	}
+	assert.Len(t, seen, 1000)
+}
+
+func TestHashidsGenerator_Generate(t *testing.T) {
+	for _, length := range []int{0, 4, 8} {
+		gen := NewHashidsGenerator("salt", length)
+		seen := make(map[string]uint64)
+		for seq := uint64(0); seq < 20000; seq++ {
+			id, err := gen.Generate(seq)
+			require.NoError(t, err)
+			require.GreaterOrEqual(t, len(id), length)
+			prev, ok := seen[id]
+			require.False(t, ok, "length %d: %d and %d have the same id %s", length, prev, seq, id)
+			seen[id] = seq
+		}
+	}
+
+	t.Run("deterministic", func(t *testing.T) {
+		a, _ := NewHashidsGenerator("salt", 6).Generate(42)
+		b, _ := NewHashidsGenerator("salt", 6).Generate(42)
+		assert.Equal(t, a, b)
+	})
+	t.Run("salt changes ids", func(t *testing.T) {
+		a, _ := NewHashidsGenerator("salt", 6).Generate(42)
+		b, _ := NewHashidsGenerator("pepper", 6).Generate(42)
+		assert.NotEqual(t, a, b)
+	})
+	t.Run("not sequential", func(t *testing.T) {
+		a, _ := NewHashidsGenerator("salt", 0).Generate(100)
+		b, _ := NewHashidsGenerator("salt", 0).Generate(101)
+		assert.NotEqual(t, a[1:], b[1:])
+	})
+}
+
+func TestNewIDGenerator(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy string
+		length   int
+		want     IDGenerator
+		wantErr  bool
+	}{
+		{name: "default", strategy: "", want: CounterGenerator{}},
+		{name: "counter", strategy: IDStrategyCounter, length: 3, want: CounterGenerator{Length: 3}},
+		{name: "random default length", strategy: IDStrategyRandom, want: RandomGenerator{Length: defaultRandomIDLength}},
+		{name: "random", strategy: IDStrategyRandom, length: 12, want: RandomGenerator{Length: 12}},
+		{name: "hashids", strategy: IDStrategyHashids, length: 6, want: NewHashidsGenerator("salt", 6)},
+		{name: "unknown", strategy: "uuid", wantErr: true},
+		{name: "negative length", strategy: IDStrategyCounter, length: -1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gen, err := NewIDGenerator(tt.strategy, tt.length, "salt")
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, gen)
+		})
+	}
+}
+
+func TestInMemory_IDCollision(t *testing.T) {
+	ctx := context.Background()
+	u1, _ := url.Parse("https://practicum.yandex.ru/1")
+	u2, _ := url.Parse("https://practicum.yandex.ru/2")
+
+	t.Run("retry with next seq", func(t *testing.T) {
+		// every seq collides with the previous one
+		gen := IDGeneratorFunc(func(seq uint64) (string, error) {
+			return CounterGenerator{}.Generate(seq / 2)
+		})
+		store := NewInMemory(WithIDGenerator(gen))
+		id1, err := store.Save(ctx, u1)
+		require.NoError(t, err)
+		id2, err := store.Save(ctx, u2)
+		require.NoError(t, err)
+		assert.NotEqual(t, id1, id2)
+	})
+	t.Run("give up", func(t *testing.T) {
+		gen := IDGeneratorFunc(func(uint64) (string, error) {
+			return "same", nil
+		})
+		store := NewInMemory(WithIDGenerator(gen))
+		_, err := store.Save(ctx, u1)
+		require.NoError(t, err)
+		_, err = store.Save(ctx, u2)
+		assert.ErrorIs(t, err, ErrIDCollision)
+	})
+}
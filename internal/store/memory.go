@@ -3,9 +3,9 @@ package store
 import (
 	"context"
 	"errors"
-	"fmt"
 	"net/url"
 	"sync"
+	"time"
 
 	"github.com/gofrs/uuid"
 )
@@ -18,135 +18,364 @@ type InMemory struct {
 	mu        sync.RWMutex
 	store     map[string]*url.URL
 	userStore map[string]map[string]*url.URL
+	index     map[string]string     // index идентификаторы неудаленных ссылок по их адресу
+	links     map[string]*Link      // links параметры ссылок, сохраненных через SaveLink
+	history   map[string][]Revision // history прежние адреса ссылок
+	created   map[string]time.Time  // created время создания ссылок
+	counts    map[string]int64      // counts число переходов по ссылкам
+	clicks    []Click
+	ids       IDGenerator
 }
 
 // NewInMemory create new InMemory instance
-func NewInMemory() *InMemory {
+func NewInMemory(opts ...Option) *InMemory {
+	o := newOptions(opts)
 	return &InMemory{
 		mu:        sync.RWMutex{},
 		store:     make(map[string]*url.URL),
 		userStore: make(map[string]map[string]*url.URL),
+		index:     make(map[string]string),
+		links:     make(map[string]*Link),
+		history:   make(map[string][]Revision),
+		created:   make(map[string]time.Time),
+		counts:    make(map[string]int64),
+		ids:       o.ids,
 	}
 }
 
 // Save сохранить ссылку
 func (m *InMemory) Save(_ context.Context, u *url.URL) (id string, err error) {
 	m.mu.Lock()
-	id = fmt.Sprintf("%x", len(m.store))
-	m.store[id] = u
-	m.mu.Unlock()
+	defer m.mu.Unlock()
+	id, created, err := m.save(u)
+	if err != nil {
+		return "", err
+	}
+	if !created {
+		return id, ErrConflict
+	}
 	return id, nil
 }
 
 // SaveBatch сохранить ссылки
 func (m *InMemory) SaveBatch(_ context.Context, urls []*url.URL) (ids []string, err error) {
 	m.mu.Lock()
+	defer m.mu.Unlock()
 	for _, u := range urls {
-		id := fmt.Sprintf("%x", len(m.store))
-		m.store[id] = u
+		id, _, err := m.save(u)
+		if err != nil {
+			return nil, err
+		}
 		ids = append(ids, id)
 	}
-	m.mu.Unlock()
 	if len(ids) != len(urls) {
 		return nil, errors.New("not all URLs have been saved")
 	}
 	return
 }
 
-// Load загрузить ссылку по идентификатору
+// Load загрузить ссылку по идентификатору, списав переход у ссылки с ограничением
 func (m *InMemory) Load(_ context.Context, id string) (u *url.URL, err error) {
 	m.mu.RLock()
-	u, ok := m.store[id]
+	link, limited := m.links[id]
+	if !limited || link.ClicksLeft == 0 {
+		defer m.mu.RUnlock()
+		u, ok := m.store[id]
+		if !ok {
+			return nil, ErrNotFound
+		}
+		return m.check(id, u)
+	}
 	m.mu.RUnlock()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// the link could have been exhausted, deleted or purged while the lock was released
+	u, ok := m.store[id]
 	if !ok {
 		return nil, ErrNotFound
 	}
-	if u == nil {
-		return nil, ErrDeleted
+	if u, err = m.check(id, u); err != nil {
+		return nil, err
+	}
+	if link, ok := m.links[id]; ok && link.ClicksLeft > 0 {
+		link.ClicksLeft--
+		if link.ClicksLeft == 0 {
+			m.store[id] = nil
+			if link.UserID != nil {
+				m.userURLs(*link.UserID)[id] = nil
+			}
+		}
 	}
 	return u, nil
 }
 
 // SaveUser сохранить ссылку для указанного пользователя
-func (m *InMemory) SaveUser(ctx context.Context, uid uuid.UUID, u *url.URL) (id string, err error) {
-	id, err = m.Save(ctx, u)
+func (m *InMemory) SaveUser(_ context.Context, uid uuid.UUID, u *url.URL) (id string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id, created, err := m.save(u)
 	if err != nil {
-		return "", fmt.Errorf("cannot save URL to shared store: %w", err)
+		return "", err
 	}
-	m.mu.Lock()
-	if _, ok := m.userStore[uid.String()]; !ok {
-		m.userStore[uid.String()] = make(map[string]*url.URL)
+	if !created {
+		return id, ErrConflict
 	}
-	m.userStore[uid.String()][id] = u
-	m.mu.Unlock()
+	m.userURLs(uid)[id] = u
 	return id, nil
 }
 
 // SaveUserBatch сохранить ссылки для указанного пользователя
-func (m *InMemory) SaveUserBatch(ctx context.Context, uid uuid.UUID, urls []*url.URL) (ids []string, err error) {
-	ids, err = m.SaveBatch(ctx, urls)
-	if err != nil {
-		return nil, fmt.Errorf("cannot save URLs to shared store: %w", err)
-	}
+func (m *InMemory) SaveUserBatch(_ context.Context, uid uuid.UUID, urls []*url.URL) (ids []string, err error) {
 	m.mu.Lock()
-	if _, ok := m.userStore[uid.String()]; !ok {
-		m.userStore[uid.String()] = make(map[string]*url.URL)
+	defer m.mu.Unlock()
+	for _, u := range urls {
+		id, created, err := m.save(u)
+		if err != nil {
+			return nil, err
+		}
+		if created {
+			m.userURLs(uid)[id] = u
+		}
+		ids = append(ids, id)
 	}
-	for i, id := range ids {
-		m.userStore[uid.String()][id] = urls[i]
+	if len(ids) != len(urls) {
+		return nil, errors.New("not all URLs have been saved")
 	}
-	m.mu.Unlock()
 	return ids, nil
 }
 
 // LoadUser загрузить ссылку для указанного пользователя
-func (m *InMemory) LoadUser(ctx context.Context, uid uuid.UUID, id string) (u *url.URL, err error) {
-	urls, err := m.LoadUsers(ctx, uid)
-	if err != nil {
-		return nil, fmt.Errorf("cannot load user urls: %w", err)
-	}
-	u, ok := urls[id]
+func (m *InMemory) LoadUser(_ context.Context, uid uuid.UUID, id string) (u *url.URL, err error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	u, ok := m.userStore[uid.String()][id]
 	if !ok {
 		return nil, ErrNotFound
 	}
-	if u == nil {
-		return nil, ErrDeleted
-	}
-	return u, nil
+	return m.check(id, u)
 }
 
 // LoadUsers загрузить ссылки для указанного пользователя
 func (m *InMemory) LoadUsers(_ context.Context, uid uuid.UUID) (urls map[string]*url.URL, err error) {
 	m.mu.RLock()
-	urls, ok := m.userStore[uid.String()]
-	m.mu.RUnlock()
-	if !ok {
-		return nil, ErrNotFound
-	}
+	defer m.mu.RUnlock()
 	// filter out deleted URLs
 	res := make(map[string]*url.URL)
-	for k, v := range urls {
+	for k, v := range m.userStore[uid.String()] {
 		if v != nil {
 			res[k] = v
 		}
 	}
+	if len(res) == 0 {
+		return nil, ErrNotFound
+	}
 	return res, nil
 }
 
 // DeleteUsers удалить ссылки для указанного пользователя по их идентификаторам
 func (m *InMemory) DeleteUsers(_ context.Context, uid uuid.UUID, ids ...string) error {
-	userID := uid.String()
 	m.mu.Lock()
+	userURLs := m.userStore[uid.String()]
 	for _, id := range ids {
-		if _, ok := m.userStore[userID]; ok {
-			m.store[id] = nil
-			m.userStore[userID][id] = nil
+		u, ok := userURLs[id]
+		if !ok || u == nil {
+			continue
 		}
+		if m.index[u.String()] == id {
+			delete(m.index, u.String())
+		}
+		m.store[id] = nil
+		userURLs[id] = nil
 	}
 	m.mu.Unlock()
 	return nil
 }
 
+// SaveLink сохранить ссылку с параметрами
+func (m *InMemory) SaveLink(_ context.Context, link Link) (id string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id, err = m.linkID(link)
+	if err != nil {
+		return "", err
+	}
+	m.store[id] = link.URL
+	if link.UserID != nil {
+		m.userURLs(*link.UserID)[id] = link.URL
+	}
+	link.ID = id
+	m.links[id] = &link
+	m.created[id] = time.Now()
+	return id, nil
+}
+
+// LoadLink загрузить ссылку с параметрами
+func (m *InMemory) LoadLink(_ context.Context, id string) (link *Link, err error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	u, ok := m.store[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if _, err := m.check(id, u); err != nil {
+		return nil, err
+	}
+	link = &Link{ID: id, URL: u}
+	if l, ok := m.links[id]; ok {
+		res := *l
+		link = &res
+	}
+	link.CreatedAt = m.created[id]
+	return link, nil
+}
+
+// UpdateUser заменить адрес ссылки пользователя, сохранив прежний в истории
+func (m *InMemory) UpdateUser(ctx context.Context, uid uuid.UUID, id string, u *url.URL) error {
+	return m.UpdateLink(ctx, uid, id, LinkUpdate{URL: u})
+}
+
+// SetMeta заменить описание ссылки пользователя
+func (m *InMemory) SetMeta(ctx context.Context, uid uuid.UUID, id string, meta Meta) error {
+	return m.UpdateLink(ctx, uid, id, LinkUpdate{Meta: &meta})
+}
+
+// SetRules заменить правила перехода ссылки пользователя
+func (m *InMemory) SetRules(ctx context.Context, uid uuid.UUID, id string, rules []Rule) error {
+	return m.UpdateLink(ctx, uid, id, LinkUpdate{Rules: &rules})
+}
+
+// UpdateLink изменить ссылку пользователя под одной блокировкой
+func (m *InMemory) UpdateLink(_ context.Context, uid uuid.UUID, id string, upd LinkUpdate) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	old, ok := m.userStore[uid.String()][id]
+	if !ok {
+		return ErrNotFound
+	}
+	if _, err := m.check(id, old); err != nil {
+		return err
+	}
+
+	link, ok := m.links[id]
+	if !ok && (upd.Meta != nil || upd.Rules != nil) {
+		// plain links get their parameters on first description
+		link = &Link{ID: id, URL: old, UserID: &uid}
+		m.links[id] = link
+	}
+	if u := upd.URL; u != nil {
+		m.history[id] = append(m.history[id], Revision{URL: old, ReplacedAt: time.Now()})
+		if m.index[old.String()] == id {
+			delete(m.index, old.String())
+		}
+		m.store[id] = u
+		m.userStore[uid.String()][id] = u
+		if link != nil {
+			link.URL = u
+		}
+	}
+	if upd.Meta != nil {
+		meta := *upd.Meta
+		meta.Tags = append([]string(nil), meta.Tags...)
+		link.Meta = meta
+	}
+	if upd.Rules != nil {
+		link.Rules = append([]Rule(nil), *upd.Rules...)
+	}
+	return nil
+}
+
+// LoadUserLinks загрузить ссылки пользователя с параметрами
+func (m *InMemory) LoadUserLinks(_ context.Context, uid uuid.UUID, filter LinkFilter) (links []Link, err error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	userURLs := m.userStore[uid.String()]
+	var found bool
+	for id, u := range userURLs {
+		if u == nil {
+			continue
+		}
+		found = true
+		link := Link{ID: id, URL: u, UserID: &uid}
+		if l, ok := m.links[id]; ok {
+			link = *l
+		}
+		link.CreatedAt, link.Clicks = m.created[id], m.counts[id]
+		if filter.Match(&link) {
+			links = append(links, link)
+		}
+	}
+	if !found {
+		return nil, ErrNotFound
+	}
+	return filter.page(links), nil
+}
+
+// LoadHistory загрузить прежние адреса ссылки пользователя
+func (m *InMemory) LoadHistory(_ context.Context, uid uuid.UUID, id string) (revisions []Revision, err error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if _, ok := m.userStore[uid.String()][id]; !ok {
+		return nil, ErrNotFound
+	}
+	return append([]Revision(nil), m.history[id]...), nil
+}
+
+// PurgeExpired окончательно удалить ссылки, срок действия которых истек раньше before
+func (m *InMemory) PurgeExpired(_ context.Context, before time.Time) (n int64, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	purged := make(map[string]bool)
+	for id, link := range m.links {
+		if !link.Expired(before) {
+			continue
+		}
+		purged[id] = true
+		delete(m.store, id)
+		delete(m.links, id)
+		delete(m.history, id)
+		delete(m.created, id)
+		delete(m.counts, id)
+		if link.UserID != nil {
+			delete(m.userStore[link.UserID.String()], id)
+		}
+		n++
+	}
+	if n > 0 {
+		m.clicks = keepClicks(m.clicks, func(c Click) bool { return !purged[c.ID] })
+	}
+	return n, nil
+}
+
+// PurgeClicks окончательно удалить переходы, совершенные раньше before
+func (m *InMemory) PurgeClicks(_ context.Context, before time.Time) (n int64, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	kept := keepClicks(m.clicks, func(c Click) bool { return !c.Time.Before(before) })
+	n = int64(len(m.clicks) - len(kept))
+	m.clicks = kept
+	return n, nil
+}
+
+// SaveClicks сохранить переходы по ссылкам
+func (m *InMemory) SaveClicks(_ context.Context, clicks []Click) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.clicks = append(m.clicks, clicks...)
+	for _, c := range clicks {
+		m.counts[c.ID]++
+	}
+	return nil
+}
+
+// LoadClicks загрузить переходы по ссылке за период
+func (m *InMemory) LoadClicks(_ context.Context, id string, from, to time.Time) (clicks []Click, err error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return filterClicks(m.clicks, id, from, to), nil
+}
+
 // Close закрыть хранилище
 func (m *InMemory) Close() error {
 	return nil
@@ -159,10 +388,67 @@ func (m *InMemory) Ping(_ context.Context) error {
 
 // Users статистика по пользователям
 func (m *InMemory) Users(_ context.Context) int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	return len(m.userStore)
 }
 
 // Urls статистика по ссылкам
 func (m *InMemory) Urls(_ context.Context) int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	return len(m.store)
 }
+
+// save сохраняет ссылку, если ее еще нет среди неудаленных, и возвращает ее идентификатор.
+// Вызывается под блокировкой на запись.
+func (m *InMemory) save(u *url.URL) (id string, created bool, err error) {
+	if id, ok := m.index[u.String()]; ok {
+		return id, false, nil
+	}
+	id, err = m.linkID(Link{})
+	if err != nil {
+		return "", false, err
+	}
+	m.store[id] = u
+	m.index[u.String()] = id
+	m.created[id] = time.Now()
+	return id, true, nil
+}
+
+// check проверяет, что ссылка не удалена и не просрочена. Вызывается под блокировкой на чтение.
+func (m *InMemory) check(id string, u *url.URL) (*url.URL, error) {
+	if u == nil {
+		return nil, ErrDeleted
+	}
+	if link, ok := m.links[id]; ok && link.Expired(time.Now()) {
+		return nil, ErrExpired
+	}
+	return u, nil
+}
+
+// linkID возвращает заданный идентификатор ссылки link на ее домене, если он свободен, или генерирует новый.
+// Вызывается под блокировкой на запись.
+func (m *InMemory) linkID(link Link) (string, error) {
+	taken := func(id string) (bool, error) {
+		_, ok := m.store[id]
+		return ok, nil
+	}
+	id, ids := scopedID(link, m.ids)
+	if id == "" {
+		return nextID(ids, uint64(len(m.store)), taken)
+	}
+	if busy, _ := taken(id); busy {
+		return "", ErrIDTaken
+	}
+	return id, nil
+}
+
+// userURLs возвращает ссылки пользователя, создавая их при необходимости.
+// Вызывается под блокировкой на запись.
+func (m *InMemory) userURLs(uid uuid.UUID) map[string]*url.URL {
+	if _, ok := m.userStore[uid.String()]; !ok {
+		m.userStore[uid.String()] = make(map[string]*url.URL)
+	}
+	return m.userStore[uid.String()]
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/url"
 	"testing"
+	"time"
 
 	"github.com/gofrs/uuid"
 	"github.com/stretchr/testify/assert"
@@ -225,7 +226,7 @@ func TestInMemory_LoadUsers(t *testing.T) {
 	urlToStore, _ := url.Parse("https://practicum.yandex.ru/")
 	uuidToStore := uuid.Must(uuid.NewV4())
 	for i := 0; i < 1000; i++ {
-		urls[i] = urlToStore
+		urls[i], _ = urlToStore.Parse(fmt.Sprint(i))
 	}
 	t.Run("load users regular", func(t *testing.T) {
 		_, _ = store.SaveUserBatch(ctx, uuidToStore, urls)
@@ -266,7 +267,7 @@ func TestNewInMemory(t *testing.T) {
 		name string
 		want *InMemory
 	}{
-		{"reg", &InMemory{store: make(map[string]*url.URL), userStore: make(map[string]map[string]*url.URL)}},
+		{"reg", &InMemory{store: make(map[string]*url.URL), userStore: make(map[string]map[string]*url.URL), index: make(map[string]string), links: make(map[string]*Link), history: make(map[string][]Revision), created: make(map[string]time.Time), counts: make(map[string]int64), ids: CounterGenerator{}}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -283,7 +284,7 @@ func TestInMemory_Urls(t *testing.T) {
 	uuidToStore := uuid.Must(uuid.NewV4())
 	ids := make([]string, 10)
 	for i := 0; i < 10; i++ {
-		urls[i] = urlToStore
+		urls[i], _ = urlToStore.Parse(fmt.Sprint(i))
 		ids[i] = fmt.Sprintf("%d", i)
 	}
 	_, _ = store.SaveUserBatch(ctx, uuidToStore, urls)
@@ -0,0 +1,263 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+// migrationLockID ключ advisory-блокировки, чтобы несколько экземпляров не применяли миграции одновременно
+const migrationLockID = 7_231_000_101
+
+// ErrNoMigrations нет миграций для отката
+var ErrNoMigrations = errors.New("no migrations to revert")
+
+// Migration шаг миграции схемы БД
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus состояние миграции в БД
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time // AppliedAt время применения, nil если миграция еще не применена
+}
+
+// Migrator применяет и откатывает миграции схемы Postgres.
+// Миграции встроены в бинарный файл и применяются по возрастанию версии,
+// примененные версии хранятся в таблице schema_migrations.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// NewMigrator создает Migrator со встроенными миграциями
+func NewMigrator(db *sql.DB) (*Migrator, error) {
+	migrations, err := loadMigrations(migrationsFS, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("cannot load migrations: %w", err)
+	}
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+	}, nil
+}
+
+// Up применяет все еще не примененные миграции и возвращает их
+func (m *Migrator) Up(ctx context.Context) (applied []Migration, err error) {
+	err = m.locked(ctx, func(conn *sql.Conn) error {
+		done, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mg := range m.migrations {
+			if _, ok := done[mg.Version]; ok {
+				continue
+			}
+			err := inTx(ctx, conn, func(tx *sql.Tx) error {
+				if _, err := tx.ExecContext(ctx, mg.Up); err != nil {
+					return err
+				}
+				_, err := tx.ExecContext(ctx,
+					`INSERT INTO schema_migrations (version, name) VALUES ($1, $2);`, mg.Version, mg.Name)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("cannot apply migration %d_%s: %w", mg.Version, mg.Name, err)
+			}
+			applied = append(applied, mg)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down откатывает последнюю примененную миграцию и возвращает ее
+func (m *Migrator) Down(ctx context.Context) (reverted *Migration, err error) {
+	err = m.locked(ctx, func(conn *sql.Conn) error {
+		done, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			mg := m.migrations[i]
+			if _, ok := done[mg.Version]; !ok {
+				continue
+			}
+			err := inTx(ctx, conn, func(tx *sql.Tx) error {
+				if _, err := tx.ExecContext(ctx, mg.Down); err != nil {
+					return err
+				}
+				_, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1;`, mg.Version)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("cannot revert migration %d_%s: %w", mg.Version, mg.Name, err)
+			}
+			reverted = &mg
+			return nil
+		}
+		return ErrNoMigrations
+	})
+	return reverted, err
+}
+
+// Status возвращает все известные миграции с отметкой о применении
+func (m *Migrator) Status(ctx context.Context) (statuses []MigrationStatus, err error) {
+	err = m.locked(ctx, func(conn *sql.Conn) error {
+		done, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mg := range m.migrations {
+			status := MigrationStatus{Migration: mg}
+			if appliedAt, ok := done[mg.Version]; ok {
+				status.AppliedAt = &appliedAt
+			}
+			statuses = append(statuses, status)
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+// locked выполняет fn на отдельном соединении под advisory-блокировкой
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1);`, migrationLockID); err != nil {
+		return fmt.Errorf("cannot acquire migration lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1);`, migrationLockID)
+
+	query := `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version bigint PRIMARY KEY,
+			name text NOT NULL,
+			applied_at timestamp without time zone NOT NULL DEFAULT NOW()
+		);
+	`
+	if _, err := conn.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("cannot create `schema_migrations` table: %w", err)
+	}
+
+	return fn(conn)
+}
+
+// appliedVersions возвращает примененные версии и время их применения
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations;`)
+	if err != nil {
+		return nil, fmt.Errorf("cannot query applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	res := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("cannot scan row: %w", err)
+		}
+		res[version] = appliedAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return res, nil
+}
+
+func inTx(ctx context.Context, conn *sql.Conn, fn func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("cannot start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// loadMigrations читает пары файлов <версия>_<имя>.up.sql и <версия>_<имя>.down.sql
+// и возвращает миграции, упорядоченные по версии
+func loadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		name := entry.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(name, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+
+		base := strings.TrimSuffix(name, "."+direction+".sql")
+		rawVersion, title, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("bad migration file name %s", name)
+		}
+		version, err := strconv.ParseInt(rawVersion, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad migration version in %s: %w", name, err)
+		}
+
+		body, err := fs.ReadFile(fsys, path.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+
+		mg, ok := byVersion[version]
+		if !ok {
+			mg = &Migration{Version: version, Name: title}
+			byVersion[version] = mg
+		}
+		if mg.Name != title {
+			return nil, fmt.Errorf("migration %d has different names: %s and %s", version, mg.Name, title)
+		}
+		if direction == "up" {
+			mg.Up = string(body)
+		} else {
+			mg.Down = string(body)
+		}
+	}
+
+	res := make([]Migration, 0, len(byVersion))
+	for _, mg := range byVersion {
+		if mg.Up == "" || mg.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s must have both up and down steps", mg.Version, mg.Name)
+		}
+		res = append(res, *mg)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Version < res[j].Version
+	})
+	return res, nil
+}
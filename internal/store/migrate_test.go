@@ -0,0 +1,49 @@
+package store
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_loadMigrations(t *testing.T) {
+	t.Run("embedded migrations", func(t *testing.T) {
+		migrations, err := loadMigrations(migrationsFS, "migrations")
+		require.NoError(t, err)
+		require.NotEmpty(t, migrations)
+		assert.Equal(t, int64(1), migrations[0].Version)
+		assert.Equal(t, "create_urls", migrations[0].Name)
+	})
+	t.Run("ordered by version", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"m/0010_second.up.sql":   {Data: []byte("up 10")},
+			"m/0010_second.down.sql": {Data: []byte("down 10")},
+			"m/0002_first.up.sql":    {Data: []byte("up 2")},
+			"m/0002_first.down.sql":  {Data: []byte("down 2")},
+			"m/README.md":            {Data: []byte("ignored")},
+		}
+		migrations, err := loadMigrations(fsys, "m")
+		require.NoError(t, err)
+		assert.Equal(t, []Migration{
+			{Version: 2, Name: "first", Up: "up 2", Down: "down 2"},
+			{Version: 10, Name: "second", Up: "up 10", Down: "down 10"},
+		}, migrations)
+	})
+	t.Run("missing down step", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"m/0001_first.up.sql": {Data: []byte("up")},
+		}
+		_, err := loadMigrations(fsys, "m")
+		assert.Error(t, err)
+	})
+	t.Run("bad version", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"m/first.up.sql":   {Data: []byte("up")},
+			"m/first.down.sql": {Data: []byte("down")},
+		}
+		_, err := loadMigrations(fsys, "m")
+		assert.Error(t, err)
+	})
+}
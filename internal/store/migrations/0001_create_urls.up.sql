@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS urls (
+    id serial PRIMARY KEY,
+    original_url text,
+    user_id uuid,
+    updated_at timestamp without time zone,
+    deleted_at timestamp without time zone
+);
+
+CREATE INDEX IF NOT EXISTS user_id_idx ON urls (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS original_url_idx ON urls (original_url) WHERE deleted_at IS NULL;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS code_idx;
+ALTER TABLE urls DROP COLUMN IF EXISTS code;
@@ -0,0 +1,6 @@
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS code text;
+
+-- existing links keep their numeric short ids
+UPDATE urls SET code = id::text WHERE code IS NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS code_idx ON urls (code);
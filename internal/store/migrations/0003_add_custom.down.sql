@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS original_url_idx;
+CREATE UNIQUE INDEX original_url_idx ON urls (original_url) WHERE deleted_at IS NULL;
+
+ALTER TABLE urls DROP COLUMN IF EXISTS custom;
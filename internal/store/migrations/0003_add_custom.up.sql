@@ -0,0 +1,5 @@
+-- links with parameters (e.g. custom aliases) are not deduplicated by address
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS custom boolean NOT NULL DEFAULT false;
+
+DROP INDEX IF EXISTS original_url_idx;
+CREATE UNIQUE INDEX original_url_idx ON urls (original_url) WHERE deleted_at IS NULL AND NOT custom;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS expires_at_idx;
+ALTER TABLE urls DROP COLUMN IF EXISTS expires_at;
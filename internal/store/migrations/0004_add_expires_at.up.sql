@@ -0,0 +1,3 @@
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS expires_at timestamp with time zone;
+
+CREATE INDEX IF NOT EXISTS expires_at_idx ON urls (expires_at) WHERE expires_at IS NOT NULL;
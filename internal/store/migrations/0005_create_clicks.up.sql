@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS clicks (
+    id bigserial PRIMARY KEY,
+    code text NOT NULL,
+    clicked_at timestamp with time zone NOT NULL,
+    referrer text NOT NULL DEFAULT '',
+    user_agent text NOT NULL DEFAULT '',
+    ip_hash text NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS clicks_code_idx ON clicks (code, clicked_at);
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS url_history (
+    id bigserial PRIMARY KEY,
+    url_id integer NOT NULL REFERENCES urls (id) ON DELETE CASCADE,
+    original_url text NOT NULL,
+    replaced_at timestamp with time zone NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS url_history_url_id_idx ON url_history (url_id, replaced_at);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS tags_idx;
+
+ALTER TABLE urls DROP COLUMN IF EXISTS tags;
+ALTER TABLE urls DROP COLUMN IF EXISTS notes;
+ALTER TABLE urls DROP COLUMN IF EXISTS title;
@@ -0,0 +1,5 @@
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS title text NOT NULL DEFAULT '';
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS notes text NOT NULL DEFAULT '';
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS tags text[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS tags_idx ON urls USING gin (tags);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS user_clicks_idx;
+DROP INDEX IF EXISTS user_created_idx;
+
+ALTER TABLE urls DROP COLUMN IF EXISTS click_count;
+ALTER TABLE urls DROP COLUMN IF EXISTS created_at;
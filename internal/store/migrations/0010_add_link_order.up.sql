@@ -0,0 +1,11 @@
+-- creation time of existing links is unknown, they get the migration time
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS created_at timestamp with time zone NOT NULL DEFAULT NOW();
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS click_count bigint NOT NULL DEFAULT 0;
+
+UPDATE urls SET click_count = c.n
+FROM (SELECT code, COUNT(*) AS n FROM clicks GROUP BY code) AS c
+WHERE urls.code = c.code;
+
+-- keyset pagination of user links
+CREATE INDEX IF NOT EXISTS user_created_idx ON urls (user_id, created_at, code);
+CREATE INDEX IF NOT EXISTS user_clicks_idx ON urls (user_id, click_count, code);
@@ -0,0 +1 @@
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS is_template boolean NOT NULL DEFAULT false;
@@ -0,0 +1,2 @@
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS variants jsonb NOT NULL DEFAULT '[]';
+ALTER TABLE clicks ADD COLUMN IF NOT EXISTS variant text NOT NULL DEFAULT '';
@@ -0,0 +1,34 @@
+package store
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// RunReaper раз в interval окончательно удаляет ссылки, срок действия которых
+// истек больше retention назад, и переходы старше clickRetention. Нулевой clickRetention
+// оставляет переходы навсегда. Работает до отмены контекста.
+func RunReaper(ctx context.Context, s AuthStore, interval, retention, clickRetention time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if _, err := s.PurgeExpired(ctx, now.Add(-retention)); err != nil {
+				log.Printf("cannot purge expired links: %v\n", err)
+			}
+			if clickRetention <= 0 {
+				continue
+			}
+			if _, err := s.PurgeClicks(ctx, now.Add(-clickRetention)); err != nil {
+				log.Printf("cannot purge old clicks: %v\n", err)
+			}
+		}
+	}
+}
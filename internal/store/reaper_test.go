@@ -0,0 +1,36 @@
+package store
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunReaper(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	u, _ := url.Parse("https://practicum.yandex.ru/")
+	past := time.Now().Add(-time.Hour)
+	s := NewInMemory()
+	id, err := s.SaveLink(ctx, Link{URL: u, ExpiresAt: &past})
+	require.NoError(t, err)
+	plain, err := s.Save(ctx, u)
+	require.NoError(t, err)
+	require.NoError(t, s.SaveClicks(ctx, []Click{{ID: plain, Time: past}, {ID: plain, Time: time.Now()}}))
+
+	go RunReaper(ctx, s, 10*time.Millisecond, time.Minute, 30*time.Minute)
+
+	assert.Eventually(t, func() bool {
+		_, err := s.Load(ctx, id)
+		return err == ErrNotFound
+	}, time.Second, 10*time.Millisecond)
+	assert.Eventually(t, func() bool {
+		clicks, err := s.LoadClicks(ctx, plain, past.Add(-time.Hour), time.Now().Add(time.Hour))
+		return err == nil && len(clicks) == 1
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,54 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Rule правило выбора адреса перехода по запросу. Правило подходит запросу, если выполнены
+// все заданные в нем условия. Хранилище правила только сохраняет, проверяет их приложение.
+type Rule struct {
+	Devices   []string      `json:"devices,omitempty"`   // Devices классы устройства: ios, android, mobile, desktop
+	Languages []string      `json:"languages,omitempty"` // Languages языки из Accept-Language, например en или pt-BR
+	Headers   []HeaderMatch `json:"headers,omitempty"`   // Headers условия на заголовки запроса
+	Time      *TimeWindow   `json:"time,omitempty"`      // Time окно времени, в которое действует правило
+	URL       string        `json:"url"`                 // URL адрес перехода по правилу
+}
+
+// HeaderMatch условие на заголовок запроса
+type HeaderMatch struct {
+	Name  string `json:"name"`            // Name имя заголовка
+	Value string `json:"value,omitempty"` // Value значение без учета регистра, пустое - заголовок задан
+}
+
+// TimeWindow окно времени. Если To раньше From, окно переходит через полночь.
+type TimeWindow struct {
+	Days []string `json:"days,omitempty"` // Days дни недели mon..sun, пустой список - все дни
+	From string   `json:"from,omitempty"` // From начало окна HH:MM включительно, пустое - начало суток
+	To   string   `json:"to,omitempty"`   // To конец окна HH:MM не включительно, пустое - конец суток
+	Zone string   `json:"zone,omitempty"` // Zone часовой пояс из базы IANA, пустой - UTC
+}
+
+// rulesJSON кодирует правила для хранения в одном столбце
+func rulesJSON(rules []Rule) (string, error) {
+	if len(rules) == 0 {
+		return "[]", nil
+	}
+	b, err := json.Marshal(rules)
+	if err != nil {
+		return "", fmt.Errorf("cannot encode rules: %w", err)
+	}
+	return string(b), nil
+}
+
+// parseRules разбирает правила, сохраненные rulesJSON
+func parseRules(raw []byte) ([]Rule, error) {
+	var rules []Rule
+	if err := json.Unmarshal(raw, &rules); err != nil {
+		return nil, fmt.Errorf("cannot decode rules: %w", err)
+	}
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	return rules, nil
+}
@@ -16,278 +16,619 @@ import (
 var _ Store = (*RDB)(nil)
 var _ AuthStore = (*RDB)(nil)
 
+// clicksPerInsert сколько переходов вставляется одним запросом: у каждого перехода 6 параметров,
+// а их число в запросе ограничено (65535 в PostgreSQL, 32766 в SQLite)
+const clicksPerInsert = 1000
+
 // RDB структура для представления БД
 type RDB struct {
-	db *sql.DB
+	db  *sql.DB
+	ids IDGenerator
 }
 
 // NewRDB функция для создания нового хранилища в БД
-func NewRDB(db *sql.DB) *RDB {
+func NewRDB(db *sql.DB, opts ...Option) *RDB {
+	o := newOptions(opts)
 	return &RDB{
-		db: db,
+		db:  db,
+		ids: o.ids,
 	}
 }
 
-// Bootstrap creates all necessary tables and their structures
+// Bootstrap применяет все недостающие миграции схемы
 func (r *RDB) Bootstrap(ctx context.Context) error {
-	query := `
-		CREATE TABLE IF NOT EXISTS urls (
-			id serial PRIMARY KEY,
-			original_url text,
-			user_id uuid,
-			updated_at timestamp without time zone,
-		    deleted_at timestamp without time zone
-		);
-
-		CREATE INDEX IF NOT EXISTS user_id_idx ON urls (user_id);
-		CREATE UNIQUE INDEX IF NOT EXISTS original_url_idx ON urls (original_url) WHERE deleted_at IS NULL;
-	`
-
-	tx, err := r.db.BeginTx(ctx, nil)
+	m, err := r.Migrator()
 	if err != nil {
-		return fmt.Errorf("cannot start transaction: %w", err)
+		return err
 	}
-	defer tx.Rollback()
-
-	_, err = tx.ExecContext(ctx, query)
-	if err != nil {
-		return fmt.Errorf("cannot create `urls` table: %w", err)
-	}
-
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("cannot commit transaction: %w", err)
+	if _, err := m.Up(ctx); err != nil {
+		return fmt.Errorf("cannot migrate schema: %w", err)
 	}
 	return nil
 }
 
+// Migrator возвращает Migrator для схемы хранилища
+func (r *RDB) Migrator() (*Migrator, error) {
+	return NewMigrator(r.db)
+}
+
 // Save функция сохранения ссылки в БД
 func (r *RDB) Save(ctx context.Context, url *url.URL) (id string, err error) {
+	return r.save(ctx, url, nil)
+}
+
+// SaveBatch сохранение массива ссылок в БД
+func (r *RDB) SaveBatch(ctx context.Context, urls []*url.URL) (ids []string, err error) {
+	return r.saveBatch(ctx, urls, nil)
+}
+
+// Load загрузка ссылки по идентификатору
+func (r *RDB) Load(ctx context.Context, id string) (url *url.URL, err error) {
+	var rawURL string
+	var deletedAt *time.Time
+	var expired, limited sql.NullBool
+	query := `SELECT original_url, deleted_at, expires_at <= NOW(), clicks_left IS NOT NULL FROM urls WHERE code = $1;`
+
+	err = r.db.QueryRowContext(ctx, query, id).Scan(&rawURL, &deletedAt, &expired, &limited)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("cannot scan row: %w", err)
+	}
+	if deletedAt != nil {
+		return nil, ErrDeleted
+	}
+	if expired.Bool {
+		return nil, ErrExpired
+	}
+	if limited.Bool {
+		return r.consume(ctx, id)
+	}
+
+	return url.Parse(rawURL)
+}
+
+// consume списывает переход по ссылке с ограничением, помечая исчерпанную ссылку удаленной.
+// Условие в UPDATE не дает списать больше переходов, чем осталось, при параллельных запросах.
+func (r *RDB) consume(ctx context.Context, id string) (*url.URL, error) {
+	var rawURL string
 	query := `
-		INSERT INTO urls
-		    (original_url)
-		VALUES
-		    ($1)
-		ON CONFLICT (original_url) WHERE deleted_at IS NULL
-		DO UPDATE SET updated_at = NOW()
-		RETURNING
-		    id,
-		    updated_at
+		UPDATE urls
+		SET clicks_left = clicks_left - 1,
+		    deleted_at = CASE WHEN clicks_left = 1 THEN NOW() END
+		WHERE code = $1 AND clicks_left > 0 AND deleted_at IS NULL
+		RETURNING original_url;
 	`
 
-	var lid int64
-	var updatedAt *time.Time
-	err = r.db.QueryRowContext(ctx, query, url.String()).Scan(&lid, &updatedAt)
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&rawURL)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrDeleted
+	}
 	if err != nil {
-		return "", fmt.Errorf("cannot fetch conflict url: %w", err)
+		return nil, fmt.Errorf("cannot consume click: %w", err)
 	}
+	return url.Parse(rawURL)
+}
 
-	id = fmt.Sprint(lid)
-	if updatedAt != nil && !updatedAt.IsZero() {
-		err = ErrConflict
-	}
-	return
+// SaveUser сохранить ссылку для пользователя
+func (r *RDB) SaveUser(ctx context.Context, uid uuid.UUID, url *url.URL) (id string, err error) {
+	return r.save(ctx, url, &uid)
 }
 
-// SaveBatch сохранение массива ссылок в БД
-func (r *RDB) SaveBatch(ctx context.Context, urls []*url.URL) (ids []string, err error) {
-	var args []interface{}
+// SaveUserBatch сохранить ссылки для пользователя
+func (r *RDB) SaveUserBatch(ctx context.Context, uid uuid.UUID, urls []*url.URL) (ids []string, err error) {
+	return r.saveBatch(ctx, urls, &uid)
+}
 
-	var insertValues string
-	for i, u := range urls {
-		if i > 0 {
-			insertValues += ","
+// LoadUser загрузить ссылку для пользователя по идентификатору
+func (r *RDB) LoadUser(ctx context.Context, uid uuid.UUID, id string) (url *url.URL, err error) {
+	var rawURL string
+	var deletedAt *time.Time
+	var expired sql.NullBool
+	query := `SELECT original_url, deleted_at, expires_at <= NOW() FROM urls WHERE code = $1 AND user_id = $2;`
+
+	err = r.db.QueryRowContext(ctx, query, id, uid).Scan(&rawURL, &deletedAt, &expired)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
 		}
-		insertValues += fmt.Sprintf("($%d)", i+1)
-		args = append(args, u.String())
+		return nil, fmt.Errorf("cannot scan row: %w", err)
+	}
+	if deletedAt != nil {
+		return nil, ErrDeleted
+	}
+	if expired.Bool {
+		return nil, ErrExpired
 	}
 
-	query := `
-		INSERT INTO urls
-			(original_url)
-		VALUES ` + insertValues + `
-		ON CONFLICT (original_url) WHERE deleted_at IS NULL
-		DO UPDATE SET updated_at = NOW()
-		RETURNING id
-	`
+	return url.Parse(rawURL)
+}
 
-	rows, err := r.db.QueryContext(ctx, query, args...)
+// LoadUsers загрузить ссылки для пользователя
+func (r *RDB) LoadUsers(ctx context.Context, uid uuid.UUID) (urls map[string]*url.URL, err error) {
+	query := `SELECT code, original_url FROM urls WHERE user_id = $1 AND deleted_at IS NULL;`
+
+	rows, err := r.db.QueryContext(ctx, query, uid)
 	if err != nil {
-		return nil, fmt.Errorf("query error: %w", err)
+		return nil, fmt.Errorf("cannot query rows: %w", err)
 	}
 	defer rows.Close()
 
+	res := make(map[string]*url.URL)
 	for rows.Next() {
-		var id int64
-		if err := rows.Scan(&id); err != nil {
-			return nil, fmt.Errorf("error scanning row: %w", err)
+		var id, rawURL string
+
+		if err := rows.Scan(&id, &rawURL); err != nil {
+			return nil, fmt.Errorf("cannot scan row: %w", err)
 		}
-		ids = append(ids, fmt.Sprint(id))
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse URL: %w", err)
+		}
+
+		res[id] = u
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("cursor error: %w", err)
+		return nil, fmt.Errorf("rows error: %w", err)
 	}
 
-	if len(ids) != len(urls) {
-		return nil, errors.New("not all URLs have been saved")
+	if len(res) == 0 {
+		return nil, ErrNotFound
 	}
+	return res, nil
+}
 
-	return ids, nil
+// DeleteUsers удалить ссылки для указанного пользователя
+func (r *RDB) DeleteUsers(ctx context.Context, uid uuid.UUID, ids ...string) error {
+	arr := new(pgtype.VarcharArray)
+	if err := arr.Set(ids); err != nil {
+		return fmt.Errorf("cannot set ids to pg variable: %w", err)
+	}
+
+	query := `UPDATE urls SET deleted_at = NOW() WHERE user_id = $1 AND code = ANY($2);`
+	_, err := r.db.ExecContext(ctx, query, uid, arr)
+	return err
 }
 
-// Load загрузка ссылки по идентификатору
-func (r *RDB) Load(ctx context.Context, id string) (url *url.URL, err error) {
+// SaveLink сохранить ссылку с параметрами
+func (r *RDB) SaveLink(ctx context.Context, link Link) (id string, err error) {
+	query := `
+		INSERT INTO urls
+		    (id, code, original_url, user_id, custom, expires_at, password_hash, clicks_left, title, notes, tags,
+		     redirect_mode, passthrough, is_template, rules, variants)
+		VALUES
+		    ($1, $2, $3, $4, true, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15);
+	`
+	tags, err := tagsArray(link.Tags)
+	if err != nil {
+		return "", err
+	}
+	rules, err := rulesJSON(link.Rules)
+	if err != nil {
+		return "", err
+	}
+	variants, err := variantsJSON(link.Variants)
+	if err != nil {
+		return "", err
+	}
+
+	given, ids := scopedID(link, r.ids)
+	for i := 0; i < maxIDAttempts; i++ {
+		seqs, err := r.nextSeq(ctx, 1)
+		if err != nil {
+			return "", err
+		}
+		id := given
+		if id == "" {
+			if id, err = ids.Generate(seqs[0]); err != nil {
+				return "", err
+			}
+		}
+
+		_, err = r.db.ExecContext(ctx, query, seqs[0], id, link.URL.String(), link.UserID, link.ExpiresAt, link.PasswordHash,
+			clicksLeft(link), link.Title, link.Notes, tags, string(link.Redirect), string(link.Passthrough),
+			link.Template, rules, variants)
+		if isUniqueViolation(err) {
+			if link.ID != "" {
+				return "", ErrIDTaken
+			}
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("cannot insert url: %w", err)
+		}
+		return id, nil
+	}
+	return "", ErrIDCollision
+}
+
+// LoadLink загрузить ссылку с параметрами
+func (r *RDB) LoadLink(ctx context.Context, id string) (link *Link, err error) {
 	var rawURL string
+	var userID uuid.NullUUID
 	var deletedAt *time.Time
-	query := `SELECT original_url, deleted_at FROM urls WHERE id = $1;`
+	var expired sql.NullBool
+	var left sql.NullInt64
+	var tags pgtype.TextArray
+	var rules, variants []byte
+	link = &Link{ID: id}
+	query := `
+		SELECT original_url, user_id, deleted_at, expires_at, expires_at <= NOW(), password_hash, clicks_left,
+		       title, notes, tags, redirect_mode, passthrough, is_template, rules, variants, created_at
+		FROM urls WHERE code = $1;
+	`
 
-	err = r.db.QueryRowContext(ctx, query, id).Scan(&rawURL, &deletedAt)
+	err = r.db.QueryRowContext(ctx, query, id).Scan(&rawURL, &userID, &deletedAt, &link.ExpiresAt, &expired,
+		&link.PasswordHash, &left, &link.Title, &link.Notes, &tags, &link.Redirect, &link.Passthrough, &link.Template,
+		&rules, &variants, &link.CreatedAt)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, nil
+			return nil, ErrNotFound
 		}
 		return nil, fmt.Errorf("cannot scan row: %w", err)
 	}
 	if deletedAt != nil {
 		return nil, ErrDeleted
 	}
+	if expired.Bool {
+		return nil, ErrExpired
+	}
 
-	return url.Parse(rawURL)
+	if userID.Valid {
+		link.UserID = &userID.UUID
+	}
+	link.ClicksLeft = int(left.Int64)
+	if link.Tags, err = tagsSlice(tags); err != nil {
+		return nil, err
+	}
+	if link.Rules, err = parseRules(rules); err != nil {
+		return nil, err
+	}
+	if link.Variants, err = parseVariants(variants); err != nil {
+		return nil, err
+	}
+	if link.URL, err = url.Parse(rawURL); err != nil {
+		return nil, err
+	}
+	return link, nil
 }
 
-// SaveUser сохранить ссылку для пользователя
-func (r *RDB) SaveUser(ctx context.Context, uid uuid.UUID, url *url.URL) (id string, err error) {
-	query := `
-		INSERT INTO urls
-		    (original_url, user_id)
-		VALUES
-		    ($1, $2)
-		ON CONFLICT (original_url) WHERE deleted_at IS NULL
-		DO UPDATE SET updated_at = NOW()
-		RETURNING
-		    id,
-		    updated_at
-	`
+// SetMeta заменить описание ссылки пользователя
+func (r *RDB) SetMeta(ctx context.Context, uid uuid.UUID, id string, meta Meta) error {
+	return r.UpdateLink(ctx, uid, id, LinkUpdate{Meta: &meta})
+}
 
-	var lid int64
-	var updatedAt *time.Time
-	err = r.db.QueryRowContext(ctx, query, url.String(), uid).Scan(&lid, &updatedAt)
+// SetRules заменить правила перехода ссылки пользователя
+func (r *RDB) SetRules(ctx context.Context, uid uuid.UUID, id string, rules []Rule) error {
+	return r.UpdateLink(ctx, uid, id, LinkUpdate{Rules: &rules})
+}
+
+// LoadUserLinks загрузить ссылки пользователя с параметрами
+func (r *RDB) LoadUserLinks(ctx context.Context, uid uuid.UUID, filter LinkFilter) (links []Link, err error) {
+	var found bool
+	err = r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM urls WHERE user_id = $1 AND deleted_at IS NULL);`, uid).Scan(&found)
 	if err != nil {
-		return "", fmt.Errorf("cannot fetch conflict url: %w", err)
+		return nil, fmt.Errorf("cannot scan row: %w", err)
 	}
-
-	id = fmt.Sprint(lid)
-	if updatedAt != nil && !updatedAt.IsZero() {
-		err = ErrConflict
+	if !found {
+		return nil, ErrNotFound
 	}
-	return
-}
 
-// SaveUserBatch сохранить ссылки для пользователя
-func (r *RDB) SaveUserBatch(ctx context.Context, uid uuid.UUID, urls []*url.URL) (ids []string, err error) {
-	var args []interface{}
-	uidPos := len(urls) + 1
-
-	var insertValues string
-	for i, u := range urls {
-		if i > 0 {
-			insertValues += ","
+	column, dir, cmp := "created_at", "ASC", ">"
+	if filter.Order == OrderClicks {
+		column = "click_count"
+	}
+	if filter.Desc {
+		dir, cmp = "DESC", "<"
+	}
+	args := []interface{}{uid, filter.Tag, filter.Search}
+	query := `
+		SELECT code, original_url, expires_at, password_hash, clicks_left, title, notes, tags, rules, created_at,
+		       click_count
+		FROM urls
+		WHERE user_id = $1 AND deleted_at IS NULL AND ($2 = '' OR $2 = ANY (tags))
+		  AND ($3 = '' OR strpos(lower(original_url), lower($3)) > 0 OR strpos(lower(title), lower($3)) > 0)`
+	if filter.After != nil {
+		// keyset pagination: continue right after the last link of the previous page
+		var key interface{} = filter.After.Key
+		if filter.Order == OrderCreated {
+			key = time.Unix(0, filter.After.Key)
 		}
-		insertValues += fmt.Sprintf("($%d, $%d)", i+1, uidPos)
-		args = append(args, u.String())
+		args = append(args, key, filter.After.ID)
+		query += fmt.Sprintf(" AND (%s, code) %s ($4, $5)", column, cmp)
+	}
+	query += fmt.Sprintf(" ORDER BY %s %s, code %s", column, dir, dir)
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
 	}
-	args = append(args, uid)
-
-	query := `INSERT INTO urls (original_url, user_id) VALUES ` + insertValues + ` RETURNING id;`
-
 	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("query error: %w", err)
+		return nil, fmt.Errorf("cannot query rows: %w", err)
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		var id int64
-		if err := rows.Scan(&id); err != nil {
-			return nil, fmt.Errorf("error scanning row: %w", err)
+		var rawURL string
+		var left sql.NullInt64
+		var tags pgtype.TextArray
+		var rules []byte
+		link := Link{UserID: &uid}
+		err := rows.Scan(&link.ID, &rawURL, &link.ExpiresAt, &link.PasswordHash, &left, &link.Title, &link.Notes, &tags,
+			&rules, &link.CreatedAt, &link.Clicks)
+		if err != nil {
+			return nil, fmt.Errorf("cannot scan row: %w", err)
 		}
-		ids = append(ids, fmt.Sprint(id))
+		if link.URL, err = url.Parse(rawURL); err != nil {
+			return nil, fmt.Errorf("cannot parse URL: %w", err)
+		}
+		if link.Tags, err = tagsSlice(tags); err != nil {
+			return nil, err
+		}
+		if link.Rules, err = parseRules(rules); err != nil {
+			return nil, err
+		}
+		link.ClicksLeft = int(left.Int64)
+		links = append(links, link)
 	}
-
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("cursor error: %w", err)
+		return nil, fmt.Errorf("rows error: %w", err)
 	}
+	return links, nil
+}
 
-	if len(ids) != len(urls) {
-		return nil, errors.New("not all URLs have been saved")
+// tagsArray переводит метки в массив postgres, пустой вместо NULL
+func tagsArray(tags []string) (*pgtype.TextArray, error) {
+	if tags == nil {
+		tags = []string{}
 	}
+	arr := new(pgtype.TextArray)
+	if err := arr.Set(tags); err != nil {
+		return nil, fmt.Errorf("cannot set tags to pg variable: %w", err)
+	}
+	return arr, nil
+}
 
-	return ids, nil
+// tagsSlice переводит массив меток postgres в срез, nil для пустого массива
+func tagsSlice(arr pgtype.TextArray) (tags []string, err error) {
+	if len(arr.Elements) == 0 {
+		return nil, nil
+	}
+	if err := arr.AssignTo(&tags); err != nil {
+		return nil, fmt.Errorf("cannot read tags: %w", err)
+	}
+	return tags, nil
 }
 
-// LoadUser загрузить ссылку для пользователя по идентификатору
-func (r *RDB) LoadUser(ctx context.Context, uid uuid.UUID, id string) (url *url.URL, err error) {
+// UpdateUser заменить адрес ссылки пользователя, сохранив прежний в истории
+func (r *RDB) UpdateUser(ctx context.Context, uid uuid.UUID, id string, u *url.URL) error {
+	return r.UpdateLink(ctx, uid, id, LinkUpdate{URL: u})
+}
+
+// UpdateLink изменить ссылку пользователя в одной транзакции
+func (r *RDB) UpdateLink(ctx context.Context, uid uuid.UUID, id string, upd LinkUpdate) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("cannot start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var lid int64
 	var rawURL string
 	var deletedAt *time.Time
-	query := `SELECT original_url, deleted_at FROM urls WHERE id = $1 AND user_id = $2;`
-
-	err = r.db.QueryRowContext(ctx, query, id, uid).Scan(&rawURL, &deletedAt)
+	var expired sql.NullBool
+	query := `
+		SELECT id, original_url, deleted_at, expires_at <= NOW()
+		FROM urls WHERE code = $1 AND user_id = $2
+		FOR UPDATE;
+	`
+	err = tx.QueryRowContext(ctx, query, id, uid).Scan(&lid, &rawURL, &deletedAt, &expired)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, nil
+			return ErrNotFound
 		}
-		return nil, fmt.Errorf("cannot scan row: %w", err)
+		return fmt.Errorf("cannot scan row: %w", err)
 	}
 	if deletedAt != nil {
-		return nil, ErrDeleted
+		return ErrDeleted
+	}
+	if expired.Bool {
+		return ErrExpired
 	}
 
-	return url.Parse(rawURL)
+	if upd.URL != nil {
+		query = `INSERT INTO url_history (url_id, original_url, replaced_at) VALUES ($1, $2, NOW());`
+		if _, err := tx.ExecContext(ctx, query, lid, rawURL); err != nil {
+			return fmt.Errorf("cannot save url history: %w", err)
+		}
+		// an edited link is no longer deduplicated by address
+		query = `UPDATE urls SET original_url = $1, custom = true, updated_at = NOW() WHERE id = $2;`
+		if _, err := tx.ExecContext(ctx, query, upd.URL.String(), lid); err != nil {
+			return fmt.Errorf("cannot update url: %w", err)
+		}
+	}
+	if upd.Meta != nil {
+		tags, err := tagsArray(upd.Meta.Tags)
+		if err != nil {
+			return err
+		}
+		query = `UPDATE urls SET title = $1, notes = $2, tags = $3, updated_at = NOW() WHERE id = $4;`
+		if _, err := tx.ExecContext(ctx, query, upd.Meta.Title, upd.Meta.Notes, tags, lid); err != nil {
+			return fmt.Errorf("cannot update url meta: %w", err)
+		}
+	}
+	if upd.Rules != nil {
+		raw, err := rulesJSON(*upd.Rules)
+		if err != nil {
+			return err
+		}
+		query = `UPDATE urls SET rules = $1, custom = true, updated_at = NOW() WHERE id = $2;`
+		if _, err := tx.ExecContext(ctx, query, raw, lid); err != nil {
+			return fmt.Errorf("cannot update url rules: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("cannot commit transaction: %w", err)
+	}
+	return nil
 }
 
-// LoadUsers загрузить ссылки для пользователя
-func (r *RDB) LoadUsers(ctx context.Context, uid uuid.UUID) (urls map[string]*url.URL, err error) {
-	query := `SELECT id, original_url FROM urls WHERE user_id = $1 AND deleted_at IS NULL;`
+// LoadHistory загрузить прежние адреса ссылки пользователя
+func (r *RDB) LoadHistory(ctx context.Context, uid uuid.UUID, id string) (revisions []Revision, err error) {
+	var lid int64
+	err = r.db.QueryRowContext(ctx, `SELECT id FROM urls WHERE code = $1 AND user_id = $2;`, id, uid).Scan(&lid)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("cannot scan row: %w", err)
+	}
 
-	rows, err := r.db.QueryContext(ctx, query, uid)
+	query := `SELECT original_url, replaced_at FROM url_history WHERE url_id = $1 ORDER BY replaced_at, id;`
+	rows, err := r.db.QueryContext(ctx, query, lid)
 	if err != nil {
-		return nil, fmt.Errorf("cannot query rows: %w", err)
+		return nil, fmt.Errorf("cannot query url history: %w", err)
 	}
 	defer rows.Close()
 
-	res := make(map[string]*url.URL)
 	for rows.Next() {
-		var id int64
 		var rawURL string
-
-		if err := rows.Scan(&id, &rawURL); err != nil {
+		var rev Revision
+		if err := rows.Scan(&rawURL, &rev.ReplacedAt); err != nil {
 			return nil, fmt.Errorf("cannot scan row: %w", err)
 		}
-		u, err := url.Parse(rawURL)
-		if err != nil {
-			return nil, fmt.Errorf("cannot parse URL: %w", err)
+		if rev.URL, err = url.Parse(rawURL); err != nil {
+			return nil, err
 		}
-
-		res[fmt.Sprint(id)] = u
+		revisions = append(revisions, rev)
 	}
-
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("rows error: %w", err)
 	}
+	return revisions, nil
+}
 
-	return res, nil
+// PurgeExpired окончательно удалить ссылки, срок действия которых истек раньше before, вместе с их переходами
+func (r *RDB) PurgeExpired(ctx context.Context, before time.Time) (n int64, err error) {
+	query := `
+		WITH purged AS (
+			DELETE FROM urls WHERE expires_at <= $1
+			RETURNING code
+		), dropped AS (
+			DELETE FROM clicks WHERE code IN (SELECT code FROM purged)
+		)
+		SELECT COUNT(*) FROM purged
+	`
+	if err := r.db.QueryRowContext(ctx, query, before).Scan(&n); err != nil {
+		return 0, fmt.Errorf("cannot delete expired urls: %w", err)
+	}
+	return n, nil
 }
 
-// DeleteUsers удалить ссылки для указанного пользователя
-func (r *RDB) DeleteUsers(ctx context.Context, uid uuid.UUID, ids ...string) error {
-	arr := new(pgtype.VarcharArray)
-	if err := arr.Set(ids); err != nil {
-		return fmt.Errorf("cannot set ids to pg variable: %w", err)
+// PurgeClicks окончательно удалить переходы, совершенные раньше before
+func (r *RDB) PurgeClicks(ctx context.Context, before time.Time) (n int64, err error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM clicks WHERE clicked_at < $1;`, before)
+	if err != nil {
+		return 0, fmt.Errorf("cannot delete old clicks: %w", err)
 	}
+	return res.RowsAffected()
+}
 
-	query := `UPDATE urls SET deleted_at = NOW() WHERE user_id = $1 AND id = ANY($2);`
-	_, err := r.db.ExecContext(ctx, query, uid, arr)
-	return err
+// SaveClicks сохранить переходы по ссылкам. Большой пакет вставляется частями в одной транзакции.
+func (r *RDB) SaveClicks(ctx context.Context, clicks []Click) error {
+	if len(clicks) == 0 {
+		return nil
+	}
+	if len(clicks) <= clicksPerInsert {
+		return r.insertClicks(ctx, r.db, clicks)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("cannot start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for len(clicks) > 0 {
+		n := clicksPerInsert
+		if n > len(clicks) {
+			n = len(clicks)
+		}
+		if err := r.insertClicks(ctx, tx, clicks[:n]); err != nil {
+			return err
+		}
+		clicks = clicks[n:]
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("cannot commit transaction: %w", err)
+	}
+	return nil
+}
+
+// execer выполняет запрос в базе или в транзакции
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// insertClicks вставляет переходы одним запросом, их не больше clicksPerInsert
+func (r *RDB) insertClicks(ctx context.Context, db execer, clicks []Click) error {
+	var args []interface{}
+	var insertValues string
+	for i, c := range clicks {
+		args = append(args, c.ID, c.Time, c.Referrer, c.UserAgent, c.IPHash, c.Variant)
+		if i > 0 {
+			insertValues += ","
+		}
+		insertValues += fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d)", 6*i+1, 6*i+2, 6*i+3, 6*i+4, 6*i+5, 6*i+6)
+	}
+
+	// the per-link counter used to order user links is updated by the same statement
+	query := `
+		WITH inserted AS (
+			INSERT INTO clicks
+				(code, clicked_at, referrer, user_agent, ip_hash, variant)
+			VALUES ` + insertValues + `
+			RETURNING code
+		)
+		UPDATE urls SET click_count = click_count + c.n
+		FROM (SELECT code, COUNT(*) AS n FROM inserted GROUP BY code) AS c
+		WHERE urls.code = c.code
+	`
+
+	if _, err := db.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("cannot insert clicks: %w", err)
+	}
+	return nil
+}
+
+// LoadClicks загрузить переходы по ссылке за период
+func (r *RDB) LoadClicks(ctx context.Context, id string, from, to time.Time) (clicks []Click, err error) {
+	query := `
+		SELECT clicked_at, referrer, user_agent, ip_hash, variant FROM clicks
+		WHERE code = $1 AND clicked_at >= $2 AND clicked_at < $3
+		ORDER BY clicked_at
+	`
+	rows, err := r.db.QueryContext(ctx, query, id, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("cannot query clicks: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		c := Click{ID: id}
+		if err := rows.Scan(&c.Time, &c.Referrer, &c.UserAgent, &c.IPHash, &c.Variant); err != nil {
+			return nil, fmt.Errorf("cannot scan row: %w", err)
+		}
+		clicks = append(clicks, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return clicks, nil
 }
 
 // Ping проверка хранилища
@@ -323,3 +664,167 @@ func (r *RDB) Urls(ctx context.Context) int {
 	}
 	return count
 }
+
+// sqlStateUniqueViolation код ошибки Postgres при нарушении уникального индекса
+const sqlStateUniqueViolation = "23505"
+
+// isUniqueViolation сообщает, что запрос нарушил уникальный индекс.
+// Конфликт по адресу ссылки разрешается в самом запросе, поэтому остается только индекс кодов.
+func isUniqueViolation(err error) bool {
+	var pgErr interface{ SQLState() string }
+	return errors.As(err, &pgErr) && pgErr.SQLState() == sqlStateUniqueViolation
+}
+
+// nextSeq резервирует n порядковых номеров для новых ссылок
+func (r *RDB) nextSeq(ctx context.Context, n int) ([]uint64, error) {
+	query := `SELECT nextval(pg_get_serial_sequence('urls', 'id')) FROM generate_series(1, $1);`
+
+	rows, err := r.db.QueryContext(ctx, query, n)
+	if err != nil {
+		return nil, fmt.Errorf("cannot query sequence: %w", err)
+	}
+	defer rows.Close()
+
+	seqs := make([]uint64, 0, n)
+	for rows.Next() {
+		var seq uint64
+		if err := rows.Scan(&seq); err != nil {
+			return nil, fmt.Errorf("cannot scan row: %w", err)
+		}
+		seqs = append(seqs, seq)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return seqs, nil
+}
+
+// save сохраняет ссылку. Если такая ссылка уже есть, возвращает ее идентификатор и ErrConflict.
+// При совпадении сгенерированного кода с занятым повторяет вставку с новым номером.
+func (r *RDB) save(ctx context.Context, url *url.URL, uid *uuid.UUID) (id string, err error) {
+	query := `
+		INSERT INTO urls
+		    (id, code, original_url, user_id)
+		VALUES
+		    ($1, $2, $3, $4)
+		ON CONFLICT (original_url) WHERE deleted_at IS NULL AND NOT custom
+		DO UPDATE SET updated_at = NOW()
+		RETURNING
+		    code,
+		    updated_at
+	`
+
+	for i := 0; i < maxIDAttempts; i++ {
+		seqs, err := r.nextSeq(ctx, 1)
+		if err != nil {
+			return "", err
+		}
+		code, err := r.ids.Generate(seqs[0])
+		if err != nil {
+			return "", err
+		}
+
+		var updatedAt *time.Time
+		err = r.db.QueryRowContext(ctx, query, seqs[0], code, url.String(), uid).Scan(&id, &updatedAt)
+		if isUniqueViolation(err) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("cannot fetch conflict url: %w", err)
+		}
+
+		if updatedAt != nil && !updatedAt.IsZero() {
+			err = ErrConflict
+		}
+		return id, err
+	}
+	return "", ErrIDCollision
+}
+
+// saveBatch сохраняет ссылки одним запросом, для уже существующих ссылок возвращает их идентификаторы
+func (r *RDB) saveBatch(ctx context.Context, urls []*url.URL, uid *uuid.UUID) (ids []string, err error) {
+	// a single INSERT cannot upsert the same row twice, so duplicates are sent once
+	var rawURLs []string
+	positions := make(map[string]int)
+	for _, u := range urls {
+		if _, ok := positions[u.String()]; !ok {
+			positions[u.String()] = len(rawURLs)
+			rawURLs = append(rawURLs, u.String())
+		}
+	}
+
+	var saved []string
+	for i := 0; ; i++ {
+		if i == maxIDAttempts {
+			return nil, ErrIDCollision
+		}
+		saved, err = r.insertBatch(ctx, rawURLs, uid)
+		if !isUniqueViolation(err) {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(saved) != len(positions) {
+		return nil, errors.New("not all URLs have been saved")
+	}
+
+	for _, u := range urls {
+		ids = append(ids, saved[positions[u.String()]])
+	}
+	return ids, nil
+}
+
+// insertBatch вставляет уникальные ссылки одним запросом и возвращает их идентификаторы в том же порядке
+func (r *RDB) insertBatch(ctx context.Context, rawURLs []string, uid *uuid.UUID) (ids []string, err error) {
+	seqs, err := r.nextSeq(ctx, len(rawURLs))
+	if err != nil {
+		return nil, err
+	}
+
+	var args []interface{}
+	uidPos := 3*len(rawURLs) + 1
+	var insertValues string
+	for i, rawURL := range rawURLs {
+		code, err := r.ids.Generate(seqs[i])
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, seqs[i], code, rawURL)
+		if i > 0 {
+			insertValues += ","
+		}
+		insertValues += fmt.Sprintf("($%d::integer, $%d, $%d, $%d::uuid)", 3*i+1, 3*i+2, 3*i+3, uidPos)
+	}
+	args = append(args, uid)
+
+	query := `
+		INSERT INTO urls
+			(id, code, original_url, user_id)
+		VALUES ` + insertValues + `
+		ON CONFLICT (original_url) WHERE deleted_at IS NULL AND NOT custom
+		DO UPDATE SET updated_at = NOW()
+		RETURNING code
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query error: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+	return ids, nil
+}
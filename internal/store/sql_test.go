@@ -7,6 +7,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strconv"
 	"testing"
 	"time"
@@ -21,6 +22,7 @@ import (
 
 var (
 	store *RDB
+	pgDSN string
 )
 
 func TestMain(m *testing.M) {
@@ -32,6 +34,7 @@ func TestMain(m *testing.M) {
 	if err != nil {
 		panic(err)
 	}
+	pgDSN = connStr
 	conn, err := sql.Open("postgres", connStr)
 	if err != nil {
 		panic(err)
@@ -181,13 +184,13 @@ func TestRDB_Close(t *testing.T) {
 
 func TestRDB_Urls(t *testing.T) {
 	ctx := context.Background()
-	store, _ := NewFileStore("./store")
+	store, _ := NewFileStore(filepath.Join(t.TempDir(), "store"))
 	urls := make([]*url.URL, 10)
 	urlToStore, _ := url.Parse("https://practicum.yandex.ru/")
 	uuidToStore := uuid.Must(uuid.NewV4())
 	ids := make([]string, 10)
 	for i := 0; i < 10; i++ {
-		urls[i] = urlToStore
+		urls[i], _ = urlToStore.Parse(fmt.Sprint(i))
 		ids[i] = fmt.Sprintf("%d", i)
 	}
 	_, _ = store.SaveUserBatch(ctx, uuidToStore, urls)
@@ -201,7 +204,7 @@ func TestRDB_Urls(t *testing.T) {
 
 func TestRDB_Users(t *testing.T) {
 	ctx := context.Background()
-	store, _ := NewFileStore("./store")
+	store, _ := NewFileStore(filepath.Join(t.TempDir(), "store"))
 	urls := make([]*url.URL, 10)
 	urlToStore, _ := url.Parse("https://practicum.yandex.ru/")
 	uuidToStore := uuid.Must(uuid.NewV4())
@@ -218,3 +221,51 @@ func TestRDB_Users(t *testing.T) {
 		assert.Equal(t, 1, count)
 	})
 }
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+	m, err := NewMigrator(store.db)
+	require.NoError(t, err)
+
+	t.Run("all migrations applied by bootstrap", func(t *testing.T) {
+		statuses, err := m.Status(ctx)
+		require.NoError(t, err)
+		require.NotEmpty(t, statuses)
+		for _, s := range statuses {
+			assert.NotNil(t, s.AppliedAt, "migration %d_%s", s.Version, s.Name)
+		}
+	})
+	t.Run("up is idempotent and safe to run concurrently", func(t *testing.T) {
+		errs := make(chan error, 4)
+		for i := 0; i < cap(errs); i++ {
+			go func() {
+				applied, err := m.Up(ctx)
+				if err == nil && len(applied) != 0 {
+					err = fmt.Errorf("unexpected migrations applied: %v", applied)
+				}
+				errs <- err
+			}()
+		}
+		for i := 0; i < cap(errs); i++ {
+			assert.NoError(t, <-errs)
+		}
+	})
+	t.Run("down reverts the last migration", func(t *testing.T) {
+		statuses, err := m.Status(ctx)
+		require.NoError(t, err)
+		last := statuses[len(statuses)-1]
+
+		reverted, err := m.Down(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, last.Version, reverted.Version)
+
+		statuses, err = m.Status(ctx)
+		require.NoError(t, err)
+		assert.Nil(t, statuses[len(statuses)-1].AppliedAt)
+
+		applied, err := m.Up(ctx)
+		require.NoError(t, err)
+		require.Len(t, applied, 1)
+		assert.Equal(t, last.Version, applied[0].Version)
+	})
+}
@@ -0,0 +1,876 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+var _ Store = (*SQLite)(nil)
+var _ AuthStore = (*SQLite)(nil)
+
+// SQLite структура для хранилища ссылок во встраиваемой БД SQLite
+type SQLite struct {
+	db  *sql.DB
+	ids IDGenerator
+}
+
+// NewSQLite функция для создания нового хранилища в SQLite
+func NewSQLite(db *sql.DB, opts ...Option) *SQLite {
+	o := newOptions(opts)
+	return &SQLite{
+		db:  db,
+		ids: o.ids,
+	}
+}
+
+// sqliteMigrations шаги схемы SQLite, номер примененного шага хранится в PRAGMA user_version
+var sqliteMigrations = []string{
+	`
+		CREATE TABLE IF NOT EXISTS urls (
+			id integer PRIMARY KEY AUTOINCREMENT,
+			original_url text,
+			user_id text,
+			updated_at timestamp,
+			deleted_at timestamp
+		);
+
+		CREATE INDEX IF NOT EXISTS user_id_idx ON urls (user_id);
+		CREATE UNIQUE INDEX IF NOT EXISTS original_url_idx ON urls (original_url) WHERE deleted_at IS NULL;
+	`,
+	`
+		ALTER TABLE urls ADD COLUMN code text;
+		UPDATE urls SET code = CAST(id AS text);
+		CREATE UNIQUE INDEX code_idx ON urls (code);
+	`,
+	`
+		ALTER TABLE urls ADD COLUMN custom boolean NOT NULL DEFAULT false;
+		DROP INDEX original_url_idx;
+		CREATE UNIQUE INDEX original_url_idx ON urls (original_url) WHERE deleted_at IS NULL AND NOT custom;
+	`,
+	`
+		ALTER TABLE urls ADD COLUMN expires_at integer; -- unix time in nanoseconds
+		CREATE INDEX expires_at_idx ON urls (expires_at) WHERE expires_at IS NOT NULL;
+	`,
+	`
+		CREATE TABLE clicks (
+			id integer PRIMARY KEY AUTOINCREMENT,
+			code text NOT NULL,
+			clicked_at integer NOT NULL, -- unix time in nanoseconds
+			referrer text NOT NULL DEFAULT '',
+			user_agent text NOT NULL DEFAULT '',
+			ip_hash text NOT NULL DEFAULT ''
+		);
+		CREATE INDEX clicks_code_idx ON clicks (code, clicked_at);
+	`,
+	`
+		ALTER TABLE urls ADD COLUMN password_hash text NOT NULL DEFAULT '';
+	`,
+	`
+		ALTER TABLE urls ADD COLUMN clicks_left integer;
+	`,
+	`
+		CREATE TABLE url_history (
+			id integer PRIMARY KEY AUTOINCREMENT,
+			url_id integer NOT NULL,
+			original_url text NOT NULL,
+			replaced_at integer NOT NULL -- unix time in nanoseconds
+		);
+		CREATE INDEX url_history_url_id_idx ON url_history (url_id, replaced_at);
+		-- foreign keys are off by default in SQLite, so purged links drop their history here
+		CREATE TRIGGER url_history_purge AFTER DELETE ON urls BEGIN
+			DELETE FROM url_history WHERE url_id = OLD.id;
+		END;
+	`,
+	`
+		ALTER TABLE urls ADD COLUMN title text NOT NULL DEFAULT '';
+		ALTER TABLE urls ADD COLUMN notes text NOT NULL DEFAULT '';
+		ALTER TABLE urls ADD COLUMN tags text NOT NULL DEFAULT '[]'; -- JSON array of strings
+	`,
+	`
+		ALTER TABLE urls ADD COLUMN created_at integer NOT NULL DEFAULT 0; -- unix time in nanoseconds
+		ALTER TABLE urls ADD COLUMN click_count integer NOT NULL DEFAULT 0;
+		-- creation time of existing links is unknown, they get the migration time
+		UPDATE urls SET created_at = CAST((julianday('now') - 2440587.5) * 86400 AS integer) * 1000000000;
+		UPDATE urls SET click_count = (SELECT COUNT(*) FROM clicks WHERE clicks.code = urls.code);
+		CREATE INDEX user_created_idx ON urls (user_id, created_at, code);
+		CREATE INDEX user_clicks_idx ON urls (user_id, click_count, code);
+	`,
+	`
+		ALTER TABLE urls ADD COLUMN redirect_mode text NOT NULL DEFAULT '';
+	`,
+	`
+		ALTER TABLE urls ADD COLUMN passthrough text NOT NULL DEFAULT '';
+	`,
+	`
+		ALTER TABLE urls ADD COLUMN is_template integer NOT NULL DEFAULT 0;
+	`,
+	`
+		ALTER TABLE urls ADD COLUMN rules text NOT NULL DEFAULT '[]'; -- JSON array of redirect rules
+	`,
+	`
+		ALTER TABLE urls ADD COLUMN variants text NOT NULL DEFAULT '[]'; -- JSON array of weighted targets
+		ALTER TABLE clicks ADD COLUMN variant text NOT NULL DEFAULT '';
+	`,
+}
+
+// Bootstrap применяет недостающие шаги схемы
+func (s *SQLite) Bootstrap(ctx context.Context) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("cannot start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var version int
+	if err := tx.QueryRowContext(ctx, `PRAGMA user_version;`).Scan(&version); err != nil {
+		return fmt.Errorf("cannot get schema version: %w", err)
+	}
+	for ; version < len(sqliteMigrations); version++ {
+		if _, err := tx.ExecContext(ctx, sqliteMigrations[version]); err != nil {
+			return fmt.Errorf("cannot apply schema step %d: %w", version+1, err)
+		}
+	}
+	// PRAGMA does not accept bind parameters
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(`PRAGMA user_version = %d;`, version)); err != nil {
+		return fmt.Errorf("cannot set schema version: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("cannot commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Save функция сохранения ссылки в SQLite
+func (s *SQLite) Save(ctx context.Context, url *url.URL) (id string, err error) {
+	return s.saveOne(ctx, url, nil)
+}
+
+// SaveBatch сохранение массива ссылок в SQLite
+func (s *SQLite) SaveBatch(ctx context.Context, urls []*url.URL) (ids []string, err error) {
+	return s.saveBatch(ctx, urls, nil)
+}
+
+// Load загрузка ссылки по идентификатору
+func (s *SQLite) Load(ctx context.Context, id string) (url *url.URL, err error) {
+	var rawURL string
+	var deleted, expired, limited bool
+	query := `SELECT original_url, deleted_at IS NOT NULL, COALESCE(expires_at <= ?, false), clicks_left IS NOT NULL
+		FROM urls WHERE code = ?;`
+
+	err = s.db.QueryRowContext(ctx, query, time.Now().UnixNano(), id).Scan(&rawURL, &deleted, &expired, &limited)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("cannot scan row: %w", err)
+	}
+	if deleted {
+		return nil, ErrDeleted
+	}
+	if expired {
+		return nil, ErrExpired
+	}
+	if limited {
+		return s.consume(ctx, id)
+	}
+
+	return url.Parse(rawURL)
+}
+
+// consume списывает переход по ссылке с ограничением, помечая исчерпанную ссылку удаленной
+func (s *SQLite) consume(ctx context.Context, id string) (*url.URL, error) {
+	var rawURL string
+	query := `
+		UPDATE urls
+		SET clicks_left = clicks_left - 1,
+		    deleted_at = CASE WHEN clicks_left = 1 THEN CURRENT_TIMESTAMP END
+		WHERE code = ? AND clicks_left > 0 AND deleted_at IS NULL
+		RETURNING original_url;
+	`
+
+	err := s.db.QueryRowContext(ctx, query, id).Scan(&rawURL)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrDeleted
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot consume click: %w", err)
+	}
+	return url.Parse(rawURL)
+}
+
+// LoadLink загрузить ссылку с параметрами
+func (s *SQLite) LoadLink(ctx context.Context, id string) (link *Link, err error) {
+	var rawURL string
+	var userID sql.NullString
+	var expiresAt, left sql.NullInt64
+	var deleted bool
+	var tags, rules, variants string
+	var createdAt int64
+	link = &Link{ID: id}
+	query := `SELECT original_url, user_id, deleted_at IS NOT NULL, expires_at, password_hash, clicks_left, title, notes, tags,
+		redirect_mode, passthrough, is_template, rules, variants, created_at
+		FROM urls WHERE code = ?;`
+
+	err = s.db.QueryRowContext(ctx, query, id).Scan(&rawURL, &userID, &deleted, &expiresAt, &link.PasswordHash, &left,
+		&link.Title, &link.Notes, &tags, &link.Redirect, &link.Passthrough, &link.Template, &rules, &variants, &createdAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("cannot scan row: %w", err)
+	}
+	if deleted {
+		return nil, ErrDeleted
+	}
+	if expiresAt.Valid {
+		t := time.Unix(0, expiresAt.Int64)
+		link.ExpiresAt = &t
+	}
+	if link.Expired(time.Now()) {
+		return nil, ErrExpired
+	}
+	link.ClicksLeft = int(left.Int64)
+	link.CreatedAt = time.Unix(0, createdAt)
+	if link.Tags, err = sqliteTags(tags); err != nil {
+		return nil, err
+	}
+	if link.Rules, err = parseRules([]byte(rules)); err != nil {
+		return nil, err
+	}
+	if link.Variants, err = parseVariants([]byte(variants)); err != nil {
+		return nil, err
+	}
+
+	if userID.Valid {
+		uid, err := uuid.FromString(userID.String)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse user id: %w", err)
+		}
+		link.UserID = &uid
+	}
+	if link.URL, err = url.Parse(rawURL); err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+// SaveUser сохранить ссылку для пользователя
+func (s *SQLite) SaveUser(ctx context.Context, uid uuid.UUID, url *url.URL) (id string, err error) {
+	return s.saveOne(ctx, url, &uid)
+}
+
+// SaveUserBatch сохранить ссылки для пользователя
+func (s *SQLite) SaveUserBatch(ctx context.Context, uid uuid.UUID, urls []*url.URL) (ids []string, err error) {
+	return s.saveBatch(ctx, urls, &uid)
+}
+
+// LoadUser загрузить ссылку для пользователя по идентификатору
+func (s *SQLite) LoadUser(ctx context.Context, uid uuid.UUID, id string) (url *url.URL, err error) {
+	var rawURL string
+	var deleted, expired bool
+	query := `SELECT original_url, deleted_at IS NOT NULL, COALESCE(expires_at <= ?, false) FROM urls WHERE code = ? AND user_id = ?;`
+
+	err = s.db.QueryRowContext(ctx, query, time.Now().UnixNano(), id, uid.String()).Scan(&rawURL, &deleted, &expired)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("cannot scan row: %w", err)
+	}
+	if deleted {
+		return nil, ErrDeleted
+	}
+	if expired {
+		return nil, ErrExpired
+	}
+
+	return url.Parse(rawURL)
+}
+
+// LoadUsers загрузить ссылки для пользователя
+func (s *SQLite) LoadUsers(ctx context.Context, uid uuid.UUID) (urls map[string]*url.URL, err error) {
+	query := `SELECT code, original_url FROM urls WHERE user_id = ? AND deleted_at IS NULL;`
+
+	rows, err := s.db.QueryContext(ctx, query, uid.String())
+	if err != nil {
+		return nil, fmt.Errorf("cannot query rows: %w", err)
+	}
+	defer rows.Close()
+
+	res := make(map[string]*url.URL)
+	for rows.Next() {
+		var id, rawURL string
+
+		if err := rows.Scan(&id, &rawURL); err != nil {
+			return nil, fmt.Errorf("cannot scan row: %w", err)
+		}
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse URL: %w", err)
+		}
+
+		res[id] = u
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	if len(res) == 0 {
+		return nil, ErrNotFound
+	}
+	return res, nil
+}
+
+// DeleteUsers удалить ссылки для указанного пользователя
+func (s *SQLite) DeleteUsers(ctx context.Context, uid uuid.UUID, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	args := []interface{}{uid.String()}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+
+	query := `UPDATE urls SET deleted_at = CURRENT_TIMESTAMP
+		WHERE user_id = ? AND deleted_at IS NULL AND code IN (?` + strings.Repeat(",?", len(ids)-1) + `);`
+	_, err := s.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+// SaveLink сохранить ссылку с параметрами
+func (s *SQLite) SaveLink(ctx context.Context, link Link) (id string, err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("cannot start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	given, ids := scopedID(link, s.ids)
+	var code interface{}
+	if given != "" {
+		taken, err := s.codeTaken(ctx, tx, given)
+		if err != nil {
+			return "", err
+		}
+		if taken {
+			return "", ErrIDTaken
+		}
+		code = given
+	}
+
+	var userID interface{}
+	if link.UserID != nil {
+		userID = link.UserID.String()
+	}
+
+	var expiresAt interface{}
+	if link.ExpiresAt != nil {
+		expiresAt = link.ExpiresAt.UnixNano()
+	}
+
+	tags, err := sqliteTagsJSON(link.Tags)
+	if err != nil {
+		return "", err
+	}
+	rules, err := rulesJSON(link.Rules)
+	if err != nil {
+		return "", err
+	}
+	variants, err := variantsJSON(link.Variants)
+	if err != nil {
+		return "", err
+	}
+
+	query := `INSERT INTO urls (code, original_url, user_id, custom, expires_at, password_hash, clicks_left, title, notes, tags,
+		created_at, redirect_mode, passthrough, is_template, rules, variants)
+		VALUES (?, ?, ?, true, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id;`
+	var lid int64
+	err = tx.QueryRowContext(ctx, query, code, link.URL.String(), userID, expiresAt, link.PasswordHash, clicksLeft(link),
+		link.Title, link.Notes, tags, time.Now().UnixNano(), string(link.Redirect), string(link.Passthrough),
+		link.Template, rules, variants).Scan(&lid)
+	if err != nil {
+		return "", fmt.Errorf("cannot insert url: %w", err)
+	}
+
+	id = given
+	if id == "" {
+		if id, err = s.assignCode(ctx, tx, lid, ids); err != nil {
+			return "", err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("cannot commit transaction: %w", err)
+	}
+	return id, nil
+}
+
+// UpdateUser заменить адрес ссылки пользователя, сохранив прежний в истории
+func (s *SQLite) UpdateUser(ctx context.Context, uid uuid.UUID, id string, u *url.URL) error {
+	return s.UpdateLink(ctx, uid, id, LinkUpdate{URL: u})
+}
+
+// UpdateLink изменить ссылку пользователя в одной транзакции
+func (s *SQLite) UpdateLink(ctx context.Context, uid uuid.UUID, id string, upd LinkUpdate) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("cannot start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var lid int64
+	var rawURL string
+	var deleted, expired bool
+	query := `SELECT id, original_url, deleted_at IS NOT NULL, COALESCE(expires_at <= ?, false)
+		FROM urls WHERE code = ? AND user_id = ?;`
+	err = tx.QueryRowContext(ctx, query, time.Now().UnixNano(), id, uid.String()).Scan(&lid, &rawURL, &deleted, &expired)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("cannot scan row: %w", err)
+	}
+	if deleted {
+		return ErrDeleted
+	}
+	if expired {
+		return ErrExpired
+	}
+
+	if upd.URL != nil {
+		query = `INSERT INTO url_history (url_id, original_url, replaced_at) VALUES (?, ?, ?);`
+		if _, err := tx.ExecContext(ctx, query, lid, rawURL, time.Now().UnixNano()); err != nil {
+			return fmt.Errorf("cannot save url history: %w", err)
+		}
+		// an edited link is no longer deduplicated by address
+		query = `UPDATE urls SET original_url = ?, custom = true, updated_at = CURRENT_TIMESTAMP WHERE id = ?;`
+		if _, err := tx.ExecContext(ctx, query, upd.URL.String(), lid); err != nil {
+			return fmt.Errorf("cannot update url: %w", err)
+		}
+	}
+	if upd.Meta != nil {
+		tags, err := sqliteTagsJSON(upd.Meta.Tags)
+		if err != nil {
+			return err
+		}
+		query = `UPDATE urls SET title = ?, notes = ?, tags = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?;`
+		if _, err := tx.ExecContext(ctx, query, upd.Meta.Title, upd.Meta.Notes, tags, lid); err != nil {
+			return fmt.Errorf("cannot update url meta: %w", err)
+		}
+	}
+	if upd.Rules != nil {
+		raw, err := rulesJSON(*upd.Rules)
+		if err != nil {
+			return err
+		}
+		query = `UPDATE urls SET rules = ?, custom = true, updated_at = CURRENT_TIMESTAMP WHERE id = ?;`
+		if _, err := tx.ExecContext(ctx, query, raw, lid); err != nil {
+			return fmt.Errorf("cannot update url rules: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("cannot commit transaction: %w", err)
+	}
+	return nil
+}
+
+// SetMeta заменить описание ссылки пользователя
+func (s *SQLite) SetMeta(ctx context.Context, uid uuid.UUID, id string, meta Meta) error {
+	return s.UpdateLink(ctx, uid, id, LinkUpdate{Meta: &meta})
+}
+
+// SetRules заменить правила перехода ссылки пользователя
+func (s *SQLite) SetRules(ctx context.Context, uid uuid.UUID, id string, rules []Rule) error {
+	return s.UpdateLink(ctx, uid, id, LinkUpdate{Rules: &rules})
+}
+
+// LoadUserLinks загрузить ссылки пользователя с параметрами
+func (s *SQLite) LoadUserLinks(ctx context.Context, uid uuid.UUID, filter LinkFilter) (links []Link, err error) {
+	var found bool
+	err = s.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM urls WHERE user_id = ? AND deleted_at IS NULL);`, uid.String()).Scan(&found)
+	if err != nil {
+		return nil, fmt.Errorf("cannot scan row: %w", err)
+	}
+	if !found {
+		return nil, ErrNotFound
+	}
+
+	column, dir, cmp := "created_at", "ASC", ">"
+	if filter.Order == OrderClicks {
+		column = "click_count"
+	}
+	if filter.Desc {
+		dir, cmp = "DESC", "<"
+	}
+	args := []interface{}{uid.String(), filter.Tag, filter.Tag, filter.Search, filter.Search, filter.Search}
+	query := `SELECT code, original_url, expires_at, password_hash, clicks_left, title, notes, tags, rules, created_at,
+		click_count
+		FROM urls
+		WHERE user_id = ? AND deleted_at IS NULL
+		  AND (? = '' OR EXISTS (SELECT 1 FROM json_each(urls.tags) WHERE json_each.value = ?))
+		  AND (? = '' OR instr(lower(original_url), lower(?)) > 0 OR instr(lower(title), lower(?)) > 0)`
+	if filter.After != nil {
+		// keyset pagination: continue right after the last link of the previous page
+		args = append(args, filter.After.Key, filter.After.ID)
+		query += fmt.Sprintf(" AND (%s, code) %s (?, ?)", column, cmp)
+	}
+	query += fmt.Sprintf(" ORDER BY %s %s, code %s", column, dir, dir)
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += " LIMIT ?"
+	}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("cannot query rows: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rawURL, tags, rules string
+		var expiresAt, left sql.NullInt64
+		var createdAt int64
+		link := Link{UserID: &uid}
+		err := rows.Scan(&link.ID, &rawURL, &expiresAt, &link.PasswordHash, &left, &link.Title, &link.Notes, &tags,
+			&rules, &createdAt, &link.Clicks)
+		if err != nil {
+			return nil, fmt.Errorf("cannot scan row: %w", err)
+		}
+		if link.URL, err = url.Parse(rawURL); err != nil {
+			return nil, fmt.Errorf("cannot parse URL: %w", err)
+		}
+		if link.Tags, err = sqliteTags(tags); err != nil {
+			return nil, err
+		}
+		if link.Rules, err = parseRules([]byte(rules)); err != nil {
+			return nil, err
+		}
+		if expiresAt.Valid {
+			t := time.Unix(0, expiresAt.Int64)
+			link.ExpiresAt = &t
+		}
+		link.ClicksLeft = int(left.Int64)
+		link.CreatedAt = time.Unix(0, createdAt)
+		links = append(links, link)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return links, nil
+}
+
+// sqliteTagsJSON переводит метки в JSON-массив для хранения в SQLite
+func sqliteTagsJSON(tags []string) (string, error) {
+	if tags == nil {
+		tags = []string{}
+	}
+	b, err := json.Marshal(tags)
+	if err != nil {
+		return "", fmt.Errorf("cannot encode tags: %w", err)
+	}
+	return string(b), nil
+}
+
+// sqliteTags читает метки из JSON-массива, nil для пустого массива
+func sqliteTags(raw string) (tags []string, err error) {
+	if err := json.Unmarshal([]byte(raw), &tags); err != nil {
+		return nil, fmt.Errorf("cannot decode tags: %w", err)
+	}
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	return tags, nil
+}
+
+// LoadHistory загрузить прежние адреса ссылки пользователя
+func (s *SQLite) LoadHistory(ctx context.Context, uid uuid.UUID, id string) (revisions []Revision, err error) {
+	var lid int64
+	err = s.db.QueryRowContext(ctx, `SELECT id FROM urls WHERE code = ? AND user_id = ?;`, id, uid.String()).Scan(&lid)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("cannot scan row: %w", err)
+	}
+
+	query := `SELECT original_url, replaced_at FROM url_history WHERE url_id = ? ORDER BY replaced_at, id;`
+	rows, err := s.db.QueryContext(ctx, query, lid)
+	if err != nil {
+		return nil, fmt.Errorf("cannot query url history: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rawURL string
+		var replacedAt int64
+		if err := rows.Scan(&rawURL, &replacedAt); err != nil {
+			return nil, fmt.Errorf("cannot scan row: %w", err)
+		}
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, Revision{URL: u, ReplacedAt: time.Unix(0, replacedAt)})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return revisions, nil
+}
+
+// PurgeExpired окончательно удалить ссылки, срок действия которых истек раньше before, вместе с их переходами
+func (s *SQLite) PurgeExpired(ctx context.Context, before time.Time) (n int64, err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("cannot start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM clicks WHERE code IN (SELECT code FROM urls WHERE expires_at <= ?);`,
+		before.UnixNano())
+	if err != nil {
+		return 0, fmt.Errorf("cannot delete clicks of expired urls: %w", err)
+	}
+	res, err := tx.ExecContext(ctx, `DELETE FROM urls WHERE expires_at <= ?;`, before.UnixNano())
+	if err != nil {
+		return 0, fmt.Errorf("cannot delete expired urls: %w", err)
+	}
+	if n, err = res.RowsAffected(); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("cannot commit transaction: %w", err)
+	}
+	return n, nil
+}
+
+// PurgeClicks окончательно удалить переходы, совершенные раньше before
+func (s *SQLite) PurgeClicks(ctx context.Context, before time.Time) (n int64, err error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM clicks WHERE clicked_at < ?;`, before.UnixNano())
+	if err != nil {
+		return 0, fmt.Errorf("cannot delete old clicks: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// SaveClicks сохранить переходы по ссылкам
+func (s *SQLite) SaveClicks(ctx context.Context, clicks []Click) error {
+	if len(clicks) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("cannot start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for part := clicks; len(part) > 0; {
+		n := clicksPerInsert
+		if n > len(part) {
+			n = len(part)
+		}
+		var args []interface{}
+		for _, c := range part[:n] {
+			args = append(args, c.ID, c.Time.UnixNano(), c.Referrer, c.UserAgent, c.IPHash, c.Variant)
+		}
+		query := `INSERT INTO clicks (code, clicked_at, referrer, user_agent, ip_hash, variant) VALUES (?, ?, ?, ?, ?, ?)` +
+			strings.Repeat(", (?, ?, ?, ?, ?, ?)", n-1) + `;`
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return fmt.Errorf("cannot insert clicks: %w", err)
+		}
+		part = part[n:]
+	}
+	// the per-link counter used to order user links
+	counts := make(map[string]int64)
+	for _, c := range clicks {
+		counts[c.ID]++
+	}
+	for id, n := range counts {
+		if _, err := tx.ExecContext(ctx, `UPDATE urls SET click_count = click_count + ? WHERE code = ?;`, n, id); err != nil {
+			return fmt.Errorf("cannot update click count: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("cannot commit transaction: %w", err)
+	}
+	return nil
+}
+
+// LoadClicks загрузить переходы по ссылке за период
+func (s *SQLite) LoadClicks(ctx context.Context, id string, from, to time.Time) (clicks []Click, err error) {
+	query := `SELECT clicked_at, referrer, user_agent, ip_hash, variant FROM clicks
+		WHERE code = ? AND clicked_at >= ? AND clicked_at < ?
+		ORDER BY clicked_at;`
+	rows, err := s.db.QueryContext(ctx, query, id, from.UnixNano(), to.UnixNano())
+	if err != nil {
+		return nil, fmt.Errorf("cannot query clicks: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		c := Click{ID: id}
+		var clickedAt int64
+		if err := rows.Scan(&clickedAt, &c.Referrer, &c.UserAgent, &c.IPHash, &c.Variant); err != nil {
+			return nil, fmt.Errorf("cannot scan row: %w", err)
+		}
+		c.Time = time.Unix(0, clickedAt)
+		clicks = append(clicks, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return clicks, nil
+}
+
+// Ping проверка хранилища
+func (s *SQLite) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+// Close закрыть хранилище
+func (s *SQLite) Close() error {
+	return s.db.Close()
+}
+
+// Users статистика по пользователям
+func (s *SQLite) Users(ctx context.Context) int {
+	var count int
+	query := `SELECT COUNT(distinct user_id) FROM urls;`
+
+	err := s.db.QueryRowContext(ctx, query).Scan(&count)
+	if err != nil {
+		log.Printf("cannot scan row: %v\n", err)
+	}
+	return count
+}
+
+// Urls статистика по ссылкам
+func (s *SQLite) Urls(ctx context.Context) int {
+	var count int
+	query := `SELECT COUNT(1) FROM urls;`
+
+	err := s.db.QueryRowContext(ctx, query).Scan(&count)
+	if err != nil {
+		log.Printf("cannot scan row: %v\n", err)
+	}
+	return count
+}
+
+// saveOne сохраняет одну ссылку в отдельной транзакции
+func (s *SQLite) saveOne(ctx context.Context, url *url.URL, uid *uuid.UUID) (id string, err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("cannot start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	id, err = s.save(ctx, tx, url, uid)
+	if err != nil && !errors.Is(err, ErrConflict) {
+		return "", err
+	}
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("cannot commit transaction: %w", err)
+	}
+	return id, err
+}
+
+// save сохраняет ссылку. Если такая ссылка уже есть, возвращает ее идентификатор и ErrConflict.
+// Новой строке идентификатор назначается после вставки, порядковым номером служит ее id.
+func (s *SQLite) save(ctx context.Context, tx *sql.Tx, url *url.URL, uid *uuid.UUID) (id string, err error) {
+	query := `
+		INSERT INTO urls
+		    (original_url, user_id, created_at)
+		VALUES
+		    (?, ?, ?)
+		ON CONFLICT (original_url) WHERE deleted_at IS NULL AND NOT custom
+		DO UPDATE SET updated_at = CURRENT_TIMESTAMP
+		RETURNING
+		    id,
+		    code,
+		    updated_at IS NOT NULL
+	`
+
+	var userID interface{}
+	if uid != nil {
+		userID = uid.String()
+	}
+
+	var lid int64
+	var code sql.NullString
+	var updated bool
+	err = tx.QueryRowContext(ctx, query, url.String(), userID, time.Now().UnixNano()).Scan(&lid, &code, &updated)
+	if err != nil {
+		return "", fmt.Errorf("cannot fetch conflict url: %w", err)
+	}
+	if updated {
+		return code.String, ErrConflict
+	}
+
+	return s.assignCode(ctx, tx, lid, s.ids)
+}
+
+// assignCode генерирует свободный идентификатор для строки lid генератором ids.
+// Транзакция держит единственную блокировку записи, поэтому проверка занятости не гоняется.
+func (s *SQLite) assignCode(ctx context.Context, tx *sql.Tx, lid int64, ids IDGenerator) (id string, err error) {
+	id, err = nextID(ids, uint64(lid), func(id string) (bool, error) {
+		return s.codeTaken(ctx, tx, id)
+	})
+	if err != nil {
+		return "", err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE urls SET code = ? WHERE id = ?;`, id, lid); err != nil {
+		return "", fmt.Errorf("cannot set url code: %w", err)
+	}
+	return id, nil
+}
+
+// codeTaken проверяет, занят ли идентификатор
+func (s *SQLite) codeTaken(ctx context.Context, tx *sql.Tx, id string) (taken bool, err error) {
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM urls WHERE code = ?);`, id).Scan(&taken)
+	if err != nil {
+		return false, fmt.Errorf("cannot check url code: %w", err)
+	}
+	return taken, nil
+}
+
+// saveBatch сохраняет ссылки в одной транзакции, для уже существующих ссылок возвращает их идентификаторы
+func (s *SQLite) saveBatch(ctx context.Context, urls []*url.URL, uid *uuid.UUID) (ids []string, err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, u := range urls {
+		id, err := s.save(ctx, tx, u, uid)
+		if err != nil && !errors.Is(err, ErrConflict) {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("cannot commit transaction: %w", err)
+	}
+
+	if len(ids) != len(urls) {
+		return nil, errors.New("not all URLs have been saved")
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,173 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSQLite(t *testing.T) *SQLite {
+	conn, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "store.db"))
+	require.NoError(t, err)
+	conn.SetMaxOpenConns(1)
+	s := NewSQLite(conn)
+	require.NoError(t, s.Bootstrap(context.Background()))
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+func TestSQLite_Save(t *testing.T) {
+	s := newTestSQLite(t)
+	ctx := context.Background()
+	urlToStore, _ := url.Parse("https://practicum.yandex.ru/")
+
+	id, err := s.Save(ctx, urlToStore)
+	require.NoError(t, err)
+	require.NotEmpty(t, id)
+
+	t.Run("conflict", func(t *testing.T) {
+		conflictID, err := s.SaveUser(ctx, uuid.Must(uuid.NewV4()), urlToStore)
+		assert.ErrorIs(t, err, ErrConflict)
+		assert.Equal(t, id, conflictID)
+	})
+}
+
+func TestSQLite_SaveBatch(t *testing.T) {
+	s := newTestSQLite(t)
+	ctx := context.Background()
+	var urls []*url.URL
+	for i := 0; i < 10; i++ {
+		u, _ := url.Parse(fmt.Sprintf("https://practicum.yandex.ru/%d", i))
+		urls = append(urls, u)
+	}
+
+	ids, err := s.SaveBatch(ctx, urls)
+	require.NoError(t, err)
+	require.Len(t, ids, len(urls))
+	for i, id := range ids {
+		u, err := s.Load(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, urls[i], u)
+	}
+}
+
+func TestSQLite_Load(t *testing.T) {
+	s := newTestSQLite(t)
+	ctx := context.Background()
+
+	_, err := s.Load(ctx, "42")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestSQLite_LoadUser(t *testing.T) {
+	s := newTestSQLite(t)
+	ctx := context.Background()
+	user := uuid.Must(uuid.NewV4())
+	urlToStore, _ := url.Parse("https://practicum.yandex.ru/" + user.String())
+
+	id, err := s.SaveUser(ctx, user, urlToStore)
+	require.NoError(t, err)
+
+	u, err := s.LoadUser(ctx, user, id)
+	require.NoError(t, err)
+	assert.Equal(t, urlToStore, u)
+
+	_, err = s.LoadUser(ctx, uuid.Must(uuid.NewV4()), id)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestSQLite_LoadUsers(t *testing.T) {
+	s := newTestSQLite(t)
+	ctx := context.Background()
+	user := uuid.Must(uuid.NewV4())
+	var urls []*url.URL
+	for i := 0; i < 10; i++ {
+		u, _ := url.Parse(fmt.Sprintf("https://practicum.yandex.ru/%s/%d", user, i))
+		urls = append(urls, u)
+	}
+
+	ids, err := s.SaveUserBatch(ctx, user, urls)
+	require.NoError(t, err)
+
+	res, err := s.LoadUsers(ctx, user)
+	require.NoError(t, err)
+	assert.Len(t, res, len(urls))
+	assert.Equal(t, urls[0], res[ids[0]])
+}
+
+func TestSQLite_DeleteUsers(t *testing.T) {
+	s := newTestSQLite(t)
+	ctx := context.Background()
+	user := uuid.Must(uuid.NewV4())
+	urlToStore, _ := url.Parse("https://practicum.yandex.ru/" + user.String())
+
+	id, err := s.SaveUser(ctx, user, urlToStore)
+	require.NoError(t, err)
+
+	t.Run("foreign user", func(t *testing.T) {
+		require.NoError(t, s.DeleteUsers(ctx, uuid.Must(uuid.NewV4()), id))
+		_, err := s.Load(ctx, id)
+		assert.NoError(t, err)
+	})
+	t.Run("owner", func(t *testing.T) {
+		require.NoError(t, s.DeleteUsers(ctx, user, id))
+		_, err := s.Load(ctx, id)
+		assert.ErrorIs(t, err, ErrDeleted)
+		_, err = s.LoadUser(ctx, user, id)
+		assert.ErrorIs(t, err, ErrDeleted)
+	})
+	t.Run("save again after delete", func(t *testing.T) {
+		newID, err := s.SaveUser(ctx, user, urlToStore)
+		assert.NoError(t, err)
+		assert.NotEqual(t, id, newID)
+	})
+}
+
+func TestSQLite_Statistics(t *testing.T) {
+	s := newTestSQLite(t)
+	ctx := context.Background()
+	user := uuid.Must(uuid.NewV4())
+	for i := 0; i < 10; i++ {
+		u, _ := url.Parse(fmt.Sprintf("https://practicum.yandex.ru/%d", i))
+		_, err := s.SaveUser(ctx, user, u)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, 10, s.Urls(ctx))
+	assert.Equal(t, 1, s.Users(ctx))
+}
+
+func TestSQLite_Bootstrap(t *testing.T) {
+	ctx := context.Background()
+	conn, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "store.db"))
+	require.NoError(t, err)
+	conn.SetMaxOpenConns(1)
+	defer conn.Close()
+
+	// database created before short codes were introduced
+	_, err = conn.ExecContext(ctx, sqliteMigrations[0])
+	require.NoError(t, err)
+	_, err = conn.ExecContext(ctx, `INSERT INTO urls (original_url) VALUES ('https://practicum.yandex.ru/');`)
+	require.NoError(t, err)
+
+	s := NewSQLite(conn)
+	require.NoError(t, s.Bootstrap(ctx))
+	// repeated bootstrap is a no-op
+	require.NoError(t, s.Bootstrap(ctx))
+
+	u, err := s.Load(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, "https://practicum.yandex.ru/", u.String())
+
+	var version int
+	require.NoError(t, conn.QueryRowContext(ctx, `PRAGMA user_version;`).Scan(&version))
+	assert.Equal(t, len(sqliteMigrations), version)
+}
@@ -2,9 +2,13 @@ package store
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"io"
 	"net/url"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/gofrs/uuid"
 )
@@ -12,6 +16,7 @@ import (
 // Пользовательские ошибки
 var (
 	ErrDeleted = errors.New("record deleted") // ErrDeleted ошибка запись удалена
+	ErrExpired = errors.New("record expired") // ErrExpired ошибка срок действия ссылки истек
 )
 
 // Store описывает типовое хранилище ссылок
@@ -19,6 +24,8 @@ type Store interface {
 	io.Closer
 
 	Save(ctx context.Context, url *url.URL) (id string, err error)
+	// Load возвращает ссылку для перехода по ней. У ссылки с ограниченным числом переходов
+	// атомарно списывается один переход, а исчерпанная ссылка считается удаленной.
 	Load(ctx context.Context, id string) (url *url.URL, err error)
 	Ping(ctx context.Context) error
 }
@@ -41,4 +48,275 @@ type AuthStore interface {
 	DeleteUsers(ctx context.Context, uid uuid.UUID, ids ...string) error
 	Users(ctx context.Context) int
 	Urls(ctx context.Context) int
+
+	// SaveLink сохраняет ссылку с параметрами. Такая ссылка всегда создается заново
+	// и не участвует в поиске уже сохраненных ссылок с тем же адресом.
+	// Если идентификатор задан и уже занят, возвращается ErrIDTaken.
+	// Для ссылки на другом домене возвращается идентификатор вида DomainID(link.Domain, код).
+	SaveLink(ctx context.Context, link Link) (id string, err error)
+	// LoadLink возвращает ссылку вместе с параметрами, ошибки такие же, как у Load.
+	// В отличие от Load переходы по ссылке не списываются.
+	// Владелец гарантированно заполнен только для ссылок, сохраненных через SaveLink.
+	LoadLink(ctx context.Context, id string) (link *Link, err error)
+	// UpdateUser заменяет адрес ссылки id пользователя uid, сохраняя прежний адрес в истории.
+	// Измененная ссылка больше не участвует в поиске ссылок с тем же адресом.
+	// Ошибки такие же, как у LoadUser.
+	UpdateUser(ctx context.Context, uid uuid.UUID, id string, u *url.URL) error
+	// LoadHistory возвращает прежние адреса ссылки id пользователя uid от старых к новым.
+	// Для чужой или несуществующей ссылки возвращается ErrNotFound.
+	LoadHistory(ctx context.Context, uid uuid.UUID, id string) (revisions []Revision, err error)
+	// SetMeta заменяет описание ссылки id пользователя uid. Ошибки такие же, как у LoadUser.
+	SetMeta(ctx context.Context, uid uuid.UUID, id string, meta Meta) error
+	// SetRules заменяет правила перехода ссылки id пользователя uid, пустой список удаляет их.
+	// Ошибки такие же, как у LoadUser.
+	SetRules(ctx context.Context, uid uuid.UUID, id string, rules []Rule) error
+	// UpdateLink применяет к ссылке id пользователя uid все изменения из upd разом: если одно
+	// из них не сохранилось, ссылка остается прежней. Ошибки такие же, как у LoadUser.
+	UpdateLink(ctx context.Context, uid uuid.UUID, id string, upd LinkUpdate) error
+	// LoadUserLinks возвращает неудаленные ссылки пользователя вместе с параметрами, подходящие под filter,
+	// в заданном в filter порядке. Если у пользователя нет ни одной ссылки, возвращается ErrNotFound.
+	LoadUserLinks(ctx context.Context, uid uuid.UUID, filter LinkFilter) (links []Link, err error)
+	// PurgeExpired окончательно удаляет ссылки, срок действия которых истек раньше before, вместе с их переходами
+	PurgeExpired(ctx context.Context, before time.Time) (n int64, err error)
+	// PurgeClicks окончательно удаляет переходы, совершенные раньше before, и возвращает их число.
+	// Счетчики переходов по ссылкам при этом не уменьшаются.
+	PurgeClicks(ctx context.Context, before time.Time) (n int64, err error)
+	// SaveClicks сохраняет пакет переходов по ссылкам
+	SaveClicks(ctx context.Context, clicks []Click) error
+	// LoadClicks возвращает переходы по ссылке id с from включительно по to не включительно
+	LoadClicks(ctx context.Context, id string, from, to time.Time) (clicks []Click, err error)
+}
+
+// DomainID возвращает идентификатор ссылки с кодом code на домене domain.
+// Ссылки домена по умолчанию хранятся под самим кодом, ссылки других доменов под "domain/code",
+// поэтому один и тот же код может вести на разные адреса на разных доменах.
+func DomainID(domain, code string) string {
+	if domain == "" {
+		return code
+	}
+	return domain + "/" + code
+}
+
+// SplitID разделяет идентификатор ссылки на домен и код, домен пустой для домена по умолчанию
+func SplitID(id string) (domain, code string) {
+	if i := strings.LastIndexByte(id, '/'); i >= 0 {
+		return id[:i], id[i+1:]
+	}
+	return "", id
+}
+
+// scopedID возвращает идентификатор, заданный для ссылки link, с учетом ее домена,
+// и генератор идентификаторов на ее домене
+func scopedID(link Link, ids IDGenerator) (string, IDGenerator) {
+	if link.Domain == "" {
+		return link.ID, ids
+	}
+	gen := IDGeneratorFunc(func(seq uint64) (string, error) {
+		code, err := ids.Generate(seq)
+		if err != nil {
+			return "", err
+		}
+		return DomainID(link.Domain, code), nil
+	})
+	if link.ID == "" {
+		return "", gen
+	}
+	return DomainID(link.Domain, link.ID), gen
+}
+
+// RedirectMode способ перехода по короткой ссылке
+type RedirectMode string
+
+// Способы перехода по короткой ссылке
+const (
+	RedirectMovedPermanently RedirectMode = "301"          // RedirectMovedPermanently постоянное перенаправление 301
+	RedirectFound            RedirectMode = "302"          // RedirectFound временное перенаправление 302
+	RedirectTemporary        RedirectMode = "307"          // RedirectTemporary временное перенаправление 307 с сохранением метода
+	RedirectPermanent        RedirectMode = "308"          // RedirectPermanent постоянное перенаправление 308 с сохранением метода
+	RedirectInterstitial     RedirectMode = "interstitial" // RedirectInterstitial страница с адресом назначения и подтверждением перехода
+	RedirectMetaRefresh      RedirectMode = "meta-refresh" // RedirectMetaRefresh переход через meta refresh без передачи Referer
+)
+
+// PassthroughMode какие части запроса после кода ссылки переносятся в адрес перехода
+type PassthroughMode string
+
+// Режимы переноса частей запроса в адрес перехода
+const (
+	PassthroughPath  PassthroughMode = "path"  // PassthroughPath путь после кода дописывается к пути адреса
+	PassthroughQuery PassthroughMode = "query" // PassthroughQuery параметры запроса добавляются к параметрам адреса
+	PassthroughAll   PassthroughMode = "all"   // PassthroughAll переносятся и путь, и параметры
+)
+
+// Path сообщает, переносится ли путь
+func (m PassthroughMode) Path() bool {
+	return m == PassthroughPath || m == PassthroughAll
+}
+
+// Query сообщает, переносятся ли параметры запроса
+func (m PassthroughMode) Query() bool {
+	return m == PassthroughQuery || m == PassthroughAll
+}
+
+// Link ссылка с параметрами
+type Link struct {
+	ID        string     // ID короткий идентификатор, если пустой - генерируется хранилищем
+	Domain    string     // Domain домен ссылки, пустой для домена по умолчанию. Учитывается только SaveLink
+	URL       *url.URL   // URL исходная ссылка
+	UserID    *uuid.UUID // UserID владелец ссылки, nil для анонимной ссылки
+	ExpiresAt *time.Time // ExpiresAt время, после которого ссылка перестает работать
+
+	PasswordHash string          // PasswordHash соленый хеш пароля, пустой для ссылки без пароля
+	ClicksLeft   int             // ClicksLeft сколько переходов осталось, 0 - без ограничения
+	Redirect     RedirectMode    // Redirect способ перехода по ссылке, пустой - RedirectTemporary
+	Passthrough  PassthroughMode // Passthrough что переносится из запроса в адрес перехода, пустой - ничего
+	Template     bool            // Template адрес содержит подстановки вида {1} и {name}, заполняемые из запроса
+	Rules        []Rule          // Rules правила выбора адреса перехода по запросу, проверяются по порядку
+	Variants     []Variant       // Variants адреса для A/B-теста, если не подошло ни одно правило
+
+	Meta
+
+	CreatedAt time.Time // CreatedAt время создания, задается хранилищем и заполняется LoadLink и LoadUserLinks
+	Clicks    int64     // Clicks число сохраненных переходов, заполняется LoadUserLinks
+	Variant   string    // Variant название варианта, выбранного при переходе, заполняется приложением
+}
+
+// Meta описание ссылки, которое задает ее владелец
+type Meta struct {
+	Title string   // Title название ссылки
+	Notes string   // Notes произвольные заметки
+	Tags  []string // Tags метки для группировки ссылок
+}
+
+// HasTag сообщает, отмечена ли ссылка меткой tag
+func (m Meta) HasTag(tag string) bool {
+	for _, t := range m.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// LinkOrder поле, по которому упорядочиваются ссылки пользователя
+type LinkOrder int
+
+// Порядок ссылок пользователя. Ссылки с одинаковым значением поля упорядочиваются по идентификатору.
+const (
+	OrderCreated LinkOrder = iota // OrderCreated по времени создания
+	OrderClicks                   // OrderClicks по числу переходов
+)
+
+// LinkFilter условия отбора и порядок ссылок пользователя
+type LinkFilter struct {
+	Tag    string // Tag метка, которая должна быть у ссылки, пустая - любая
+	Search string // Search подстрока адреса или названия без учета регистра, пустая - любые
+
+	Order LinkOrder
+	Desc  bool        // Desc обратный порядок
+	After *LinkCursor // After позиция последней ссылки предыдущей страницы, nil - с начала
+	Limit int         // Limit наибольшее число ссылок, 0 - без ограничения
+}
+
+// LinkCursor позиция ссылки в упорядоченном списке
+type LinkCursor struct {
+	Key int64  // Key значение поля сортировки: время создания в наносекундах или число переходов
+	ID  string // ID идентификатор ссылки
+}
+
+// Match сообщает, подходит ли ссылка под условия
+func (f LinkFilter) Match(link *Link) bool {
+	if f.Tag != "" && !link.HasTag(f.Tag) {
+		return false
+	}
+	if f.Search == "" {
+		return true
+	}
+	search := strings.ToLower(f.Search)
+	return strings.Contains(strings.ToLower(link.URL.String()), search) ||
+		strings.Contains(strings.ToLower(link.Title), search)
+}
+
+// Cursor возвращает позицию ссылки в порядке f
+func (f LinkFilter) Cursor(link *Link) LinkCursor {
+	if f.Order == OrderClicks {
+		return LinkCursor{Key: link.Clicks, ID: link.ID}
+	}
+	return LinkCursor{Key: link.CreatedAt.UnixNano(), ID: link.ID}
+}
+
+// before сообщает, стоит ли позиция a раньше b в порядке f
+func (f LinkFilter) before(a, b LinkCursor) bool {
+	if f.Desc {
+		a, b = b, a
+	}
+	return a.Key < b.Key || a.Key == b.Key && a.ID < b.ID
+}
+
+// page упорядочивает ссылки, отобранные Match, и возвращает страницу после f.After
+func (f LinkFilter) page(links []Link) []Link {
+	sort.Slice(links, func(i, j int) bool { return f.before(f.Cursor(&links[i]), f.Cursor(&links[j])) })
+	if f.After != nil {
+		start := sort.Search(len(links), func(i int) bool { return f.before(*f.After, f.Cursor(&links[i])) })
+		links = links[start:]
+	}
+	if f.Limit > 0 && len(links) > f.Limit {
+		links = links[:f.Limit]
+	}
+	return links
+}
+
+// clicksLeft возвращает значение остатка переходов для записи в базу, NULL - без ограничения
+func clicksLeft(link Link) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(link.ClicksLeft), Valid: link.ClicksLeft > 0}
+}
+
+// Revision прежний адрес ссылки
+type Revision struct {
+	URL        *url.URL  // URL адрес, на который вела ссылка
+	ReplacedAt time.Time // ReplacedAt время, когда адрес был заменен
+}
+
+// LinkUpdate изменения ссылки пользователя, nil поля не меняются
+type LinkUpdate struct {
+	URL   *url.URL // URL новый адрес, прежний сохраняется в истории, как в UpdateUser
+	Meta  *Meta    // Meta новое описание
+	Rules *[]Rule  // Rules новые правила перехода, пустой список удаляет их
+}
+
+// Click переход по короткой ссылке
+type Click struct {
+	Time      time.Time // Time время перехода
+	ID        string    // ID короткий идентификатор ссылки
+	Referrer  string    // Referrer адрес страницы, с которой пришел пользователь
+	UserAgent string    // UserAgent клиент пользователя
+	IPHash    string    // IPHash хеш адреса клиента, сам адрес не сохраняется
+	Variant   string    // Variant название варианта ссылки с A/B-тестом, пустое для обычной ссылки
+}
+
+// filterClicks выбирает переходы по ссылке id с from включительно по to не включительно
+func filterClicks(clicks []Click, id string, from, to time.Time) []Click {
+	var res []Click
+	for _, c := range clicks {
+		if c.ID == id && !c.Time.Before(from) && c.Time.Before(to) {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+// keepClicks возвращает новый срез с переходами, для которых keep вернула true.
+// Исходный срез не меняется: его может разделять снимок хранилища.
+func keepClicks(clicks []Click, keep func(Click) bool) []Click {
+	var res []Click
+	for _, c := range clicks {
+		if keep(c) {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+// Expired сообщает, истек ли срок действия ссылки к моменту now
+func (l Link) Expired(now time.Time) bool {
+	return l.ExpiresAt != nil && !now.Before(*l.ExpiresAt)
 }
@@ -0,0 +1,1101 @@
+// Package storetest содержит общий набор проверок поведения для любой реализации store.AuthStore.
+//
+// Новое хранилище подключается к проверкам одной строкой в тестах:
+//
+//	func TestInMemory_Conformance(t *testing.T) {
+//		storetest.Run(t, func(t *testing.T) store.AuthStore {
+//			return store.NewInMemory()
+//		})
+//	}
+package storetest
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/store"
+)
+
+// Factory создает новое хранилище для очередной проверки.
+// Хранилище закрывается по завершении проверки.
+type Factory func(t *testing.T) store.AuthStore
+
+// Run проверяет, что хранилище, созданное factory, соблюдает общий контракт AuthStore.
+// Проверки не рассчитывают на пустое хранилище, поэтому factory может
+// возвращать хранилища поверх одной и той же БД.
+func Run(t *testing.T, factory Factory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s store.AuthStore)
+	}{
+		{"SaveLoad", testSaveLoad},
+		{"SaveConflict", testSaveConflict},
+		{"LoadNotFound", testLoadNotFound},
+		{"SaveBatch", testSaveBatch},
+		{"SaveUser", testSaveUser},
+		{"SaveUserBatch", testSaveUserBatch},
+		{"LoadUsers", testLoadUsers},
+		{"DeleteUsers", testDeleteUsers},
+		{"SaveAfterDelete", testSaveAfterDelete},
+		{"Statistics", testStatistics},
+		{"ConcurrentSave", testConcurrentSave},
+		{"SaveLink", testSaveLink},
+		{"SaveLinkIDTaken", testSaveLinkIDTaken},
+		{"SaveLinkNoDedup", testSaveLinkNoDedup},
+		{"SaveLinkDomain", testSaveLinkDomain},
+		{"LoadLink", testLoadLink},
+		{"MaxClicks", testMaxClicks},
+		{"MaxClicksConcurrent", testMaxClicksConcurrent},
+		{"UpdateUser", testUpdateUser},
+		{"UpdateUserErrors", testUpdateUserErrors},
+		{"SetMeta", testSetMeta},
+		{"SetRules", testSetRules},
+		{"UpdateLink", testUpdateLink},
+		{"LoadUserLinks", testLoadUserLinks},
+		{"LoadUserLinksOrder", testLoadUserLinksOrder},
+		{"LoadUserLinksPages", testLoadUserLinksPages},
+		{"Expiry", testExpiry},
+		{"PurgeExpired", testPurgeExpired},
+		{"SaveClicks", testSaveClicks},
+		{"SaveClicksLargeBatch", testSaveClicksLargeBatch},
+		{"PurgeClicks", testPurgeClicks},
+		{"Ping", testPing},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := factory(t)
+			defer func() {
+				assert.NoError(t, s.Close())
+			}()
+			tt.fn(t, s)
+		})
+	}
+}
+
+// newURL возвращает уникальную ссылку, чтобы проверки не конфликтовали между собой
+func newURL(t *testing.T) *url.URL {
+	u, err := url.Parse("https://practicum.yandex.ru/" + uuid.Must(uuid.NewV4()).String())
+	require.NoError(t, err)
+	return u
+}
+
+func newURLs(t *testing.T, n int) []*url.URL {
+	urls := make([]*url.URL, 0, n)
+	for i := 0; i < n; i++ {
+		urls = append(urls, newURL(t))
+	}
+	return urls
+}
+
+func testSaveLoad(t *testing.T, s store.AuthStore) {
+	ctx := context.Background()
+	u := newURL(t)
+
+	id, err := s.Save(ctx, u)
+	require.NoError(t, err)
+	require.NotEmpty(t, id)
+
+	got, err := s.Load(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, u.String(), got.String())
+}
+
+func testSaveConflict(t *testing.T, s store.AuthStore) {
+	ctx := context.Background()
+	u := newURL(t)
+
+	id, err := s.Save(ctx, u)
+	require.NoError(t, err)
+
+	conflictID, err := s.Save(ctx, u)
+	assert.ErrorIs(t, err, store.ErrConflict)
+	assert.Equal(t, id, conflictID)
+
+	conflictID, err = s.SaveUser(ctx, uuid.Must(uuid.NewV4()), u)
+	assert.ErrorIs(t, err, store.ErrConflict)
+	assert.Equal(t, id, conflictID)
+}
+
+func testLoadNotFound(t *testing.T, s store.AuthStore) {
+	ctx := context.Background()
+	uid := uuid.Must(uuid.NewV4())
+
+	_, err := s.Load(ctx, "404404404")
+	assert.ErrorIs(t, err, store.ErrNotFound)
+
+	_, err = s.LoadUser(ctx, uid, "404404404")
+	assert.ErrorIs(t, err, store.ErrNotFound)
+
+	_, err = s.LoadUsers(ctx, uid)
+	assert.ErrorIs(t, err, store.ErrNotFound)
+}
+
+func testSaveBatch(t *testing.T, s store.AuthStore) {
+	ctx := context.Background()
+	existing := newURL(t)
+	existingID, err := s.Save(ctx, existing)
+	require.NoError(t, err)
+
+	urls := newURLs(t, 5)
+	// duplicates inside the batch and already stored URLs resolve to a single link
+	batch := append([]*url.URL{existing}, urls...)
+	batch = append(batch, urls[0])
+
+	ids, err := s.SaveBatch(ctx, batch)
+	require.NoError(t, err)
+	require.Len(t, ids, len(batch))
+	assert.Equal(t, existingID, ids[0])
+	assert.Equal(t, ids[1], ids[len(ids)-1])
+
+	for i, id := range ids {
+		got, err := s.Load(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, batch[i].String(), got.String())
+	}
+}
+
+func testSaveUser(t *testing.T, s store.AuthStore) {
+	ctx := context.Background()
+	uid := uuid.Must(uuid.NewV4())
+	u := newURL(t)
+
+	id, err := s.SaveUser(ctx, uid, u)
+	require.NoError(t, err)
+	require.NotEmpty(t, id)
+
+	got, err := s.LoadUser(ctx, uid, id)
+	require.NoError(t, err)
+	assert.Equal(t, u.String(), got.String())
+
+	got, err = s.Load(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, u.String(), got.String())
+
+	_, err = s.LoadUser(ctx, uuid.Must(uuid.NewV4()), id)
+	assert.ErrorIs(t, err, store.ErrNotFound)
+}
+
+func testSaveUserBatch(t *testing.T, s store.AuthStore) {
+	ctx := context.Background()
+	uid := uuid.Must(uuid.NewV4())
+	urls := newURLs(t, 10)
+
+	ids, err := s.SaveUserBatch(ctx, uid, urls)
+	require.NoError(t, err)
+	require.Len(t, ids, len(urls))
+
+	for i, id := range ids {
+		got, err := s.LoadUser(ctx, uid, id)
+		require.NoError(t, err)
+		assert.Equal(t, urls[i].String(), got.String())
+	}
+
+	again, err := s.SaveUserBatch(ctx, uid, urls)
+	require.NoError(t, err)
+	assert.Equal(t, ids, again)
+}
+
+func testLoadUsers(t *testing.T, s store.AuthStore) {
+	ctx := context.Background()
+	uid := uuid.Must(uuid.NewV4())
+	urls := newURLs(t, 3)
+
+	ids, err := s.SaveUserBatch(ctx, uid, urls)
+	require.NoError(t, err)
+	_, err = s.SaveUser(ctx, uuid.Must(uuid.NewV4()), newURL(t))
+	require.NoError(t, err)
+	_, err = s.Save(ctx, newURL(t))
+	require.NoError(t, err)
+
+	got, err := s.LoadUsers(ctx, uid)
+	require.NoError(t, err)
+	require.Len(t, got, len(urls))
+	for i, id := range ids {
+		require.Contains(t, got, id)
+		assert.Equal(t, urls[i].String(), got[id].String())
+	}
+}
+
+func testDeleteUsers(t *testing.T, s store.AuthStore) {
+	ctx := context.Background()
+	uid := uuid.Must(uuid.NewV4())
+	other := uuid.Must(uuid.NewV4())
+
+	ids, err := s.SaveUserBatch(ctx, uid, newURLs(t, 3))
+	require.NoError(t, err)
+	otherID, err := s.SaveUser(ctx, other, newURL(t))
+	require.NoError(t, err)
+
+	err = s.DeleteUsers(ctx, uid, ids[0], otherID, "404404404")
+	require.NoError(t, err)
+
+	_, err = s.Load(ctx, ids[0])
+	assert.ErrorIs(t, err, store.ErrDeleted)
+	_, err = s.LoadUser(ctx, uid, ids[0])
+	assert.ErrorIs(t, err, store.ErrDeleted)
+
+	got, err := s.LoadUsers(ctx, uid)
+	require.NoError(t, err)
+	assert.Len(t, got, 2)
+	assert.NotContains(t, got, ids[0])
+
+	// links of other users are left untouched
+	_, err = s.Load(ctx, otherID)
+	assert.NoError(t, err)
+
+	require.NoError(t, s.DeleteUsers(ctx, uid, ids[1:]...))
+	_, err = s.LoadUsers(ctx, uid)
+	assert.ErrorIs(t, err, store.ErrNotFound)
+}
+
+func testSaveAfterDelete(t *testing.T, s store.AuthStore) {
+	ctx := context.Background()
+	uid := uuid.Must(uuid.NewV4())
+	u := newURL(t)
+
+	id, err := s.SaveUser(ctx, uid, u)
+	require.NoError(t, err)
+	require.NoError(t, s.DeleteUsers(ctx, uid, id))
+
+	newID, err := s.SaveUser(ctx, uid, u)
+	require.NoError(t, err)
+	assert.NotEqual(t, id, newID)
+
+	_, err = s.Load(ctx, id)
+	assert.ErrorIs(t, err, store.ErrDeleted)
+	got, err := s.Load(ctx, newID)
+	require.NoError(t, err)
+	assert.Equal(t, u.String(), got.String())
+}
+
+func testStatistics(t *testing.T, s store.AuthStore) {
+	ctx := context.Background()
+	urls, users := s.Urls(ctx), s.Users(ctx)
+
+	uid := uuid.Must(uuid.NewV4())
+	ids, err := s.SaveUserBatch(ctx, uid, newURLs(t, 3))
+	require.NoError(t, err)
+	_, err = s.Save(ctx, newURL(t))
+	require.NoError(t, err)
+	require.NoError(t, s.DeleteUsers(ctx, uid, ids[0]))
+
+	assert.Equal(t, urls+4, s.Urls(ctx))
+	assert.Equal(t, users+1, s.Users(ctx))
+}
+
+func testConcurrentSave(t *testing.T, s store.AuthStore) {
+	ctx := context.Background()
+	uid := uuid.Must(uuid.NewV4())
+	urls := newURLs(t, 20)
+
+	var wg sync.WaitGroup
+	ids := make([]string, len(urls))
+	errs := make([]error, len(urls))
+	for i := range urls {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ids[i], errs[i] = s.SaveUser(ctx, uid, urls[i])
+		}(i)
+	}
+	wg.Wait()
+
+	seen := make(map[string]bool)
+	for i, id := range ids {
+		require.NoError(t, errs[i])
+		assert.False(t, seen[id], fmt.Sprintf("duplicate id %s", id))
+		seen[id] = true
+
+		got, err := s.Load(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, urls[i].String(), got.String())
+	}
+}
+
+// newAlias возвращает уникальный пользовательский идентификатор
+func newAlias() string {
+	return "alias-" + uuid.Must(uuid.NewV4()).String()[:8]
+}
+
+func testSaveLink(t *testing.T, s store.AuthStore) {
+	ctx := context.Background()
+	uid := uuid.Must(uuid.NewV4())
+	u := newURL(t)
+	alias := newAlias()
+
+	id, err := s.SaveLink(ctx, store.Link{ID: alias, URL: u, UserID: &uid})
+	require.NoError(t, err)
+	assert.Equal(t, alias, id)
+
+	got, err := s.Load(ctx, alias)
+	require.NoError(t, err)
+	assert.Equal(t, u.String(), got.String())
+
+	got, err = s.LoadUser(ctx, uid, alias)
+	require.NoError(t, err)
+	assert.Equal(t, u.String(), got.String())
+
+	links, err := s.LoadUsers(ctx, uid)
+	require.NoError(t, err)
+	assert.Contains(t, links, alias)
+
+	// without an id the store generates one
+	generated, err := s.SaveLink(ctx, store.Link{URL: newURL(t)})
+	require.NoError(t, err)
+	assert.NotEmpty(t, generated)
+	_, err = s.Load(ctx, generated)
+	assert.NoError(t, err)
+
+	require.NoError(t, s.DeleteUsers(ctx, uid, alias))
+	_, err = s.Load(ctx, alias)
+	assert.ErrorIs(t, err, store.ErrDeleted)
+}
+
+func testSaveLinkIDTaken(t *testing.T, s store.AuthStore) {
+	ctx := context.Background()
+	alias := newAlias()
+
+	_, err := s.SaveLink(ctx, store.Link{ID: alias, URL: newURL(t)})
+	require.NoError(t, err)
+	_, err = s.SaveLink(ctx, store.Link{ID: alias, URL: newURL(t)})
+	assert.ErrorIs(t, err, store.ErrIDTaken)
+
+	// generated ids are taken as well
+	id, err := s.Save(ctx, newURL(t))
+	require.NoError(t, err)
+	_, err = s.SaveLink(ctx, store.Link{ID: id, URL: newURL(t)})
+	assert.ErrorIs(t, err, store.ErrIDTaken)
+}
+
+func testSaveLinkDomain(t *testing.T, s store.AuthStore) {
+	ctx := context.Background()
+	uid := uuid.Must(uuid.NewV4())
+	alias := newAlias()
+	first, second := newURL(t), newURL(t)
+
+	id, err := s.SaveLink(ctx, store.Link{ID: alias, URL: first})
+	require.NoError(t, err)
+	assert.Equal(t, alias, id)
+
+	// the same code on another domain is a different link
+	other, err := s.SaveLink(ctx, store.Link{ID: alias, Domain: "b.example", URL: second, UserID: &uid})
+	require.NoError(t, err)
+	assert.Equal(t, store.DomainID("b.example", alias), other)
+	_, err = s.SaveLink(ctx, store.Link{ID: alias, Domain: "b.example", URL: newURL(t)})
+	assert.ErrorIs(t, err, store.ErrIDTaken)
+
+	got, err := s.Load(ctx, alias)
+	require.NoError(t, err)
+	assert.Equal(t, first.String(), got.String())
+	got, err = s.Load(ctx, other)
+	require.NoError(t, err)
+	assert.Equal(t, second.String(), got.String())
+	links, err := s.LoadUsers(ctx, uid)
+	require.NoError(t, err)
+	assert.Contains(t, links, other)
+
+	generated, err := s.SaveLink(ctx, store.Link{Domain: "b.example", URL: newURL(t)})
+	require.NoError(t, err)
+	domain, code := store.SplitID(generated)
+	assert.Equal(t, "b.example", domain)
+	assert.NotEmpty(t, code)
+	_, err = s.LoadLink(ctx, generated)
+	assert.NoError(t, err)
+}
+
+func testSaveLinkNoDedup(t *testing.T, s store.AuthStore) {
+	ctx := context.Background()
+	u := newURL(t)
+
+	aliasID, err := s.SaveLink(ctx, store.Link{ID: newAlias(), URL: u})
+	require.NoError(t, err)
+
+	// a plain link for the same address is created separately
+	id, err := s.Save(ctx, u)
+	require.NoError(t, err)
+	assert.NotEqual(t, aliasID, id)
+
+	otherID, err := s.SaveLink(ctx, store.Link{ID: newAlias(), URL: u})
+	require.NoError(t, err)
+	assert.NotEqual(t, aliasID, otherID)
+
+	conflictID, err := s.Save(ctx, u)
+	assert.ErrorIs(t, err, store.ErrConflict)
+	assert.Equal(t, id, conflictID)
+}
+
+func testLoadLink(t *testing.T, s store.AuthStore) {
+	ctx := context.Background()
+	uid := uuid.Must(uuid.NewV4())
+	u := newURL(t)
+	future := time.Now().Add(time.Hour)
+
+	id, err := s.SaveLink(ctx, store.Link{URL: u, UserID: &uid, ExpiresAt: &future, PasswordHash: "hash", ClicksLeft: 3,
+		Redirect: store.RedirectInterstitial, Passthrough: store.PassthroughAll, Template: true, Rules: testRules,
+		Variants: testVariants})
+	require.NoError(t, err)
+	link, err := s.LoadLink(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, id, link.ID)
+	assert.Equal(t, u.String(), link.URL.String())
+	require.NotNil(t, link.UserID)
+	assert.Equal(t, uid, *link.UserID)
+	require.NotNil(t, link.ExpiresAt)
+	assert.WithinDuration(t, future, *link.ExpiresAt, time.Millisecond)
+	assert.Equal(t, "hash", link.PasswordHash)
+	assert.Equal(t, 3, link.ClicksLeft)
+	assert.Equal(t, store.RedirectInterstitial, link.Redirect)
+	assert.Equal(t, store.PassthroughAll, link.Passthrough)
+	assert.True(t, link.Template)
+	assert.Equal(t, testRules, link.Rules)
+	assert.Equal(t, testVariants, link.Variants)
+	assert.WithinDuration(t, time.Now(), link.CreatedAt, time.Minute)
+
+	plainURL := newURL(t)
+	plain, err := s.Save(ctx, plainURL)
+	require.NoError(t, err)
+	link, err = s.LoadLink(ctx, plain)
+	require.NoError(t, err)
+	assert.Equal(t, plainURL.String(), link.URL.String())
+	assert.Empty(t, link.PasswordHash)
+	assert.Zero(t, link.ClicksLeft)
+	assert.Empty(t, link.Redirect)
+	assert.Empty(t, link.Passthrough)
+	assert.False(t, link.Template)
+	assert.Empty(t, link.Rules)
+	assert.Empty(t, link.Variants)
+	assert.WithinDuration(t, time.Now(), link.CreatedAt, time.Minute)
+
+	_, err = s.LoadLink(ctx, newAlias())
+	assert.ErrorIs(t, err, store.ErrNotFound)
+
+	past := time.Now().Add(-time.Minute)
+	expired, err := s.SaveLink(ctx, store.Link{URL: newURL(t), ExpiresAt: &past})
+	require.NoError(t, err)
+	_, err = s.LoadLink(ctx, expired)
+	assert.ErrorIs(t, err, store.ErrExpired)
+
+	require.NoError(t, s.DeleteUsers(ctx, uid, id))
+	_, err = s.LoadLink(ctx, id)
+	assert.ErrorIs(t, err, store.ErrDeleted)
+}
+
+func testMaxClicks(t *testing.T, s store.AuthStore) {
+	ctx := context.Background()
+	uid := uuid.Must(uuid.NewV4())
+	u := newURL(t)
+
+	id, err := s.SaveLink(ctx, store.Link{URL: u, UserID: &uid, ClicksLeft: 2})
+	require.NoError(t, err)
+
+	// LoadLink does not count as an expansion
+	link, err := s.LoadLink(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, 2, link.ClicksLeft)
+
+	got, err := s.Load(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, u.String(), got.String())
+	link, err = s.LoadLink(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, 1, link.ClicksLeft)
+
+	_, err = s.Load(ctx, id)
+	require.NoError(t, err)
+
+	_, err = s.Load(ctx, id)
+	assert.ErrorIs(t, err, store.ErrDeleted)
+	_, err = s.LoadLink(ctx, id)
+	assert.ErrorIs(t, err, store.ErrDeleted)
+	_, err = s.LoadUser(ctx, uid, id)
+	assert.ErrorIs(t, err, store.ErrDeleted)
+
+	// burn after reading
+	once, err := s.SaveLink(ctx, store.Link{URL: u, ClicksLeft: 1})
+	require.NoError(t, err)
+	_, err = s.Load(ctx, once)
+	require.NoError(t, err)
+	_, err = s.Load(ctx, once)
+	assert.ErrorIs(t, err, store.ErrDeleted)
+
+	// unlimited links are not affected
+	plain, err := s.SaveLink(ctx, store.Link{URL: u})
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		_, err = s.Load(ctx, plain)
+		require.NoError(t, err)
+	}
+}
+
+func testMaxClicksConcurrent(t *testing.T, s store.AuthStore) {
+	ctx := context.Background()
+	const clicks, loads = 5, 20
+
+	id, err := s.SaveLink(ctx, store.Link{URL: newURL(t), ClicksLeft: clicks})
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	errs := make([]error, loads)
+	for i := 0; i < loads; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = s.Load(ctx, id)
+		}(i)
+	}
+	wg.Wait()
+
+	var succeeded int
+	for _, err := range errs {
+		if err == nil {
+			succeeded++
+			continue
+		}
+		assert.ErrorIs(t, err, store.ErrDeleted)
+	}
+	assert.Equal(t, clicks, succeeded)
+}
+
+func testUpdateUser(t *testing.T, s store.AuthStore) {
+	ctx := context.Background()
+	uid := uuid.Must(uuid.NewV4())
+	urls := newURLs(t, 3)
+
+	id, err := s.SaveUser(ctx, uid, urls[0])
+	require.NoError(t, err)
+	history, err := s.LoadHistory(ctx, uid, id)
+	require.NoError(t, err)
+	assert.Empty(t, history)
+
+	require.NoError(t, s.UpdateUser(ctx, uid, id, urls[1]))
+	require.NoError(t, s.UpdateUser(ctx, uid, id, urls[2]))
+
+	got, err := s.Load(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, urls[2].String(), got.String())
+	got, err = s.LoadUser(ctx, uid, id)
+	require.NoError(t, err)
+	assert.Equal(t, urls[2].String(), got.String())
+	links, err := s.LoadUsers(ctx, uid)
+	require.NoError(t, err)
+	assert.Equal(t, urls[2].String(), links[id].String())
+
+	history, err = s.LoadHistory(ctx, uid, id)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, urls[0].String(), history[0].URL.String())
+	assert.Equal(t, urls[1].String(), history[1].URL.String())
+	assert.False(t, history[1].ReplacedAt.Before(history[0].ReplacedAt))
+
+	// the original address is free again
+	other, err := s.Save(ctx, urls[0])
+	require.NoError(t, err)
+	assert.NotEqual(t, id, other)
+	// and the edited link does not take over the new one
+	_, err = s.Save(ctx, urls[2])
+	assert.NoError(t, err)
+
+	// links with parameters can be edited as well
+	future := time.Now().Add(time.Hour)
+	linkID, err := s.SaveLink(ctx, store.Link{URL: urls[0], UserID: &uid, ExpiresAt: &future})
+	require.NoError(t, err)
+	require.NoError(t, s.UpdateUser(ctx, uid, linkID, urls[1]))
+	link, err := s.LoadLink(ctx, linkID)
+	require.NoError(t, err)
+	assert.Equal(t, urls[1].String(), link.URL.String())
+	require.NotNil(t, link.ExpiresAt)
+}
+
+func testUpdateUserErrors(t *testing.T, s store.AuthStore) {
+	ctx := context.Background()
+	uid := uuid.Must(uuid.NewV4())
+	stranger := uuid.Must(uuid.NewV4())
+	u := newURL(t)
+
+	id, err := s.SaveUser(ctx, uid, u)
+	require.NoError(t, err)
+
+	err = s.UpdateUser(ctx, stranger, id, newURL(t))
+	assert.ErrorIs(t, err, store.ErrNotFound)
+	_, err = s.LoadHistory(ctx, stranger, id)
+	assert.ErrorIs(t, err, store.ErrNotFound)
+	err = s.UpdateUser(ctx, uid, newAlias(), newURL(t))
+	assert.ErrorIs(t, err, store.ErrNotFound)
+
+	got, err := s.Load(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, u.String(), got.String())
+
+	require.NoError(t, s.DeleteUsers(ctx, uid, id))
+	err = s.UpdateUser(ctx, uid, id, newURL(t))
+	assert.ErrorIs(t, err, store.ErrDeleted)
+
+	past := time.Now().Add(-time.Minute)
+	expired, err := s.SaveLink(ctx, store.Link{URL: newURL(t), UserID: &uid, ExpiresAt: &past})
+	require.NoError(t, err)
+	err = s.UpdateUser(ctx, uid, expired, newURL(t))
+	assert.ErrorIs(t, err, store.ErrExpired)
+}
+
+func testSetMeta(t *testing.T, s store.AuthStore) {
+	ctx := context.Background()
+	uid := uuid.Must(uuid.NewV4())
+	meta := store.Meta{Title: "Course", Notes: "printed on the flyer", Tags: []string{"go", "print"}}
+
+	// both plain links and links with parameters can be described
+	plain, err := s.SaveUser(ctx, uid, newURL(t))
+	require.NoError(t, err)
+	require.NoError(t, s.SetMeta(ctx, uid, plain, meta))
+	link, err := s.LoadLink(ctx, plain)
+	require.NoError(t, err)
+	assert.Equal(t, meta, link.Meta)
+	got, err := s.Load(ctx, plain)
+	require.NoError(t, err)
+	assert.Equal(t, link.URL.String(), got.String())
+
+	custom, err := s.SaveLink(ctx, store.Link{URL: newURL(t), UserID: &uid, Meta: meta})
+	require.NoError(t, err)
+	link, err = s.LoadLink(ctx, custom)
+	require.NoError(t, err)
+	assert.Equal(t, meta, link.Meta)
+
+	require.NoError(t, s.SetMeta(ctx, uid, custom, store.Meta{Title: "Renamed"}))
+	link, err = s.LoadLink(ctx, custom)
+	require.NoError(t, err)
+	assert.Equal(t, "Renamed", link.Title)
+	assert.Empty(t, link.Notes)
+	assert.Empty(t, link.Tags)
+
+	err = s.SetMeta(ctx, uuid.Must(uuid.NewV4()), plain, meta)
+	assert.ErrorIs(t, err, store.ErrNotFound)
+	require.NoError(t, s.DeleteUsers(ctx, uid, plain))
+	err = s.SetMeta(ctx, uid, plain, meta)
+	assert.ErrorIs(t, err, store.ErrDeleted)
+}
+
+// testRules правила перехода со всеми видами условий
+var testRules = []store.Rule{
+	{Devices: []string{"ios"}, URL: "https://apps.apple.com/app/id1"},
+	{
+		Languages: []string{"ru", "pt-BR"},
+		Headers:   []store.HeaderMatch{{Name: "X-Beta", Value: "on"}, {Name: "Cookie"}},
+		Time:      &store.TimeWindow{Days: []string{"mon", "fri"}, From: "09:00", To: "18:00", Zone: "Europe/Moscow"},
+		URL:       "https://practicum.yandex.ru/ru",
+	},
+}
+
+// testVariants адреса A/B-теста с разными весами
+var testVariants = []store.Variant{
+	{Name: "A", URL: "https://practicum.yandex.ru/a", Weight: 3},
+	{Name: "B", URL: "https://practicum.yandex.ru/b", Weight: 1},
+}
+
+func testSetRules(t *testing.T, s store.AuthStore) {
+	ctx := context.Background()
+	uid := uuid.Must(uuid.NewV4())
+
+	// both plain links and links with parameters can get rules
+	plain, err := s.SaveUser(ctx, uid, newURL(t))
+	require.NoError(t, err)
+	require.NoError(t, s.SetRules(ctx, uid, plain, testRules))
+	link, err := s.LoadLink(ctx, plain)
+	require.NoError(t, err)
+	assert.Equal(t, testRules, link.Rules)
+
+	custom, err := s.SaveLink(ctx, store.Link{URL: newURL(t), UserID: &uid, Meta: store.Meta{Title: "App"}})
+	require.NoError(t, err)
+	require.NoError(t, s.SetRules(ctx, uid, custom, testRules[:1]))
+	links, err := s.LoadUserLinks(ctx, uid, store.LinkFilter{})
+	require.NoError(t, err)
+	require.Len(t, links, 2)
+	for _, l := range links {
+		if l.ID == custom {
+			assert.Equal(t, testRules[:1], l.Rules)
+			assert.Equal(t, "App", l.Title, "rules leave the description alone")
+		} else {
+			assert.Equal(t, testRules, l.Rules)
+		}
+	}
+
+	require.NoError(t, s.SetRules(ctx, uid, custom, nil))
+	link, err = s.LoadLink(ctx, custom)
+	require.NoError(t, err)
+	assert.Empty(t, link.Rules)
+
+	err = s.SetRules(ctx, uuid.Must(uuid.NewV4()), plain, nil)
+	assert.ErrorIs(t, err, store.ErrNotFound)
+	require.NoError(t, s.DeleteUsers(ctx, uid, plain))
+	err = s.SetRules(ctx, uid, plain, nil)
+	assert.ErrorIs(t, err, store.ErrDeleted)
+}
+
+func testUpdateLink(t *testing.T, s store.AuthStore) {
+	ctx := context.Background()
+	uid := uuid.Must(uuid.NewV4())
+	urls := newURLs(t, 3)
+
+	id, err := s.SaveUser(ctx, uid, urls[0])
+	require.NoError(t, err)
+	meta := store.Meta{Title: "Course", Tags: []string{"go"}}
+	require.NoError(t, s.UpdateLink(ctx, uid, id, store.LinkUpdate{URL: urls[1], Meta: &meta, Rules: &testRules}))
+
+	link, err := s.LoadLink(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, urls[1].String(), link.URL.String())
+	assert.Equal(t, meta, link.Meta)
+	assert.Equal(t, testRules, link.Rules)
+	history, err := s.LoadHistory(ctx, uid, id)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, urls[0].String(), history[0].URL.String())
+
+	// unset fields stay as they are
+	require.NoError(t, s.UpdateLink(ctx, uid, id, store.LinkUpdate{Rules: &[]store.Rule{}}))
+	require.NoError(t, s.UpdateLink(ctx, uid, id, store.LinkUpdate{}))
+	link, err = s.LoadLink(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, urls[1].String(), link.URL.String())
+	assert.Equal(t, meta, link.Meta)
+	assert.Empty(t, link.Rules)
+
+	// nothing changes on a link that cannot be edited
+	require.NoError(t, s.DeleteUsers(ctx, uid, id))
+	err = s.UpdateLink(ctx, uid, id, store.LinkUpdate{URL: urls[2], Meta: &store.Meta{Title: "Gone"}})
+	assert.ErrorIs(t, err, store.ErrDeleted)
+	history, err = s.LoadHistory(ctx, uid, id)
+	require.NoError(t, err)
+	assert.Len(t, history, 1)
+	err = s.UpdateLink(ctx, uuid.Must(uuid.NewV4()), id, store.LinkUpdate{URL: urls[2]})
+	assert.ErrorIs(t, err, store.ErrNotFound)
+}
+
+func testLoadUserLinks(t *testing.T, s store.AuthStore) {
+	ctx := context.Background()
+	uid := uuid.Must(uuid.NewV4())
+	urls := newURLs(t, 3)
+
+	_, err := s.LoadUserLinks(ctx, uid, store.LinkFilter{})
+	assert.ErrorIs(t, err, store.ErrNotFound)
+
+	plain, err := s.SaveUser(ctx, uid, urls[0])
+	require.NoError(t, err)
+	work, err := s.SaveLink(ctx, store.Link{URL: urls[1], UserID: &uid, Meta: store.Meta{Title: "Work", Tags: []string{"work", "go"}}})
+	require.NoError(t, err)
+	home, err := s.SaveUser(ctx, uid, urls[2])
+	require.NoError(t, err)
+	require.NoError(t, s.SetMeta(ctx, uid, home, store.Meta{Tags: []string{"home", "go"}}))
+	deleted, err := s.SaveLink(ctx, store.Link{URL: newURL(t), UserID: &uid, Meta: store.Meta{Tags: []string{"go"}}})
+	require.NoError(t, err)
+	require.NoError(t, s.DeleteUsers(ctx, uid, deleted))
+	// other users' links are never listed
+	_, err = s.SaveLink(ctx, store.Link{URL: newURL(t), UserID: new(uuid.UUID), Meta: store.Meta{Tags: []string{"go"}}})
+	require.NoError(t, err)
+
+	ids := func(links []store.Link) (res []string) {
+		for _, l := range links {
+			res = append(res, l.ID)
+		}
+		return res
+	}
+
+	links, err := s.LoadUserLinks(ctx, uid, store.LinkFilter{})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{plain, work, home}, ids(links))
+	for _, l := range links {
+		if l.ID == work {
+			assert.Equal(t, "Work", l.Title)
+			assert.Equal(t, urls[1].String(), l.URL.String())
+		}
+	}
+
+	links, err = s.LoadUserLinks(ctx, uid, store.LinkFilter{Tag: "go"})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{work, home}, ids(links))
+
+	links, err = s.LoadUserLinks(ctx, uid, store.LinkFilter{Tag: "home"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{home}, ids(links))
+
+	links, err = s.LoadUserLinks(ctx, uid, store.LinkFilter{Tag: "missing"})
+	require.NoError(t, err)
+	assert.Empty(t, links)
+}
+
+func testLoadUserLinksOrder(t *testing.T, s store.AuthStore) {
+	ctx := context.Background()
+	uid := uuid.Must(uuid.NewV4())
+
+	// each link gets its own number of clicks, the first one is clicked most
+	var ids []string
+	var clicks []store.Click
+	for i := 0; i < 4; i++ {
+		var id string
+		var err error
+		if i%2 == 0 {
+			id, err = s.SaveUser(ctx, uid, newURL(t))
+		} else {
+			id, err = s.SaveLink(ctx, store.Link{URL: newURL(t), UserID: &uid, Meta: store.Meta{Title: "Link"}})
+		}
+		require.NoError(t, err)
+		ids = append(ids, id)
+		for j := 0; j < 4-i; j++ {
+			clicks = append(clicks, store.Click{ID: id, Time: time.Now()})
+		}
+		// keep creation times apart on stores with coarse clocks
+		time.Sleep(2 * time.Millisecond)
+	}
+	require.NoError(t, s.SaveClicks(ctx, clicks))
+
+	reversed := func(ids []string) []string {
+		res := make([]string, 0, len(ids))
+		for i := len(ids) - 1; i >= 0; i-- {
+			res = append(res, ids[i])
+		}
+		return res
+	}
+	tests := []struct {
+		name   string
+		filter store.LinkFilter
+		want   []string
+	}{
+		{name: "created", filter: store.LinkFilter{}, want: ids},
+		{name: "created desc", filter: store.LinkFilter{Desc: true}, want: reversed(ids)},
+		{name: "clicks", filter: store.LinkFilter{Order: store.OrderClicks}, want: reversed(ids)},
+		{name: "clicks desc", filter: store.LinkFilter{Order: store.OrderClicks, Desc: true}, want: ids},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			links, err := s.LoadUserLinks(ctx, uid, tt.filter)
+			require.NoError(t, err)
+			var got []string
+			for _, l := range links {
+				got = append(got, l.ID)
+			}
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, int64(4), links[0].Clicks+links[len(links)-1].Clicks-1)
+			for _, l := range links {
+				assert.False(t, l.CreatedAt.IsZero())
+			}
+		})
+	}
+}
+
+func testLoadUserLinksPages(t *testing.T, s store.AuthStore) {
+	ctx := context.Background()
+	uid := uuid.Must(uuid.NewV4())
+
+	var all []string
+	for i := 0; i < 5; i++ {
+		id, err := s.SaveUser(ctx, uid, newURL(t))
+		require.NoError(t, err)
+		all = append(all, id)
+	}
+	found, err := s.SaveLink(ctx, store.Link{
+		URL:    &url.URL{Scheme: "https", Host: "go.dev", Path: "/Doc/" + uuid.Must(uuid.NewV4()).String()},
+		UserID: &uid,
+	})
+	require.NoError(t, err)
+	all = append(all, found)
+	titled, err := s.SaveLink(ctx, store.Link{URL: newURL(t), UserID: &uid, Meta: store.Meta{Title: "Go docs"}})
+	require.NoError(t, err)
+	all = append(all, titled)
+
+	for _, desc := range []bool{false, true} {
+		for _, order := range []store.LinkOrder{store.OrderCreated, store.OrderClicks} {
+			filter := store.LinkFilter{Order: order, Desc: desc}
+			full, err := s.LoadUserLinks(ctx, uid, filter)
+			require.NoError(t, err)
+			require.Len(t, full, len(all))
+
+			// walking the pages yields the full list exactly once
+			var paged []store.Link
+			filter.Limit = 3
+			for {
+				page, err := s.LoadUserLinks(ctx, uid, filter)
+				require.NoError(t, err)
+				require.LessOrEqual(t, len(page), filter.Limit)
+				if len(page) == 0 {
+					break
+				}
+				paged = append(paged, page...)
+				cursor := filter.Cursor(&page[len(page)-1])
+				filter.After = &cursor
+			}
+			require.Len(t, paged, len(full))
+			for i := range full {
+				assert.Equal(t, full[i].ID, paged[i].ID)
+			}
+		}
+	}
+
+	// search is case-insensitive and looks at both the address and the title
+	links, err := s.LoadUserLinks(ctx, uid, store.LinkFilter{Search: "GO.dev/doc"})
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	assert.Equal(t, found, links[0].ID)
+
+	links, err = s.LoadUserLinks(ctx, uid, store.LinkFilter{Search: "go"})
+	require.NoError(t, err)
+	var got []string
+	for _, l := range links {
+		got = append(got, l.ID)
+	}
+	assert.ElementsMatch(t, []string{found, titled}, got)
+
+	links, err = s.LoadUserLinks(ctx, uid, store.LinkFilter{Search: "missing"})
+	require.NoError(t, err)
+	assert.Empty(t, links)
+}
+
+func testExpiry(t *testing.T, s store.AuthStore) {
+	ctx := context.Background()
+	uid := uuid.Must(uuid.NewV4())
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+
+	expired, err := s.SaveLink(ctx, store.Link{URL: newURL(t), UserID: &uid, ExpiresAt: &past})
+	require.NoError(t, err)
+	_, err = s.Load(ctx, expired)
+	assert.ErrorIs(t, err, store.ErrExpired)
+	_, err = s.LoadUser(ctx, uid, expired)
+	assert.ErrorIs(t, err, store.ErrExpired)
+
+	u := newURL(t)
+	active, err := s.SaveLink(ctx, store.Link{URL: u, ExpiresAt: &future})
+	require.NoError(t, err)
+	got, err := s.Load(ctx, active)
+	require.NoError(t, err)
+	assert.Equal(t, u.String(), got.String())
+}
+
+func testPurgeExpired(t *testing.T, s store.AuthStore) {
+	ctx := context.Background()
+	uid := uuid.Must(uuid.NewV4())
+	longAgo := time.Now().Add(-48 * time.Hour)
+	recently := time.Now().Add(-time.Minute)
+
+	old, err := s.SaveLink(ctx, store.Link{URL: newURL(t), UserID: &uid, ExpiresAt: &longAgo})
+	require.NoError(t, err)
+	fresh, err := s.SaveLink(ctx, store.Link{URL: newURL(t), ExpiresAt: &recently})
+	require.NoError(t, err)
+	plain, err := s.Save(ctx, newURL(t))
+	require.NoError(t, err)
+	require.NoError(t, s.SaveClicks(ctx, []store.Click{{Time: longAgo, ID: old}, {Time: longAgo, ID: plain}}))
+
+	n, err := s.PurgeExpired(ctx, time.Now().Add(-24*time.Hour))
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, n, int64(1))
+
+	_, err = s.Load(ctx, old)
+	assert.ErrorIs(t, err, store.ErrNotFound)
+	_, err = s.LoadUser(ctx, uid, old)
+	assert.ErrorIs(t, err, store.ErrNotFound)
+	_, err = s.Load(ctx, fresh)
+	assert.ErrorIs(t, err, store.ErrExpired)
+	_, err = s.Load(ctx, plain)
+	assert.NoError(t, err)
+
+	// clicks of purged links go away with them
+	clicks, err := s.LoadClicks(ctx, old, longAgo.Add(-time.Hour), time.Now())
+	require.NoError(t, err)
+	assert.Empty(t, clicks)
+	clicks, err = s.LoadClicks(ctx, plain, longAgo.Add(-time.Hour), time.Now())
+	require.NoError(t, err)
+	assert.Len(t, clicks, 1)
+}
+
+func testSaveClicks(t *testing.T, s store.AuthStore) {
+	ctx := context.Background()
+	id, err := s.Save(ctx, newURL(t))
+	require.NoError(t, err)
+
+	otherID, err := s.Save(ctx, newURL(t))
+	require.NoError(t, err)
+
+	now := time.Now().Truncate(time.Millisecond)
+	clicks := []store.Click{
+		{Time: now.Add(-2 * time.Hour), ID: id},
+		{Time: now.Add(-time.Hour), ID: id, Referrer: "https://ya.ru/", UserAgent: "curl/8.0", IPHash: "abc", Variant: "B"},
+		{Time: now, ID: id},
+		{Time: now.Add(-time.Hour), ID: otherID},
+	}
+	require.NoError(t, s.SaveClicks(ctx, clicks))
+	require.NoError(t, s.SaveClicks(ctx, nil))
+
+	got, err := s.LoadClicks(ctx, id, now.Add(-90*time.Minute), now)
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, id, got[0].ID)
+	assert.True(t, now.Add(-time.Hour).Equal(got[0].Time))
+	assert.Equal(t, "https://ya.ru/", got[0].Referrer)
+	assert.Equal(t, "curl/8.0", got[0].UserAgent)
+	assert.Equal(t, "abc", got[0].IPHash)
+	assert.Equal(t, "B", got[0].Variant)
+
+	got, err = s.LoadClicks(ctx, id, now.Add(-3*time.Hour), now.Add(time.Second))
+	require.NoError(t, err)
+	assert.Len(t, got, 3)
+
+	got, err = s.LoadClicks(ctx, "missing-"+id, now.Add(-3*time.Hour), now.Add(time.Second))
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+func testSaveClicksLargeBatch(t *testing.T, s store.AuthStore) {
+	ctx := context.Background()
+	id, err := s.Save(ctx, newURL(t))
+	require.NoError(t, err)
+
+	// more clicks than fit into a single statement
+	now := time.Now().Truncate(time.Millisecond)
+	clicks := make([]store.Click, 12000)
+	for i := range clicks {
+		clicks[i] = store.Click{Time: now, ID: id}
+	}
+	require.NoError(t, s.SaveClicks(ctx, clicks))
+
+	got, err := s.LoadClicks(ctx, id, now, now.Add(time.Second))
+	require.NoError(t, err)
+	assert.Len(t, got, len(clicks))
+}
+
+func testPurgeClicks(t *testing.T, s store.AuthStore) {
+	ctx := context.Background()
+	uid := uuid.Must(uuid.NewV4())
+	id, err := s.SaveUser(ctx, uid, newURL(t))
+	require.NoError(t, err)
+
+	now := time.Now().Truncate(time.Millisecond)
+	require.NoError(t, s.SaveClicks(ctx, []store.Click{
+		{Time: now.Add(-48 * time.Hour), ID: id},
+		{Time: now.Add(-25 * time.Hour), ID: id},
+		{Time: now, ID: id},
+	}))
+
+	n, err := s.PurgeClicks(ctx, now.Add(-24*time.Hour))
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, n, int64(2))
+
+	got, err := s.LoadClicks(ctx, id, now.Add(-72*time.Hour), now.Add(time.Second))
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.True(t, now.Equal(got[0].Time))
+
+	// the link keeps its total number of clicks
+	links, err := s.LoadUserLinks(ctx, uid, store.LinkFilter{})
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	assert.EqualValues(t, 3, links[0].Clicks)
+}
+
+func testPing(t *testing.T, s store.AuthStore) {
+	assert.NoError(t, s.Ping(context.Background()))
+}
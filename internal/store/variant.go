@@ -0,0 +1,37 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Variant один из адресов ссылки, между которыми переходы распределяются по весам
+type Variant struct {
+	Name   string `json:"name"`   // Name название варианта в статистике переходов
+	URL    string `json:"url"`    // URL адрес перехода
+	Weight int    `json:"weight"` // Weight доля переходов относительно суммы весов всех вариантов
+}
+
+// variantsJSON кодирует варианты для хранения в одном столбце
+func variantsJSON(variants []Variant) (string, error) {
+	if len(variants) == 0 {
+		return "[]", nil
+	}
+	b, err := json.Marshal(variants)
+	if err != nil {
+		return "", fmt.Errorf("cannot encode variants: %w", err)
+	}
+	return string(b), nil
+}
+
+// parseVariants разбирает варианты, сохраненные variantsJSON
+func parseVariants(raw []byte) ([]Variant, error) {
+	var variants []Variant
+	if err := json.Unmarshal(raw, &variants); err != nil {
+		return nil, fmt.Errorf("cannot decode variants: %w", err)
+	}
+	if len(variants) == 0 {
+		return nil, nil
+	}
+	return variants, nil
+}
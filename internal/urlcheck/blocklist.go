@@ -0,0 +1,163 @@
+package urlcheck
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/idna"
+)
+
+// patternPrefix префикс строки списка блокировки с регулярным выражением
+const patternPrefix = "re:"
+
+// domainProfile перевод доменов списка в punycode, как у хостов проверяемых адресов
+var domainProfile = idna.New(idna.MapForLookup(), idna.StrictDomainName(false))
+
+// Blocklist локальный список блокировки из файла. Каждая строка файла содержит домен
+// или регулярное выражение с префиксом "re:", пустые строки и строки с # пропускаются.
+// Домен запрещает и все свои поддомены, регулярное выражение проверяется на адресе целиком:
+//
+//	# phishing
+//	evil.example
+//	re:^https?://[^/]*paypa1\.
+type Blocklist struct {
+	path string
+
+	mu       sync.RWMutex
+	domains  map[string]bool
+	patterns []*regexp.Regexp
+	modTime  time.Time
+	size     int64
+}
+
+// NewBlocklist загружает список блокировки из файла path
+func NewBlocklist(path string) (*Blocklist, error) {
+	b := &Blocklist{path: path}
+	if err := b.Reload(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Reload перечитывает файл списка. Если файл не удалось прочитать или разобрать,
+// остается прежний список.
+func (b *Blocklist) Reload() error {
+	f, err := os.Open(b.path)
+	if err != nil {
+		return fmt.Errorf("cannot open blocklist: %w", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("cannot stat blocklist: %w", err)
+	}
+	domains, patterns, err := parseBlocklist(f)
+	if err != nil {
+		return fmt.Errorf("cannot parse blocklist %s: %w", b.path, err)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.domains, b.patterns = domains, patterns
+	b.modTime, b.size = info.ModTime(), info.Size()
+	return nil
+}
+
+// Watch раз в interval проверяет, изменился ли файл списка, и перечитывает его.
+// Работает до отмены контекста.
+func (b *Blocklist) Watch(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !b.changed() {
+				continue
+			}
+			if err := b.Reload(); err != nil {
+				log.Printf("cannot reload blocklist: %v\n", err)
+			}
+		}
+	}
+}
+
+// changed сообщает, отличаются ли время изменения или размер файла от загруженных
+func (b *Blocklist) changed() bool {
+	info, err := os.Stat(b.path)
+	if err != nil {
+		return false
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return !info.ModTime().Equal(b.modTime) || info.Size() != b.size
+}
+
+// Check запрещает адреса с хостом из списка или его поддоменом и адреса,
+// подходящие под одно из регулярных выражений
+func (b *Blocklist) Check(_ context.Context, u *url.URL) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	for domain := host; domain != ""; {
+		if b.domains[domain] {
+			return Blocked(fmt.Sprintf("domain %s is blocklisted", domain))
+		}
+		i := strings.IndexByte(domain, '.')
+		if i < 0 {
+			break
+		}
+		domain = domain[i+1:]
+	}
+	s := u.String()
+	for _, re := range b.patterns {
+		if re.MatchString(s) {
+			return Blocked("URL matches a blocklist pattern")
+		}
+	}
+	return nil
+}
+
+// parseBlocklist разбирает строки списка блокировки
+func parseBlocklist(r io.Reader) (map[string]bool, []*regexp.Regexp, error) {
+	domains := make(map[string]bool)
+	var patterns []*regexp.Regexp
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, patternPrefix) {
+			re, err := regexp.Compile(strings.TrimPrefix(line, patternPrefix))
+			if err != nil {
+				return nil, nil, fmt.Errorf("line %d: %w", n, err)
+			}
+			patterns = append(patterns, re)
+			continue
+		}
+		domain := strings.Trim(strings.TrimPrefix(line, "*."), ".")
+		ascii, err := domainProfile.ToASCII(domain)
+		if err != nil {
+			return nil, nil, fmt.Errorf("line %d: %q is not a valid domain", n, line)
+		}
+		domains[ascii] = true
+	}
+	if err := sc.Err(); err != nil {
+		return nil, nil, err
+	}
+	return domains, patterns, nil
+}
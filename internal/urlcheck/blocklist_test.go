@@ -0,0 +1,94 @@
+package urlcheck
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testBlocklist = `# phishing
+evil.example
+*.Bad.Example.
+пример.рф
+
+re:^https?://[^/]*paypa1\.
+`
+
+func writeBlocklist(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+}
+
+func TestBlocklist_Check(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	writeBlocklist(t, path, testBlocklist)
+	b, err := NewBlocklist(path)
+	require.NoError(t, err)
+
+	tests := []struct {
+		rawURL  string
+		blocked bool
+	}{
+		{rawURL: "https://evil.example/login", blocked: true},
+		{rawURL: "https://login.EVIL.example/", blocked: true},
+		{rawURL: "https://bad.example./", blocked: true},
+		{rawURL: "http://xn--e1afmkfd.xn--p1ai/", blocked: true},
+		{rawURL: "https://www.paypa1.com/", blocked: true},
+		{rawURL: "https://notevil.example/"},
+		{rawURL: "https://example/"},
+		{rawURL: "https://practicum.yandex.ru/?next=https://paypa1.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rawURL, func(t *testing.T) {
+			u, err := url.Parse(tt.rawURL)
+			require.NoError(t, err)
+			err = b.Check(context.Background(), u)
+			if tt.blocked {
+				assert.ErrorIs(t, err, ErrBlocked)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestNewBlocklist_Errors(t *testing.T) {
+	dir := t.TempDir()
+	_, err := NewBlocklist(filepath.Join(dir, "missing.txt"))
+	assert.Error(t, err)
+
+	path := filepath.Join(dir, "bad.txt")
+	writeBlocklist(t, path, "ok.example\nre:(\n")
+	_, err = NewBlocklist(path)
+	assert.ErrorContains(t, err, "line 2")
+}
+
+func TestBlocklist_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	writeBlocklist(t, path, "evil.example\n")
+	b, err := NewBlocklist(path)
+	require.NoError(t, err)
+	evil, _ := url.Parse("https://evil.example/")
+	worse, _ := url.Parse("https://worse.example/")
+
+	// a broken file keeps the previous list
+	writeBlocklist(t, path, "re:(\n")
+	assert.Error(t, b.Reload())
+	assert.ErrorIs(t, b.Check(context.Background(), evil), ErrBlocked)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go b.Watch(ctx, 10*time.Millisecond)
+
+	writeBlocklist(t, path, "worse.example\nand.more.example\n")
+	assert.Eventually(t, func() bool {
+		return b.Check(context.Background(), worse) != nil
+	}, time.Second, 10*time.Millisecond)
+	assert.NoError(t, b.Check(context.Background(), evil))
+}
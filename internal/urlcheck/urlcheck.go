@@ -0,0 +1,48 @@
+// Package urlcheck проверяет безопасность сокращаемых адресов.
+//
+// Проверки реализуют Checker и объединяются в Chain, которая выполняет их по порядку
+// до первой ошибки. Вместе с пакетом поставляется локальный список блокировки Blocklist,
+// внешние сервисы проверки подключаются через Func или собственную реализацию Checker.
+package urlcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// ErrBlocked адрес запрещен одной из проверок
+var ErrBlocked = errors.New("URL is blocked")
+
+// Checker проверка адреса. Check возвращает ошибку, оборачивающую ErrBlocked, если адрес запрещен,
+// и любую другую ошибку, если проверку не удалось выполнить.
+type Checker interface {
+	Check(ctx context.Context, u *url.URL) error
+}
+
+// Func функция проверки адреса, которую можно использовать как Checker
+type Func func(ctx context.Context, u *url.URL) error
+
+// Check вызывает f
+func (f Func) Check(ctx context.Context, u *url.URL) error {
+	return f(ctx, u)
+}
+
+// Chain проверки, которые выполняются по порядку до первой ошибки
+type Chain []Checker
+
+// Check проверяет адрес всеми проверками цепочки
+func (c Chain) Check(ctx context.Context, u *url.URL) error {
+	for _, checker := range c {
+		if err := checker.Check(ctx, u); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Blocked возвращает ошибку запрета адреса с указанием причины
+func Blocked(reason string) error {
+	return fmt.Errorf("%w: %s", ErrBlocked, reason)
+}
@@ -0,0 +1,40 @@
+package urlcheck
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChain_Check(t *testing.T) {
+	u, _ := url.Parse("https://practicum.yandex.ru/")
+	var calls []string
+	pass := func(name string) Checker {
+		return Func(func(context.Context, *url.URL) error {
+			calls = append(calls, name)
+			return nil
+		})
+	}
+	block := Func(func(_ context.Context, u *url.URL) error {
+		calls = append(calls, "block")
+		return Blocked(u.Hostname())
+	})
+
+	assert.NoError(t, Chain{}.Check(context.Background(), u))
+	assert.NoError(t, Chain{pass("a"), pass("b")}.Check(context.Background(), u))
+	assert.Equal(t, []string{"a", "b"}, calls)
+
+	calls = nil
+	err := Chain{pass("a"), block, pass("c")}.Check(context.Background(), u)
+	assert.ErrorIs(t, err, ErrBlocked)
+	assert.EqualError(t, err, "URL is blocked: practicum.yandex.ru")
+	assert.Equal(t, []string{"a", "block"}, calls)
+
+	failed := errors.New("service unavailable")
+	err = Chain{Func(func(context.Context, *url.URL) error { return failed })}.Check(context.Background(), u)
+	assert.ErrorIs(t, err, failed)
+	assert.NotErrorIs(t, err, ErrBlocked)
+}
@@ -0,0 +1,56 @@
+// Package urlchecktest содержит проверку адресов для тестов, которая работает в том же процессе.
+package urlchecktest
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/Yandex-Practicum/go-musthave-shortener-trainer/internal/urlcheck"
+)
+
+// Fake имитирует внешнюю проверку адресов: запрещает адреса с заблокированными хостами,
+// возвращает Err, если она задана, и запоминает проверенные адреса.
+type Fake struct {
+	Err error // Err ошибка, которую возвращает каждая проверка незапрещенного адреса
+
+	mu      sync.Mutex
+	hosts   map[string]bool
+	checked []string
+}
+
+// Block запрещает адреса с хостом host
+func (f *Fake) Block(host string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.hosts == nil {
+		f.hosts = make(map[string]bool)
+	}
+	f.hosts[strings.ToLower(host)] = true
+}
+
+// Unblock снимает запрет с хоста host
+func (f *Fake) Unblock(host string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.hosts, strings.ToLower(host))
+}
+
+// Checked возвращает проверенные адреса в порядке проверки
+func (f *Fake) Checked() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.checked...)
+}
+
+// Check реализует urlcheck.Checker
+func (f *Fake) Check(_ context.Context, u *url.URL) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.checked = append(f.checked, u.String())
+	if f.hosts[strings.ToLower(u.Hostname())] {
+		return urlcheck.Blocked("host " + u.Hostname() + " is blocked by fake checker")
+	}
+	return f.Err
+}
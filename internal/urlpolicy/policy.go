@@ -0,0 +1,190 @@
+// Package urlpolicy проверяет адреса сокращаемых ссылок и приводит их к каноническому виду.
+//
+// Адрес должен быть абсолютным, со схемой из разрешенного списка и хостом.
+// Схема и хост приводятся к нижнему регистру, интернациональные имена хостов
+// переводятся в punycode, порт по умолчанию для схемы и пустой путь отбрасываются,
+// так что HTTP://Example.com:80 и http://example.com/ дают один и тот же адрес.
+package urlpolicy
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// DefaultMaxLength наибольшая длина адреса, если в политике она не задана
+const DefaultMaxLength = 2048
+
+// DefaultSchemes схемы, разрешенные, если в политике они не заданы
+var DefaultSchemes = []string{"http", "https"}
+
+// Части адреса, к которым относятся ошибки
+const (
+	FieldURL    = "url"    // FieldURL адрес целиком
+	FieldScheme = "scheme" // FieldScheme схема
+	FieldHost   = "host"   // FieldHost хост
+)
+
+// defaultPorts порты, которые подразумеваются схемой и не указываются в каноническом адресе
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+	"ftp":   "21",
+	"ws":    "80",
+	"wss":   "443",
+}
+
+// hostProfile правила перевода имен хостов в ASCII. Подчеркивания, которые встречаются
+// в именах реальных хостов, в отличие от idna.Lookup допускаются.
+var hostProfile = idna.New(idna.MapForLookup(), idna.BidiRule(), idna.StrictDomainName(false))
+
+// trackingParams параметры запроса, которые только отслеживают переходы и не меняют страницу.
+// Параметры с префиксом utm_ удаляются все.
+var trackingParams = map[string]bool{
+	"fbclid":    true,
+	"gclid":     true,
+	"dclid":     true,
+	"msclkid":   true,
+	"yclid":     true,
+	"mc_cid":    true,
+	"mc_eid":    true,
+	"_openstat": true,
+}
+
+// Error нарушение политики в одной из частей адреса
+type Error struct {
+	Field  string // Field часть адреса: FieldURL, FieldScheme или FieldHost
+	Reason string // Reason описание нарушения
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("invalid URL %s: %s", e.Field, e.Reason)
+}
+
+// Policy правила проверки адресов. Нулевое значение разрешает DefaultSchemes
+// и адреса не длиннее DefaultMaxLength, параметры отслеживания сохраняются.
+type Policy struct {
+	Schemes       []string // Schemes разрешенные схемы в нижнем регистре
+	MaxLength     int      // MaxLength наибольшая длина канонического адреса в байтах
+	StripTracking bool     // StripTracking удалять из запроса utm_* и другие параметры отслеживания
+}
+
+// Normalize разбирает адрес, проверяет его и возвращает канонический вид.
+// Нарушения возвращаются как *Error.
+func (p *Policy) Normalize(rawURL string) (*url.URL, error) {
+	if len(rawURL) > p.maxLength() {
+		return nil, p.tooLong()
+	}
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return nil, &Error{Field: FieldURL, Reason: "cannot parse given string as URL"}
+	}
+	return p.Apply(u)
+}
+
+// Apply проверяет разобранный адрес и возвращает его канонический вид, не меняя u.
+// Нарушения возвращаются как *Error.
+func (p *Policy) Apply(u *url.URL) (*url.URL, error) {
+	res := *u
+	res.Scheme = strings.ToLower(res.Scheme)
+	if res.Scheme == "" {
+		return nil, &Error{Field: FieldScheme, Reason: "scheme is required"}
+	}
+	if !p.allowed(res.Scheme) {
+		return nil, &Error{Field: FieldScheme, Reason: fmt.Sprintf("scheme %q is not allowed", res.Scheme)}
+	}
+	if res.Opaque != "" || res.Hostname() == "" {
+		return nil, &Error{Field: FieldHost, Reason: "host is required"}
+	}
+
+	host, err := canonicalHost(res.Hostname())
+	if err != nil {
+		return nil, err
+	}
+	port := res.Port()
+	if port == defaultPorts[res.Scheme] {
+		port = ""
+	}
+	if strings.Contains(host, ":") {
+		// IPv6 literal
+		host = "[" + host + "]"
+	}
+	if port != "" {
+		host += ":" + port
+	}
+	res.Host = host
+
+	if res.Path == "" {
+		res.Path = "/"
+		res.RawPath = ""
+	}
+	if p.StripTracking && res.RawQuery != "" {
+		res.RawQuery = stripTracking(res.RawQuery)
+		res.ForceQuery = false
+	}
+
+	if len(res.String()) > p.maxLength() {
+		return nil, p.tooLong()
+	}
+	return &res, nil
+}
+
+// canonicalHost приводит имя хоста к нижнему регистру и переводит интернациональное имя в punycode
+func canonicalHost(host string) (string, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return strings.ToLower(ip.String()), nil
+	}
+	ascii, err := hostProfile.ToASCII(host)
+	if err != nil {
+		return "", &Error{Field: FieldHost, Reason: fmt.Sprintf("%q is not a valid host name", host)}
+	}
+	return ascii, nil
+}
+
+// stripTracking удаляет параметры отслеживания из строки запроса, сохраняя порядок остальных
+func stripTracking(rawQuery string) string {
+	parts := strings.Split(rawQuery, "&")
+	kept := parts[:0]
+	for _, part := range parts {
+		key := part
+		if i := strings.IndexByte(part, '='); i >= 0 {
+			key = part[:i]
+		}
+		if k, err := url.QueryUnescape(key); err == nil {
+			key = k
+		}
+		key = strings.ToLower(key)
+		if strings.HasPrefix(key, "utm_") || trackingParams[key] {
+			continue
+		}
+		kept = append(kept, part)
+	}
+	return strings.Join(kept, "&")
+}
+
+func (p *Policy) allowed(scheme string) bool {
+	schemes := p.Schemes
+	if len(schemes) == 0 {
+		schemes = DefaultSchemes
+	}
+	for _, s := range schemes {
+		if s == scheme {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Policy) maxLength() int {
+	if p.MaxLength > 0 {
+		return p.MaxLength
+	}
+	return DefaultMaxLength
+}
+
+func (p *Policy) tooLong() *Error {
+	return &Error{Field: FieldURL, Reason: fmt.Sprintf("URL is longer than %d bytes", p.maxLength())}
+}
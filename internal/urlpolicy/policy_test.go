@@ -0,0 +1,88 @@
+package urlpolicy
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicy_Normalize(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    Policy
+		rawURL    string
+		want      string
+		wantField string
+	}{
+		{name: "canonical", rawURL: "https://practicum.yandex.ru/learn?a=1#top", want: "https://practicum.yandex.ru/learn?a=1#top"},
+		{name: "case and empty path", rawURL: "HTTP://Example.COM", want: "http://example.com/"},
+		{name: "default port", rawURL: "https://example.com:443/a", want: "https://example.com/a"},
+		{name: "other port", rawURL: "http://example.com:8080", want: "http://example.com:8080/"},
+		{name: "spaces around", rawURL: "  https://example.com/ ", want: "https://example.com/"},
+		{name: "idn", rawURL: "http://Пример.рф/путь", want: "http://xn--e1afmkfd.xn--p1ai/%D0%BF%D1%83%D1%82%D1%8C"},
+		{name: "underscore", rawURL: "https://my_service.example.com/", want: "https://my_service.example.com/"},
+		{name: "ipv6", rawURL: "http://[2001:DB8::1]:80/", want: "http://[2001:db8::1]/"},
+		{name: "path case kept", rawURL: "https://example.com/Docs", want: "https://example.com/Docs"},
+		{
+			name:   "tracking kept by default",
+			rawURL: "https://example.com/?utm_source=mail&id=5",
+			want:   "https://example.com/?utm_source=mail&id=5",
+		},
+		{
+			name:   "tracking stripped",
+			policy: Policy{StripTracking: true},
+			rawURL: "https://example.com/?UTM_Source=mail&id=5&fbclid=abc&b=2",
+			want:   "https://example.com/?id=5&b=2",
+		},
+		{
+			name:   "only tracking",
+			policy: Policy{StripTracking: true},
+			rawURL: "https://example.com/?utm_medium=x",
+			want:   "https://example.com/",
+		},
+		{name: "custom scheme", policy: Policy{Schemes: []string{"ftp"}}, rawURL: "ftp://files.example.com:21/a", want: "ftp://files.example.com/a"},
+
+		{name: "javascript", rawURL: "javascript:alert(1)", wantField: FieldScheme},
+		{name: "relative", rawURL: "/path/to", wantField: FieldScheme},
+		{name: "bare word", rawURL: "practicum", wantField: FieldScheme},
+		{name: "scheme not in custom list", policy: Policy{Schemes: []string{"ftp"}}, rawURL: "https://example.com/", wantField: FieldScheme},
+		{name: "no host", rawURL: "https:///path", wantField: FieldHost},
+		{name: "opaque", rawURL: "http:example.com", wantField: FieldHost},
+		{name: "bad idn", rawURL: "http://xn--a.com/", wantField: FieldHost},
+		{name: "unparsable", rawURL: "htt_p://o.com", wantField: FieldURL},
+		{name: "too long", policy: Policy{MaxLength: 30}, rawURL: "https://example.com/" + strings.Repeat("a", 20), wantField: FieldURL},
+		{name: "too long by default", rawURL: "https://example.com/" + strings.Repeat("a", DefaultMaxLength), wantField: FieldURL},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := tt.policy.Normalize(tt.rawURL)
+			if tt.wantField != "" {
+				var perr *Error
+				require.True(t, errors.As(err, &perr), "unexpected error %v", err)
+				assert.Equal(t, tt.wantField, perr.Field)
+				assert.Contains(t, err.Error(), "invalid URL "+tt.wantField)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, u.String())
+		})
+	}
+}
+
+func TestPolicy_Apply(t *testing.T) {
+	var p Policy
+	a, err := p.Normalize("HTTP://Example.com:80")
+	require.NoError(t, err)
+	b, err := p.Normalize("http://example.com/")
+	require.NoError(t, err)
+	assert.Equal(t, a.String(), b.String())
+
+	// the argument is left untouched
+	again, err := p.Apply(a)
+	require.NoError(t, err)
+	assert.Equal(t, a, again)
+	assert.NotSame(t, a, again)
+}
@@ -1,7 +1,11 @@
 // Package models содержит структуры, которые используются для передачи в API.
 package models
 
-import "github.com/gofrs/uuid"
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
 
 // ShortenRequest запрос на сокращение ссылки.
 //
@@ -12,7 +16,59 @@ import "github.com/gofrs/uuid"
 //		err := json.NewDecoder(r.Body).Decode(&req)
 //		...
 type ShortenRequest struct {
-	URL string `json:"url"`
+	URL   string `json:"url"`
+	Alias string `json:"alias,omitempty"` // Alias желаемый короткий идентификатор, необязательный
+	LinkOptions
+}
+
+// LinkOptions необязательные параметры сокращаемой ссылки.
+// Срок действия задается либо моментом ExpiresAt, либо длительностью TTL, но не обоими сразу.
+type LinkOptions struct {
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`  // ExpiresAt момент, после которого ссылка перестает работать
+	TTL         int64      `json:"ttl,omitempty"`         // TTL время жизни ссылки в секундах
+	Password    string     `json:"password,omitempty"`    // Password пароль для перехода по ссылке, хранится только хеш
+	MaxClicks   int        `json:"max_clicks,omitempty"`  // MaxClicks число переходов, после которого ссылка удаляется, 1 - одноразовая
+	Title       string     `json:"title,omitempty"`       // Title название ссылки
+	Notes       string     `json:"notes,omitempty"`       // Notes заметки владельца
+	Tags        []string   `json:"tags,omitempty"`        // Tags метки для группировки ссылок
+	Domain      string     `json:"domain,omitempty"`      // Domain домен короткой ссылки, по умолчанию домен запроса
+	Redirect    string     `json:"redirect,omitempty"`    // Redirect способ перехода: 301, 302, 307, 308, interstitial или meta-refresh
+	Passthrough string     `json:"passthrough,omitempty"` // Passthrough перенос запроса в адрес перехода: path, query или all
+	Template    bool       `json:"template,omitempty"`    // Template адрес содержит подстановки {1} и {name}, заполняемые из запроса
+	Rules       []Rule     `json:"rules,omitempty"`       // Rules правила выбора адреса перехода, проверяются по порядку
+	Variants    []Variant  `json:"variants,omitempty"`    // Variants адреса A/B-теста, между которыми распределяются посетители
+}
+
+// Variant адрес A/B-теста. Посетитель закрепляется за вариантом, а доля посетителей варианта
+// пропорциональна его весу.
+type Variant struct {
+	Name   string `json:"name,omitempty"`   // Name название в статистике, по умолчанию буква по порядку: A, B, C...
+	URL    string `json:"url"`              // URL адрес перехода
+	Weight int    `json:"weight,omitempty"` // Weight вес варианта, по умолчанию 1
+}
+
+// Rule правило выбора адреса перехода. Правило срабатывает, если запрос подходит под все его условия,
+// из подходящих правил выбирается первое, а если не подошло ни одно - переход идет по адресу ссылки.
+type Rule struct {
+	Devices   []string     `json:"devices,omitempty"`   // Devices классы устройства: ios, android, mobile или desktop
+	Languages []string     `json:"languages,omitempty"` // Languages языки, предпочитаемые клиентом по Accept-Language, например en или pt-BR
+	Headers   []RuleHeader `json:"headers,omitempty"`   // Headers условия на заголовки запроса
+	Time      *RuleTime    `json:"time,omitempty"`      // Time окно времени, в которое действует правило
+	URL       string       `json:"url"`                 // URL адрес перехода по правилу
+}
+
+// RuleHeader условие на заголовок запроса.
+type RuleHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value,omitempty"` // Value значение без учета регистра, пустое - заголовок просто задан
+}
+
+// RuleTime окно времени. Если To раньше From, окно переходит через полночь.
+type RuleTime struct {
+	Days []string `json:"days,omitempty"` // Days дни недели mon, tue, wed, thu, fri, sat, sun, по умолчанию все
+	From string   `json:"from,omitempty"` // From начало окна HH:MM включительно, по умолчанию 00:00
+	To   string   `json:"to,omitempty"`   // To конец окна HH:MM не включительно, по умолчанию конец суток
+	Zone string   `json:"zone,omitempty"` // Zone часовой пояс, например Europe/Moscow, по умолчанию UTC
 }
 
 // ShortenResponse ответ с сокращенной ссылкой.
@@ -22,14 +78,39 @@ type ShortenResponse struct {
 
 // URLResponse ответ с сокращенной и оригинальной ссылками.
 type URLResponse struct {
-	ShortURL    string `json:"short_url"`
-	OriginalURL string `json:"original_url"`
+	ShortURL    string   `json:"short_url"`
+	OriginalURL string   `json:"original_url"`
+	Title       string   `json:"title,omitempty"`
+	Notes       string   `json:"notes,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Rules       []Rule   `json:"rules,omitempty"`
+}
+
+// Состояния ссылки в LinkPreview.
+const (
+	LinkActive  = "active"  // LinkActive по ссылке можно перейти
+	LinkDeleted = "deleted" // LinkDeleted ссылка удалена владельцем или исчерпала переходы
+	LinkExpired = "expired" // LinkExpired срок действия ссылки истек
+	LinkBlocked = "blocked" // LinkBlocked адрес ссылки запрещен
+)
+
+// LinkPreview сведения о ссылке, которые можно посмотреть до перехода по ней.
+// Адрес и название раскрываются только для активной ссылки без пароля.
+type LinkPreview struct {
+	ShortURL    string     `json:"short_url"`
+	Status      string     `json:"status"` // Status состояние ссылки: LinkActive, LinkDeleted, LinkExpired или LinkBlocked
+	OriginalURL string     `json:"original_url,omitempty"`
+	Title       string     `json:"title,omitempty"`
+	CreatedAt   *time.Time `json:"created_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	Protected   bool       `json:"password_protected,omitempty"` // Protected для перехода нужен пароль
 }
 
 // BatchShortenRequest запрос на сокращение нескольких ссылок.
 type BatchShortenRequest struct {
 	CorrelationID string `json:"correlation_id"`
 	OriginalURL   string `json:"original_url"`
+	LinkOptions
 }
 
 // BatchShortenResponse ответ на запрос на сокращение нескольких ссылок.
@@ -44,8 +125,47 @@ type BatchRemoveRequest struct {
 	Ids []string
 }
 
+// LinkStats статистика переходов по ссылке за период.
+type LinkStats struct {
+	ID             string        `json:"id"`
+	TotalClicks    int           `json:"total_clicks"`
+	UniqueVisitors int           `json:"unique_visitors"` // UniqueVisitors число разных адресов клиентов
+	Daily          []DailyClicks `json:"daily"`           // Daily переходы по дням, включая дни без переходов
+	TopReferrers   []TopValue    `json:"top_referrers"`
+	TopUserAgents  []TopValue    `json:"top_user_agents"`
+	Variants       []TopValue    `json:"variants,omitempty"` // Variants переходы по вариантам A/B-теста
+}
+
+// DailyClicks число переходов за день.
+type DailyClicks struct {
+	Date   string `json:"date"` // Date день в формате 2006-01-02 по UTC
+	Clicks int    `json:"clicks"`
+}
+
+// TopValue значение и число переходов с ним.
+type TopValue struct {
+	Value  string `json:"value"`
+	Clicks int    `json:"clicks"`
+}
+
 // Statistics структура для представления статистики
 type Statistics struct {
 	Urls  int `json:"urls"`
 	Users int `json:"users"`
 }
+
+// UpdateURLRequest запрос на изменение короткой ссылки.
+// Меняются только переданные поля, пустой список Tags удаляет все метки, а пустой список Rules - все правила.
+type UpdateURLRequest struct {
+	OriginalURL string    `json:"original_url,omitempty"`
+	Title       *string   `json:"title,omitempty"`
+	Notes       *string   `json:"notes,omitempty"`
+	Tags        *[]string `json:"tags,omitempty"`
+	Rules       *[]Rule   `json:"rules,omitempty"`
+}
+
+// URLRevision прежний адрес короткой ссылки.
+type URLRevision struct {
+	OriginalURL string    `json:"original_url"`
+	ReplacedAt  time.Time `json:"replaced_at"` // ReplacedAt время, когда адрес был заменен
+}
@@ -24,8 +24,13 @@ type ShortenerClient interface {
 	BatchRemove(ctx context.Context, in *BatchRemoveRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
 	Statistics(ctx context.Context, in *StatisticsRequest, opts ...grpc.CallOption) (*StatisticsResponse, error)
 	Expand(ctx context.Context, in *UrlRequest, opts ...grpc.CallOption) (*UrlResponse, error)
+	Preview(ctx context.Context, in *UrlRequest, opts ...grpc.CallOption) (*UrlResponse, error)
 	UserUrls(ctx context.Context, in *UserUrlsRequest, opts ...grpc.CallOption) (*UserUrlsResponse, error)
 	Ping(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	LinkStats(ctx context.Context, in *LinkStatsRequest, opts ...grpc.CallOption) (*LinkStatsResponse, error)
+	QRCode(ctx context.Context, in *QRCodeRequest, opts ...grpc.CallOption) (*QRCodeResponse, error)
+	Update(ctx context.Context, in *UpdateRequest, opts ...grpc.CallOption) (*UpdateResponse, error)
+	History(ctx context.Context, in *HistoryRequest, opts ...grpc.CallOption) (*HistoryResponse, error)
 }
 
 type shortenerClient struct {
@@ -81,6 +86,15 @@ func (c *shortenerClient) Expand(ctx context.Context, in *UrlRequest, opts ...gr
 	return out, nil
 }
 
+func (c *shortenerClient) Preview(ctx context.Context, in *UrlRequest, opts ...grpc.CallOption) (*UrlResponse, error) {
+	out := new(UrlResponse)
+	err := c.cc.Invoke(ctx, "/shortener.Shortener/Preview", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *shortenerClient) UserUrls(ctx context.Context, in *UserUrlsRequest, opts ...grpc.CallOption) (*UserUrlsResponse, error) {
 	out := new(UserUrlsResponse)
 	err := c.cc.Invoke(ctx, "/shortener.Shortener/UserUrls", in, out, opts...)
@@ -99,6 +113,42 @@ func (c *shortenerClient) Ping(ctx context.Context, in *emptypb.Empty, opts ...g
 	return out, nil
 }
 
+func (c *shortenerClient) LinkStats(ctx context.Context, in *LinkStatsRequest, opts ...grpc.CallOption) (*LinkStatsResponse, error) {
+	out := new(LinkStatsResponse)
+	err := c.cc.Invoke(ctx, "/shortener.Shortener/LinkStats", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) QRCode(ctx context.Context, in *QRCodeRequest, opts ...grpc.CallOption) (*QRCodeResponse, error) {
+	out := new(QRCodeResponse)
+	err := c.cc.Invoke(ctx, "/shortener.Shortener/QRCode", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) Update(ctx context.Context, in *UpdateRequest, opts ...grpc.CallOption) (*UpdateResponse, error) {
+	out := new(UpdateResponse)
+	err := c.cc.Invoke(ctx, "/shortener.Shortener/Update", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) History(ctx context.Context, in *HistoryRequest, opts ...grpc.CallOption) (*HistoryResponse, error) {
+	out := new(HistoryResponse)
+	err := c.cc.Invoke(ctx, "/shortener.Shortener/History", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ShortenerServer is the server API for Shortener service.
 // All implementations must embed UnimplementedShortenerServer
 // for forward compatibility
@@ -108,8 +158,13 @@ type ShortenerServer interface {
 	BatchRemove(context.Context, *BatchRemoveRequest) (*emptypb.Empty, error)
 	Statistics(context.Context, *StatisticsRequest) (*StatisticsResponse, error)
 	Expand(context.Context, *UrlRequest) (*UrlResponse, error)
+	Preview(context.Context, *UrlRequest) (*UrlResponse, error)
 	UserUrls(context.Context, *UserUrlsRequest) (*UserUrlsResponse, error)
 	Ping(context.Context, *emptypb.Empty) (*emptypb.Empty, error)
+	LinkStats(context.Context, *LinkStatsRequest) (*LinkStatsResponse, error)
+	QRCode(context.Context, *QRCodeRequest) (*QRCodeResponse, error)
+	Update(context.Context, *UpdateRequest) (*UpdateResponse, error)
+	History(context.Context, *HistoryRequest) (*HistoryResponse, error)
 	mustEmbedUnimplementedShortenerServer()
 }
 
@@ -132,12 +187,27 @@ func (UnimplementedShortenerServer) Statistics(context.Context, *StatisticsReque
 func (UnimplementedShortenerServer) Expand(context.Context, *UrlRequest) (*UrlResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Expand not implemented")
 }
+func (UnimplementedShortenerServer) Preview(context.Context, *UrlRequest) (*UrlResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Preview not implemented")
+}
 func (UnimplementedShortenerServer) UserUrls(context.Context, *UserUrlsRequest) (*UserUrlsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method UserUrls not implemented")
 }
 func (UnimplementedShortenerServer) Ping(context.Context, *emptypb.Empty) (*emptypb.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Ping not implemented")
 }
+func (UnimplementedShortenerServer) LinkStats(context.Context, *LinkStatsRequest) (*LinkStatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LinkStats not implemented")
+}
+func (UnimplementedShortenerServer) QRCode(context.Context, *QRCodeRequest) (*QRCodeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method QRCode not implemented")
+}
+func (UnimplementedShortenerServer) Update(context.Context, *UpdateRequest) (*UpdateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Update not implemented")
+}
+func (UnimplementedShortenerServer) History(context.Context, *HistoryRequest) (*HistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method History not implemented")
+}
 func (UnimplementedShortenerServer) mustEmbedUnimplementedShortenerServer() {}
 
 // UnsafeShortenerServer may be embedded to opt out of forward compatibility for this service.
@@ -241,6 +311,24 @@ func _Shortener_Expand_Handler(srv interface{}, ctx context.Context, dec func(in
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Shortener_Preview_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UrlRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).Preview(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/shortener.Shortener/Preview",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).Preview(ctx, req.(*UrlRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Shortener_UserUrls_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(UserUrlsRequest)
 	if err := dec(in); err != nil {
@@ -277,6 +365,78 @@ func _Shortener_Ping_Handler(srv interface{}, ctx context.Context, dec func(inte
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Shortener_LinkStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LinkStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).LinkStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/shortener.Shortener/LinkStats",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).LinkStats(ctx, req.(*LinkStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_QRCode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QRCodeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).QRCode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/shortener.Shortener/QRCode",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).QRCode(ctx, req.(*QRCodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_Update_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).Update(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/shortener.Shortener/Update",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).Update(ctx, req.(*UpdateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_History_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).History(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/shortener.Shortener/History",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).History(ctx, req.(*HistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Shortener_ServiceDesc is the grpc.ServiceDesc for Shortener service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -304,6 +464,10 @@ var Shortener_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Expand",
 			Handler:    _Shortener_Expand_Handler,
 		},
+		{
+			MethodName: "Preview",
+			Handler:    _Shortener_Preview_Handler,
+		},
 		{
 			MethodName: "UserUrls",
 			Handler:    _Shortener_UserUrls_Handler,
@@ -312,6 +476,22 @@ var Shortener_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Ping",
 			Handler:    _Shortener_Ping_Handler,
 		},
+		{
+			MethodName: "LinkStats",
+			Handler:    _Shortener_LinkStats_Handler,
+		},
+		{
+			MethodName: "QRCode",
+			Handler:    _Shortener_QRCode_Handler,
+		},
+		{
+			MethodName: "Update",
+			Handler:    _Shortener_Update_Handler,
+		},
+		{
+			MethodName: "History",
+			Handler:    _Shortener_History_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/shortner.proto",